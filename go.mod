@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/frankban/quicktest v1.7.2 // indirect
+	github.com/golang/protobuf v1.3.3
 	github.com/google/go-cmp v0.4.0 // indirect
 	github.com/hashicorp/go-plugin v1.0.1
 	github.com/hashicorp/vault/api v1.0.4
@@ -52,6 +52,15 @@ func PrivateKeyToAddress(key *ecdsa.PrivateKey) (Address, error) {
 	}
 	pubBytes := elliptic.Marshal(secp256k1.S256(), key.PublicKey.X, key.PublicKey.Y)
 
+	return PublicKeyBytesToAddress(pubBytes)
+}
+
+// PublicKeyBytesToAddress derives the Address for the provided 65-byte uncompressed secp256k1 public key.
+func PublicKeyBytesToAddress(pubBytes []byte) (Address, error) {
+	if len(pubBytes) != 2*keyLen+1 || pubBytes[0] != 4 {
+		return Address{}, errors.New("invalid public key: must be 65-byte uncompressed secp256k1 public key")
+	}
+
 	d := sha3.NewLegacyKeccak256()
 	_, err := d.Write(pubBytes[1:])
 	if err != nil {
@@ -79,6 +79,33 @@ func TestPrivateKeyToAddress_InvalidKey(t *testing.T) {
 	require.EqualError(t, gotErr, want)
 }
 
+func TestPublicKeyBytesToAddress(t *testing.T) {
+	byt, _ := hex.DecodeString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
+	x, y := secp256k1.S256().ScalarBaseMult(byt)
+	pubBytes := secp256k1.S256().Marshal(x, y)
+
+	addrByt, _ := hex.DecodeString("6038dc01869425004ca0b8370f6c81cf464213b3")
+	var want Address
+	copy(want[:], addrByt)
+
+	got, err := PublicKeyBytesToAddress(pubBytes)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestPublicKeyBytesToAddress_InvalidKey(t *testing.T) {
+	want := "invalid public key: must be 65-byte uncompressed secp256k1 public key"
+
+	_, gotErr := PublicKeyBytesToAddress(nil)
+	require.EqualError(t, gotErr, want)
+
+	_, gotErr = PublicKeyBytesToAddress(make([]byte, 33))
+	require.EqualError(t, gotErr, want)
+
+	_, gotErr = PublicKeyBytesToAddress(make([]byte, 65))
+	require.EqualError(t, gotErr, want)
+}
+
 func TestPrivateKeyToBytes(t *testing.T) {
 	byt, _ := hex.DecodeString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
 	key := &ecdsa.PrivateKey{
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AuthMethod checks the configuration of a Vault authentication method.  The built-in methods are registered by this
+// package, and any others are registered when their Authenticator is registered with the hashicorp package.
+type AuthMethod interface {
+	// IsConfigured returns true if any of the method's fields are set.  It is used to infer the method to use if
+	// authentication.method is not set, and to check that only one method has been configured.
+	IsConfigured(conf VaultClientAuthentication) bool
+
+	// Validate checks that the method's configuration is complete and valid.  The full client config is provided so
+	// that methods can check any dependencies on other config (e.g. TLS).
+	Validate(conf VaultClient) error
+}
+
+var (
+	authMethods = map[string]AuthMethod{
+		"token":      TokenAuthMethod{},
+		"tokenFile":  TokenFileAuthMethod{},
+		"approle":    ApproleAuthMethod{},
+		"kubernetes": KubernetesAuthMethod{},
+		"jwt":        JWTAuthMethod{},
+		"cert":       CertAuthMethod{},
+	}
+	authMethodsMu sync.RWMutex
+)
+
+// RegisterAuthMethod makes an authentication method available for use with the given authentication.method config
+// value.  Registering the same method twice replaces the previous registration.
+func RegisterAuthMethod(method string, m AuthMethod) {
+	authMethodsMu.Lock()
+	defer authMethodsMu.Unlock()
+
+	authMethods[method] = m
+}
+
+// ValidateAuthentication returns the authentication method configured in c, after validating that exactly one method
+// has been configured and that its configuration is valid.  If authentication.method is not set then the method is
+// inferred from the fields that have been set.
+func (c VaultClient) ValidateAuthentication() (string, error) {
+	authMethodsMu.RLock()
+	var methods []string
+	registered := make(map[string]AuthMethod, len(authMethods))
+	for method, m := range authMethods {
+		methods = append(methods, method)
+		registered[method] = m
+	}
+	authMethodsMu.RUnlock()
+	sort.Strings(methods)
+
+	var configured []string
+	for _, method := range methods {
+		if registered[method].IsConfigured(c.Authentication) {
+			configured = append(configured, method)
+		}
+	}
+
+	method := c.Authentication.Method
+	if method == "" {
+		if len(configured) != 1 {
+			return "", errors.New(InvalidAuthentication)
+		}
+		method = configured[0]
+	}
+
+	m, ok := registered[method]
+	if !ok {
+		return "", fmt.Errorf("unsupported authentication method %v, must be one of %v", method, methods)
+	}
+	if len(configured) > 1 || (len(configured) == 1 && configured[0] != method) {
+		return "", errors.New(InvalidAuthentication)
+	}
+	if err := m.Validate(c); err != nil {
+		return "", err
+	}
+	return method, nil
+}
+
+// TokenAuthMethod uses a token provided directly by a SecretSource
+type TokenAuthMethod struct{}
+
+func (TokenAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.Token.IsConfigured()
+}
+
+func (TokenAuthMethod) Validate(conf VaultClient) error {
+	if !conf.Authentication.Token.IsConfigured() {
+		return errors.New(InvalidAuthentication)
+	}
+	return conf.Authentication.Token.Validate("token")
+}
+
+// TokenFileAuthMethod uses a token read from a token sink file (e.g. written by Vault Agent)
+type TokenFileAuthMethod struct{}
+
+func (TokenFileAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.IsTokenFileSet()
+}
+
+func (TokenFileAuthMethod) Validate(conf VaultClient) error {
+	if !conf.Authentication.IsTokenFileSet() || !isValidAbsFileUrl(conf.Authentication.TokenFile) {
+		return errors.New(InvalidTokenFile)
+	}
+	if conf.Authentication.TokenFilePollInterval < 0 {
+		return errors.New(InvalidTokenFilePollInterval)
+	}
+	return nil
+}
+
+// ApproleAuthMethod logs in to the AppRole auth method
+type ApproleAuthMethod struct{}
+
+func (ApproleAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.RoleId.IsConfigured() || conf.SecretId.IsConfigured() || conf.ApprolePath != ""
+}
+
+func (ApproleAuthMethod) Validate(conf VaultClient) error {
+	auth := conf.Authentication
+	if !auth.RoleId.IsConfigured() || !auth.SecretId.IsConfigured() || auth.ApprolePath == "" {
+		return errors.New(InvalidAuthentication)
+	}
+	if err := auth.RoleId.Validate("roleId"); err != nil {
+		return err
+	}
+	return auth.SecretId.Validate("secretId")
+}
+
+// KubernetesAuthMethod logs in to the Kubernetes auth method using the pod's service account token
+type KubernetesAuthMethod struct{}
+
+func (KubernetesAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.Kubernetes.IsSet()
+}
+
+func (KubernetesAuthMethod) Validate(conf VaultClient) error {
+	k := conf.Authentication.Kubernetes
+	if k.Role == "" || k.Path == "" {
+		return errors.New(InvalidAuthentication)
+	}
+	if IsUrlSet(k.TokenFile) && !isValidAbsFileUrl(k.TokenFile) {
+		return errors.New(InvalidKubernetesTokenFile)
+	}
+	return nil
+}
+
+// JWTAuthMethod logs in to the JWT/OIDC auth method
+type JWTAuthMethod struct{}
+
+func (JWTAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.JWT.IsSet()
+}
+
+func (JWTAuthMethod) Validate(conf VaultClient) error {
+	j := conf.Authentication.JWT
+	if j.Role == "" || j.Path == "" || !j.Jwt.IsConfigured() {
+		return errors.New(InvalidAuthentication)
+	}
+	return j.Jwt.Validate("jwt.jwt")
+}
+
+// CertAuthMethod logs in to the TLS certificate auth method using the client certificate configured for TLS
+type CertAuthMethod struct{}
+
+func (CertAuthMethod) IsConfigured(conf VaultClientAuthentication) bool {
+	return conf.Cert.IsSet()
+}
+
+func (CertAuthMethod) Validate(conf VaultClient) error {
+	if !IsUrlSet(conf.TLS.ClientCert) || !IsUrlSet(conf.TLS.ClientKey) {
+		return errors.New(InvalidCertAuthentication)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	envSecretScheme          = "env"
+	fileSecretScheme         = "file"
+	vaultWrappedSecretScheme = "vault-wrapped"
+)
+
+// SecretSource identifies where a credential is read from.  env://NAME reads environment variable NAME and
+// file:///path/to/file reads the file at the absolute path (with surrounding whitespace trimmed).  vault-wrapped://
+// followed by an env:// or file:// source reads a Vault response-wrapping token, which must be unwrapped by the Vault
+// client to get the credential.  Any other value without a :// is used as the literal credential, which is not
+// recommended outside of development.  Sources are resolved each time the credential is needed so that rotated
+// credentials are picked up.
+type SecretSource string
+
+// IsConfigured returns true if a source has been provided, regardless of whether it can be resolved
+func (s SecretSource) IsConfigured() bool {
+	return s != ""
+}
+
+// IsVaultWrapped returns true if the source is a Vault response-wrapping token that must be unwrapped to get the value
+func (s SecretSource) IsVaultWrapped() bool {
+	scheme, _ := s.split()
+	return scheme == vaultWrappedSecretScheme
+}
+
+// WrappingToken returns the source of the response-wrapping token of a vault-wrapped:// source
+func (s SecretSource) WrappingToken() (SecretSource, error) {
+	scheme, rest := s.split()
+	if scheme != vaultWrappedSecretScheme {
+		return "", errors.New("not a vault-wrapped:// source")
+	}
+	inner := SecretSource(rest)
+	if innerScheme, _ := inner.split(); innerScheme != envSecretScheme && innerScheme != fileSecretScheme {
+		return "", errors.New("vault-wrapped:// must be followed by an env:// or file:// source, e.g. vault-wrapped://file:///path/to/token")
+	}
+	return inner, nil
+}
+
+// Resolve returns the credential identified by the source.  vault-wrapped:// sources cannot be resolved without a
+// Vault client and return an error; use WrappingToken to get the source of the token to unwrap.
+func (s SecretSource) Resolve() (string, error) {
+	scheme, rest := s.split()
+	switch scheme {
+	case "":
+		return rest, nil
+	case envSecretScheme:
+		v, ok := os.LookupEnv(rest)
+		if !ok {
+			return "", fmt.Errorf("environment variable %v not set", rest)
+		}
+		return v, nil
+	case fileSecretScheme:
+		if !filepath.IsAbs(rest) {
+			return "", errors.New("file:// source must be an absolute path, e.g. file:///path/to/file")
+		}
+		b, err := ioutil.ReadFile(rest)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	case vaultWrappedSecretScheme:
+		return "", errors.New("vault-wrapped:// source must be unwrapped by Vault")
+	default:
+		return "", fmt.Errorf("unsupported scheme %v, must be one of [env file %v] or a literal value", scheme, vaultWrappedSecretScheme)
+	}
+}
+
+// Validate checks that the source, identified in errors by name, can currently be resolved to a non-empty value.  For
+// vault-wrapped:// sources the wrapping token is checked.
+func (s SecretSource) Validate(name string) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("%v is not set", name)
+	}
+	toResolve := s
+	if s.IsVaultWrapped() {
+		var err error
+		if toResolve, err = s.WrappingToken(); err != nil {
+			return fmt.Errorf("unable to resolve %v from %v: %v", name, s, err)
+		}
+	}
+	v, err := toResolve.Resolve()
+	if err != nil {
+		return fmt.Errorf("unable to resolve %v from %v: %v", name, s, err)
+	}
+	if v == "" {
+		return fmt.Errorf("unable to resolve %v from %v: value is empty", name, s)
+	}
+	return nil
+}
+
+// String returns the source for logging.  Literal values are redacted.
+func (s SecretSource) String() string {
+	if scheme, _ := s.split(); scheme == "" && s != "" {
+		return "<literal value>"
+	}
+	return string(s)
+}
+
+// split returns the scheme and remainder of the source, or an empty scheme for a literal value
+func (s SecretSource) split() (scheme, rest string) {
+	if i := strings.Index(string(s), "://"); i >= 0 {
+		return string(s)[:i], string(s)[i+len("://"):]
+	}
+	return "", string(s)
+}
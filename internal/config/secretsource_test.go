@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretSource_Resolve_Env(t *testing.T) {
+	s := SecretSource("env://TEST_ENV")
+
+	_, err := s.Resolve()
+	require.EqualError(t, err, "environment variable TEST_ENV not set")
+
+	os.Setenv("TEST_ENV", "val")
+	defer os.Unsetenv("TEST_ENV")
+
+	got, err := s.Resolve()
+	require.NoError(t, err)
+	require.Equal(t, "val", got)
+}
+
+func TestSecretSource_Resolve_File(t *testing.T) {
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("val\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	got, err := SecretSource("file://" + f.Name()).Resolve()
+	require.NoError(t, err)
+	require.Equal(t, "val", got)
+
+	_, err = SecretSource("file://relative/path").Resolve()
+	require.EqualError(t, err, "file:// source must be an absolute path, e.g. file:///path/to/file")
+}
+
+func TestSecretSource_Resolve_Literal(t *testing.T) {
+	got, err := SecretSource("s.literal").Resolve()
+	require.NoError(t, err)
+	require.Equal(t, "s.literal", got)
+}
+
+func TestSecretSource_Resolve_Unsupported(t *testing.T) {
+	_, err := SecretSource("vault-wrapped://env://TEST_ENV").Resolve()
+	require.EqualError(t, err, "vault-wrapped:// source must be unwrapped by Vault")
+
+	_, err = SecretSource("http://vault:8200").Resolve()
+	require.EqualError(t, err, "unsupported scheme http, must be one of [env file vault-wrapped] or a literal value")
+}
+
+func TestSecretSource_WrappingToken(t *testing.T) {
+	s := SecretSource("vault-wrapped://file:///path/to/token")
+	require.True(t, s.IsVaultWrapped())
+
+	got, err := s.WrappingToken()
+	require.NoError(t, err)
+	require.Equal(t, SecretSource("file:///path/to/token"), got)
+
+	_, err = SecretSource("vault-wrapped://s.literal").WrappingToken()
+	require.Error(t, err)
+
+	_, err = SecretSource("env://TEST_ENV").WrappingToken()
+	require.Error(t, err)
+}
+
+func TestSecretSource_Validate(t *testing.T) {
+	os.Setenv("TEST_ENV", "val")
+	os.Setenv("TEST_EMPTY_ENV", "")
+	defer os.Unsetenv("TEST_ENV")
+	defer os.Unsetenv("TEST_EMPTY_ENV")
+
+	var sources = map[string]struct {
+		source  SecretSource
+		wantErr string
+	}{
+		"env":                {source: "env://TEST_ENV"},
+		"literal":            {source: "s.literal"},
+		"vault_wrapped_env":  {source: "vault-wrapped://env://TEST_ENV"},
+		"not_set":            {source: "", wantErr: "token is not set"},
+		"env_not_set":        {source: "env://NOT_SET", wantErr: "unable to resolve token from env://NOT_SET: environment variable NOT_SET not set"},
+		"env_empty":          {source: "env://TEST_EMPTY_ENV", wantErr: "unable to resolve token from env://TEST_EMPTY_ENV: value is empty"},
+		"vault_wrapped_bad":  {source: "vault-wrapped://env://NOT_SET", wantErr: "unable to resolve token from vault-wrapped://env://NOT_SET: environment variable NOT_SET not set"},
+		"unsupported_scheme": {source: "https://vault", wantErr: "unable to resolve token from https://vault: unsupported scheme https, must be one of [env file vault-wrapped] or a literal value"},
+	}
+
+	for name, tt := range sources {
+		t.Run(name, func(t *testing.T) {
+			err := tt.source.Validate("token")
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretSource_String_RedactsLiteral(t *testing.T) {
+	require.Equal(t, "<literal value>", SecretSource("s.literal").String())
+	require.Equal(t, "env://TEST_ENV", SecretSource("env://TEST_ENV").String())
+	require.Equal(t, "", SecretSource("").String())
+}
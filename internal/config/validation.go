@@ -2,54 +2,161 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 )
 
 const (
-	InvalidVaultUrl            = "vault must be a valid HTTP/HTTPS url"
-	InvalidKVEngineName        = "kvEngineName must be set"
-	InvalidAccountDirectory    = "accountDirectory must be a valid absolute file url"
-	InvalidAuthentication      = "authentication must contain roleId, secretId and approlePath OR only token, and the given environment variables must be set"
-	InvalidCaCert              = "caCert must be a valid absolute file url"
-	InvalidClientCert          = "clientCert must be a valid absolute file url"
-	InvalidClientKey           = "clientKey must be a valid absolute file url"
-	InvalidSecretName          = "secretName must be set"
-	InvalidOverwriteProtection = "currentVersion and insecureDisable cannot both be set"
+	InvalidVaultUrl              = "vault must be a valid HTTP/HTTPS url"
+	InvalidFailoverAddresses     = "failoverAddresses must be valid HTTP/HTTPS urls"
+	InvalidKVEngineName          = "kvEngineName must be set"
+	InvalidKVVersion             = "kvVersion must be 1 or 2 if set"
+	InvalidAccountDirectory      = "accountDirectory must be a valid absolute file url or a vault://<engine>/<prefix> url"
+	InvalidAuthentication        = "authentication must contain exactly one complete method (token, approle: roleId, secretId and approlePath, kubernetes: role and path, jwt: role, path and jwt, cert: role or path, or tokenFile), and the given environment variables must be set"
+	InvalidTokenFile             = "tokenFile must be a valid absolute file url"
+	InvalidTokenFilePollInterval = "tokenFile.pollInterval must not be negative"
+	InvalidCertAuthentication    = "cert authentication requires tls clientCert and clientKey to be set"
+	InvalidKubernetesTokenFile   = "kubernetes.tokenFile must be a valid absolute file url"
+	InvalidCaCert                = "caCert must be a valid absolute file url"
+	InvalidClientCert            = "clientCert must be a valid absolute file url"
+	InvalidClientKey             = "clientKey must be a valid absolute file url"
+	InvalidSecretName            = "secretName must be set"
+	InvalidTransitKeyName        = "secretName and transitKeyName cannot both be set"
+	InvalidTransitEngineName     = "transitEngineName must be set if transitKeyType is set"
+	InvalidOverwriteProtection   = "currentVersion and insecureDisable cannot both be set"
+	InvalidRetryInterval         = "retry.initialInterval and retry.maxInterval must not be negative, and retry.initialInterval must not be greater than retry.maxInterval"
+	InvalidRetryMultiplier       = "retry.multiplier must be at least 1 if set"
+	InvalidRetryJitter           = "retry.jitter must be between 0 and 1"
+	InvalidRetryMaxAttempts      = "retry.maxAttempts must not be negative"
+	InvalidSecretLayoutType      = "secretLayout.type must be addressKeyed or namedField if set"
+	InvalidSecretLayoutFields    = "secretLayout.keyField and secretLayout.addressField can only be set for the namedField layout and must be different"
+	InvalidExtraFields           = "extraFields cannot be set for transit accounts"
+	InvalidNewAccountKVEngine    = "kvEngineName cannot be set for transit accounts"
+	InvalidCustomMetadata        = "customMetadata cannot be set for transit accounts, and must have at most 63 entries with non-empty keys of at most 128 bytes, values of at most 512 bytes and no address key"
+	InvalidConnectionName        = "connections must have unique, non-empty names"
+	InvalidDiscovery             = "discovery.prefix must be a relative path and discovery.refreshInterval must not be negative"
+	InvalidHealthCheckInterval   = "healthCheckInterval must not be negative"
+	InvalidKeyStoreType          = "keyStore.type must be vault or file if set"
+	InvalidInsecureDev           = "the file key store is insecure and must only be used for development, set insecureDev to use it"
+	InvalidFileKeyStore          = "keyStore.directory must be a valid absolute file url and keyStore.passphrase must be set to use the file key store"
+	InvalidFileKeyStoreUsage     = "the file key store cannot be used with connections, discovery, transitEngineName or a vault:// accountDirectory"
 )
 
 func (c VaultClient) Validate() error {
 	if c.Vault == nil || c.Vault.Scheme == "" {
 		return errors.New(InvalidVaultUrl)
 	}
+	for _, u := range c.FailoverAddresses {
+		if u == nil || u.Scheme == "" {
+			return errors.New(InvalidFailoverAddresses)
+		}
+	}
 	if c.KVEngineName == "" {
 		return errors.New(InvalidKVEngineName)
 	}
-	if c.AccountDirectory == nil || !isValidAbsFileUrl(c.AccountDirectory) {
+	if c.KVVersion != 0 && c.KVVersion != 1 && c.KVVersion != 2 {
+		return errors.New(InvalidKVVersion)
+	}
+	if c.AccountDirectory == nil || !(isValidAbsFileUrl(c.AccountDirectory) || isValidVaultAccountDirectory(c.AccountDirectory)) {
 		return errors.New(InvalidAccountDirectory)
 	}
-	if err := c.Authentication.validate(); err != nil {
+	if c.TransitEngineName == "" && c.TransitKeyType != "" {
+		return errors.New(InvalidTransitEngineName)
+	}
+	if err := c.validateKeyStore(); err != nil {
+		return err
+	}
+	if !c.KeyStore.IsFile() {
+		// the file key store does not use Vault so needs no authentication
+		if _, err := c.ValidateAuthentication(); err != nil {
+			return err
+		}
+	}
+	if err := c.SecretLayout.validate(); err != nil {
+		return err
+	}
+	if err := c.Discovery.validate(); err != nil {
+		return err
+	}
+	if c.HealthCheckInterval < 0 {
+		return errors.New(InvalidHealthCheckInterval)
+	}
+	if err := c.Authentication.Retry.validate(); err != nil {
 		return err
 	}
 	if err := c.TLS.validate(); err != nil {
 		return err
 	}
+	names := make(map[string]bool, len(c.Connections))
+	for _, conn := range c.Connections {
+		if conn.Name == "" || names[conn.Name] {
+			return errors.New(InvalidConnectionName)
+		}
+		names[conn.Name] = true
+		if err := c.ForConnection(conn).Validate(); err != nil {
+			return fmt.Errorf("connection %v: %v", conn.Name, err)
+		}
+	}
 	return nil
 }
 
-func (c VaultClientAuthentication) validate() error {
-	var (
-		tokenIsSet       = c.Token.IsSet()
-		roleIdIsSet      = c.RoleId.IsSet()
-		secretIdIsSet    = c.SecretId.IsSet()
-		approlePathIsSet = !(c.ApprolePath == "")
-	)
-	if !tokenIsSet && roleIdIsSet && secretIdIsSet && approlePathIsSet {
-		return nil
+func (c VaultClientSecretLayout) validate() error {
+	if c.Type != "" && c.Type != AddressKeyedSecretLayout && c.Type != NamedFieldSecretLayout {
+		return errors.New(InvalidSecretLayoutType)
+	}
+	if !c.IsNamedField() && (c.KeyField != "" || c.AddressField != "") {
+		return errors.New(InvalidSecretLayoutFields)
+	}
+	if c.AddressField != "" && c.AddressField == c.KeyField {
+		return errors.New(InvalidSecretLayoutFields)
 	}
-	if tokenIsSet && !roleIdIsSet && !secretIdIsSet && !approlePathIsSet {
+	return nil
+}
+
+// validateKeyStore checks the KeyStore config.  The file key store is only allowed if InsecureDev is set, and cannot
+// be used with features that require Vault.
+func (c VaultClient) validateKeyStore() error {
+	ks := c.KeyStore
+	if ks.Type != "" && ks.Type != VaultKeyStoreType && !ks.IsFile() {
+		return errors.New(InvalidKeyStoreType)
+	}
+	if !ks.IsFile() {
 		return nil
 	}
-	return errors.New(InvalidAuthentication)
+	if !c.InsecureDev {
+		return errors.New(InvalidInsecureDev)
+	}
+	if ks.Directory == nil || !isValidAbsFileUrl(ks.Directory) || !ks.Passphrase.IsConfigured() {
+		return errors.New(InvalidFileKeyStore)
+	}
+	if len(c.Connections) > 0 || c.Discovery.Enabled || c.TransitEngineName != "" || c.AccountDirectory.Scheme == VaultAccountDirectoryScheme {
+		return errors.New(InvalidFileKeyStoreUsage)
+	}
+	return nil
+}
+
+func (c VaultClientDiscovery) validate() error {
+	if strings.HasPrefix(c.Prefix, "/") || c.RefreshInterval < 0 {
+		return errors.New(InvalidDiscovery)
+	}
+	return nil
+}
+
+func (c VaultClientRetry) validate() error {
+	if c.InitialInterval < 0 || c.MaxInterval < 0 || (c.MaxInterval != 0 && c.InitialInterval > c.MaxInterval) {
+		return errors.New(InvalidRetryInterval)
+	}
+	if c.Multiplier != 0 && c.Multiplier < 1 {
+		return errors.New(InvalidRetryMultiplier)
+	}
+	if c.Jitter != nil && (*c.Jitter < 0 || *c.Jitter > 1) {
+		return errors.New(InvalidRetryJitter)
+	}
+	if c.MaxAttempts < 0 {
+		return errors.New(InvalidRetryMaxAttempts)
+	}
+	return nil
 }
 
 func (c VaultClientTLS) validate() error {
@@ -66,15 +173,47 @@ func (c VaultClientTLS) validate() error {
 }
 
 func (c NewAccount) Validate() error {
+	if c.SecretName != "" && c.TransitKeyName != "" {
+		return errors.New(InvalidTransitKeyName)
+	}
+	if c.IsTransitAccount() {
+		if len(c.ExtraFields) > 0 {
+			return errors.New(InvalidExtraFields)
+		}
+		if c.KVEngineName != "" {
+			return errors.New(InvalidNewAccountKVEngine)
+		}
+		if len(c.CustomMetadata) > 0 {
+			return errors.New(InvalidCustomMetadata)
+		}
+		return nil
+	}
 	if c.SecretName == "" {
 		return errors.New(InvalidSecretName)
 	}
 	if err := c.OverwriteProtection.validate(); err != nil {
 		return err
 	}
+	if !isValidCustomMetadata(c.CustomMetadata) {
+		return errors.New(InvalidCustomMetadata)
+	}
 	return nil
 }
 
+// isValidCustomMetadata checks the metadata is within Vault's custom_metadata limits, leaving room for the address
+// added by the plugin
+func isValidCustomMetadata(m map[string]string) bool {
+	if len(m) > 63 {
+		return false
+	}
+	for k, v := range m {
+		if k == "" || len(k) > 128 || len(v) > 512 || k == "address" {
+			return false
+		}
+	}
+	return true
+}
+
 func (c OverwriteProtection) validate() error {
 	if c.InsecureDisable && c.CurrentVersion != 0 {
 		return errors.New(InvalidOverwriteProtection)
@@ -85,3 +224,12 @@ func (c OverwriteProtection) validate() error {
 func isValidAbsFileUrl(u *url.URL) bool {
 	return u.Scheme == "file" && u.Host == "" && u.Path != ""
 }
+
+// IsUrlSet returns true if u is non-nil and not empty
+func IsUrlSet(u *url.URL) bool {
+	return u != nil && u.String() != ""
+}
+
+func isValidVaultAccountDirectory(u *url.URL) bool {
+	return u.Scheme == VaultAccountDirectoryScheme && u.Host != "" && u.RawQuery == "" && u.Fragment == ""
+}
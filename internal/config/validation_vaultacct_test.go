@@ -1,6 +1,8 @@
 package config
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -34,6 +36,84 @@ func TestNewAccount_Validate_SecretName_Invalid(t *testing.T) {
 	require.EqualError(t, err, wantErr)
 }
 
+func TestNewAccount_Validate_TransitKeyName_Valid(t *testing.T) {
+	conf := minimumValidNewAccountConfig()
+	conf.SecretName = ""
+	conf.TransitKeyName = "key"
+	err := conf.Validate()
+	require.NoError(t, err)
+}
+
+func TestNewAccount_Validate_TransitKeyName_Invalid(t *testing.T) {
+	conf := minimumValidNewAccountConfig()
+	conf.TransitKeyName = "key"
+	err := conf.Validate()
+	require.EqualError(t, err, InvalidTransitKeyName)
+}
+
+func TestNewAccount_Validate_KVEngineName(t *testing.T) {
+	conf := minimumValidNewAccountConfig()
+	conf.KVEngineName = "team-engine"
+	err := conf.Validate()
+	require.NoError(t, err)
+
+	conf.SecretName = ""
+	conf.TransitKeyName = "key"
+	err = conf.Validate()
+	require.EqualError(t, err, InvalidNewAccountKVEngine)
+}
+
+func TestNewAccount_Validate_ExtraFields(t *testing.T) {
+	conf := minimumValidNewAccountConfig()
+	conf.ExtraFields = map[string]string{"createdBy": "node1"}
+	err := conf.Validate()
+	require.NoError(t, err)
+
+	conf.SecretName = ""
+	conf.TransitKeyName = "key"
+	err = conf.Validate()
+	require.EqualError(t, err, InvalidExtraFields)
+}
+
+func TestNewAccount_Validate_CustomMetadata(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i < 64; i++ {
+		tooMany[strconv.Itoa(i)] = "v"
+	}
+
+	var metadata = map[string]struct {
+		metadata map[string]string
+		transit  bool
+		wantErr  bool
+	}{
+		"valid":         {metadata: map[string]string{"owner": "team1", "network": "net1"}},
+		"transit":       {metadata: map[string]string{"owner": "team1"}, transit: true, wantErr: true},
+		"too_many":      {metadata: tooMany, wantErr: true},
+		"empty_key":     {metadata: map[string]string{"": "v"}, wantErr: true},
+		"long_key":      {metadata: map[string]string{strings.Repeat("k", 129): "v"}, wantErr: true},
+		"long_value":    {metadata: map[string]string{"k": strings.Repeat("v", 513)}, wantErr: true},
+		"address_key":   {metadata: map[string]string{"address": "0x"}, wantErr: true},
+		"max_key_value": {metadata: map[string]string{strings.Repeat("k", 128): strings.Repeat("v", 512)}},
+	}
+
+	for name, tt := range metadata {
+		t.Run(name, func(t *testing.T) {
+			conf := minimumValidNewAccountConfig()
+			conf.CustomMetadata = tt.metadata
+			if tt.transit {
+				conf.SecretName = ""
+				conf.TransitKeyName = "key"
+			}
+			err := conf.Validate()
+			if tt.wantErr {
+				require.EqualError(t, err, InvalidCustomMetadata)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestNewAccount_Validate_OverwriteProtection_Valid(t *testing.T) {
 	var (
 		conf NewAccount
@@ -1,22 +1,17 @@
 package config
 
 import (
+	"io/ioutil"
 	"net/url"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
 	"github.com/stretchr/testify/require"
 )
 
-func envVar(t *testing.T, envVarURL string) *EnvironmentVariable {
-	u, err := url.Parse(envVarURL)
-	require.NoError(t, err)
-	env := EnvironmentVariable(*u)
-	return &env
-}
-
 func minimumValidClientConfig(t *testing.T) VaultClient {
-	var token EnvironmentVariable
 	vault, _ := url.Parse("http://vault:1111")
 	accountDirectory, _ := url.Parse("file:///path/to/dir")
 	emptyUrl, _ := url.Parse("")
@@ -26,9 +21,8 @@ func minimumValidClientConfig(t *testing.T) VaultClient {
 		KVEngineName:     "engine",
 		AccountDirectory: accountDirectory,
 		Authentication: VaultClientAuthentication{
-			Token:       &token,
-			RoleId:      envVar(t, "env://"+testutil.MY_ROLE_ID),
-			SecretId:    envVar(t, "env://"+testutil.MY_SECRET_ID),
+			RoleId:      SecretSource("env://" + testutil.MY_ROLE_ID),
+			SecretId:    SecretSource("env://" + testutil.MY_SECRET_ID),
 			ApprolePath: "myapprole",
 		},
 		TLS: VaultClientTLS{
@@ -39,6 +33,16 @@ func minimumValidClientConfig(t *testing.T) VaultClient {
 	}
 }
 
+// tempSecretFile writes val to a temp file and returns its file:// url and a func to remove it
+func tempSecretFile(t *testing.T, val string) (string, func()) {
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	_, err = f.WriteString(val)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return "file://" + f.Name(), func() { os.Remove(f.Name()) }
+}
+
 func TestVaultClient_Validate_MinimumValidConfig(t *testing.T) {
 	defer testutil.UnsetAll()
 	testutil.SetRoleID()
@@ -95,6 +99,39 @@ func TestVaultClient_Validate_VaultUrl_Invalid(t *testing.T) {
 	}
 }
 
+func TestVaultClient_Validate_FailoverAddresses(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var addresses = map[string]struct {
+		addresses []string
+		wantErr   string
+	}{
+		"none":      {},
+		"valid":     {addresses: []string{"https://vault-2:8200", "http://127.0.0.1:8200"}},
+		"no_scheme": {addresses: []string{"https://vault-2:8200", "vault-3"}, wantErr: InvalidFailoverAddresses},
+	}
+
+	for name, tt := range addresses {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			for _, a := range tt.addresses {
+				u, err := url.Parse(a)
+				require.NoError(t, err)
+				vaultClient.FailoverAddresses = append(vaultClient.FailoverAddresses, u)
+			}
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, gotErr)
+			} else {
+				require.EqualError(t, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestVaultClient_Validate_KVEngineName_Invalid(t *testing.T) {
 	wantErrMsg := "kvEngineName must be set"
 
@@ -105,23 +142,244 @@ func TestVaultClient_Validate_KVEngineName_Invalid(t *testing.T) {
 	require.EqualError(t, gotErr, wantErrMsg)
 }
 
-func TestVaultClient_Validate_AccountDirectory_Valid(t *testing.T) {
+func TestVaultClient_Validate_KVVersion(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var versions = map[string]struct {
+		version int
+		wantErr bool
+	}{
+		"detect": {version: 0},
+		"v1":     {version: 1},
+		"v2":     {version: 2},
+		"v3":     {version: 3, wantErr: true},
+		"neg":    {version: -1, wantErr: true},
+	}
+
+	for name, tt := range versions {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.KVVersion = tt.version
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr {
+				require.EqualError(t, gotErr, "kvVersion must be 1 or 2 if set")
+			} else {
+				require.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestVaultClient_Validate_SecretLayout(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var layouts = map[string]struct {
+		layout  VaultClientSecretLayout
+		wantErr string
+	}{
+		"default":                  {},
+		"address_keyed":            {layout: VaultClientSecretLayout{Type: "addressKeyed"}},
+		"named_field":              {layout: VaultClientSecretLayout{Type: "namedField"}},
+		"named_field_with_fields":  {layout: VaultClientSecretLayout{Type: "namedField", KeyField: "key", AddressField: "address"}},
+		"unknown_type":             {layout: VaultClientSecretLayout{Type: "other"}, wantErr: InvalidSecretLayoutType},
+		"address_keyed_key_field":  {layout: VaultClientSecretLayout{KeyField: "key"}, wantErr: InvalidSecretLayoutFields},
+		"address_keyed_addr_field": {layout: VaultClientSecretLayout{Type: "addressKeyed", AddressField: "address"}, wantErr: InvalidSecretLayoutFields},
+		"same_fields":              {layout: VaultClientSecretLayout{Type: "namedField", KeyField: "key", AddressField: "key"}, wantErr: InvalidSecretLayoutFields},
+	}
+
+	for name, tt := range layouts {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.SecretLayout = tt.layout
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, gotErr)
+			} else {
+				require.EqualError(t, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultClient_Validate_Discovery(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var discoveries = map[string]struct {
+		discovery VaultClientDiscovery
+		wantErr   string
+	}{
+		"disabled":         {},
+		"whole_engine":     {discovery: VaultClientDiscovery{Enabled: true}},
+		"prefix":           {discovery: VaultClientDiscovery{Enabled: true, Prefix: "accounts/", RefreshInterval: time.Minute}},
+		"absolute_prefix":  {discovery: VaultClientDiscovery{Enabled: true, Prefix: "/accounts"}, wantErr: InvalidDiscovery},
+		"negative_refresh": {discovery: VaultClientDiscovery{Enabled: true, RefreshInterval: -time.Second}, wantErr: InvalidDiscovery},
+	}
+
+	for name, tt := range discoveries {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Discovery = tt.discovery
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, gotErr)
+			} else {
+				require.EqualError(t, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultClient_Validate_HealthCheckInterval(t *testing.T) {
 	defer testutil.UnsetAll()
 	testutil.SetRoleID()
 	testutil.SetSecretID()
 
 	vaultClient := minimumValidClientConfig(t)
+	vaultClient.HealthCheckInterval = time.Minute
+	require.NoError(t, vaultClient.Validate())
 
-	acctDir, err := url.Parse("file:///absolute/path/to/dir")
-	require.NoError(t, err)
-	vaultClient.AccountDirectory = acctDir
+	vaultClient.HealthCheckInterval = -time.Second
+	require.EqualError(t, vaultClient.Validate(), InvalidHealthCheckInterval)
+}
+
+func TestVaultClient_Validate_KeyStore(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	keysDir, _ := url.Parse("file:///path/to/keys")
+	vaultAcctDir, _ := url.Parse("vault://engine/accounts/")
+	fileKeyStore := VaultClientKeyStore{Type: "file", Directory: keysDir, Passphrase: "env://DEV_PASSPHRASE"}
+
+	var keyStores = map[string]struct {
+		keyStore    VaultClientKeyStore
+		insecureDev bool
+		modify      func(c *VaultClient)
+		wantErr     string
+	}{
+		"default":            {},
+		"vault":              {keyStore: VaultClientKeyStore{Type: "vault"}},
+		"file":               {keyStore: fileKeyStore, insecureDev: true},
+		"unknown_type":       {keyStore: VaultClientKeyStore{Type: "other"}, wantErr: InvalidKeyStoreType},
+		"file_not_dev":       {keyStore: fileKeyStore, wantErr: InvalidInsecureDev},
+		"file_no_directory":  {keyStore: VaultClientKeyStore{Type: "file", Passphrase: "env://DEV_PASSPHRASE"}, insecureDev: true, wantErr: InvalidFileKeyStore},
+		"file_no_passphrase": {keyStore: VaultClientKeyStore{Type: "file", Directory: keysDir}, insecureDev: true, wantErr: InvalidFileKeyStore},
+		"file_discovery": {keyStore: fileKeyStore, insecureDev: true, wantErr: InvalidFileKeyStoreUsage, modify: func(c *VaultClient) {
+			c.Discovery.Enabled = true
+		}},
+		"file_transit": {keyStore: fileKeyStore, insecureDev: true, wantErr: InvalidFileKeyStoreUsage, modify: func(c *VaultClient) {
+			c.TransitEngineName = "transit"
+		}},
+		"file_vault_account_directory": {keyStore: fileKeyStore, insecureDev: true, wantErr: InvalidFileKeyStoreUsage, modify: func(c *VaultClient) {
+			c.AccountDirectory = vaultAcctDir
+		}},
+	}
+
+	for name, tt := range keyStores {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.KeyStore = tt.keyStore
+			vaultClient.InsecureDev = tt.insecureDev
+			if tt.modify != nil {
+				tt.modify(&vaultClient)
+			}
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, gotErr)
+			} else {
+				require.EqualError(t, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultClient_Validate_Connections(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	valid := func(name string) VaultConnection {
+		c := minimumValidClientConfig(t)
+		return VaultConnection{Name: name, Vault: c.Vault, Authentication: c.Authentication, TLS: c.TLS}
+	}
+	noURL := valid("dr")
+	noURL.Vault = &url.URL{}
+	noAuth := valid("dr")
+	noAuth.Authentication = VaultClientAuthentication{}
+
+	var connections = map[string]struct {
+		connections []VaultConnection
+		wantErr     string
+	}{
+		"none":           {},
+		"valid":          {connections: []VaultConnection{valid("dr"), valid("escrow")}},
+		"no_name":        {connections: []VaultConnection{valid("")}, wantErr: InvalidConnectionName},
+		"duplicate_name": {connections: []VaultConnection{valid("dr"), valid("dr")}, wantErr: InvalidConnectionName},
+		"invalid_url":    {connections: []VaultConnection{noURL}, wantErr: "connection dr: " + InvalidVaultUrl},
+		"no_auth":        {connections: []VaultConnection{noAuth}, wantErr: "connection dr: " + InvalidAuthentication},
+	}
+
+	for name, tt := range connections {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Connections = tt.connections
+
+			gotErr := vaultClient.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, gotErr)
+			} else {
+				require.EqualError(t, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultClient_Validate_TransitEngineName_Invalid(t *testing.T) {
+	wantErrMsg := "transitEngineName must be set if transitKeyType is set"
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.TransitKeyType = "ecdsa-p256k1"
 
 	gotErr := vaultClient.Validate()
-	require.NoError(t, gotErr)
+	require.EqualError(t, gotErr, wantErrMsg)
+}
+
+func TestVaultClient_Validate_AccountDirectory_Valid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+
+	acctDirUrls := []string{
+		"file:///absolute/path/to/dir",
+		"vault://engine/accounts/",
+		"vault://engine/",
+	}
+	for _, u := range acctDirUrls {
+		t.Run(u, func(t *testing.T) {
+			acctDir, err := url.Parse(u)
+			require.NoError(t, err)
+			vaultClient.AccountDirectory = acctDir
+
+			gotErr := vaultClient.Validate()
+			require.NoError(t, gotErr)
+		})
+	}
 }
 
 func TestVaultClient_Validate_AccountDirectory_Invalid(t *testing.T) {
-	wantErrMsg := "accountDirectory must be a valid absolute file url"
+	wantErrMsg := "accountDirectory must be a valid absolute file url or a vault://<engine>/<prefix> url"
 
 	acctDirUrls := []string{
 		"",
@@ -131,6 +389,8 @@ func TestVaultClient_Validate_AccountDirectory_Invalid(t *testing.T) {
 		"relative/no/scheme",
 		"/absolute/no/scheme",
 		"http://notfilescheme",
+		"vault:///noengine",
+		"vault://engine/accounts?version=1",
 	}
 	for _, u := range acctDirUrls {
 		t.Run(u, func(t *testing.T) {
@@ -147,7 +407,7 @@ func TestVaultClient_Validate_AccountDirectory_Invalid(t *testing.T) {
 }
 
 func TestVaultClient_Validate_AccountDirectory_NilInvalid(t *testing.T) {
-	wantErrMsg := "accountDirectory must be a valid absolute file url"
+	wantErrMsg := "accountDirectory must be a valid absolute file url or a vault://<engine>/<prefix> url"
 
 	vaultClient := minimumValidClientConfig(t)
 	vaultClient.AccountDirectory = nil
@@ -171,6 +431,13 @@ func TestVaultClient_Validate_Authentication_Valid(t *testing.T) {
 			approlePath: "",
 			setEnvFuncs: []func(){testutil.SetToken},
 		},
+		"token_literal": {
+			tokenUrl:    "s.tokenval",
+			roleIdUrl:   "",
+			secretIdUrl: "",
+			approlePath: "",
+			setEnvFuncs: []func(){},
+		},
 		"token_all_envs": {
 			tokenUrl:    "env://" + testutil.MY_TOKEN,
 			roleIdUrl:   "",
@@ -202,9 +469,9 @@ func TestVaultClient_Validate_Authentication_Valid(t *testing.T) {
 
 			vaultClient := minimumValidClientConfig(t)
 
-			vaultClient.Authentication.Token = envVar(t, tt.tokenUrl)
-			vaultClient.Authentication.RoleId = envVar(t, tt.roleIdUrl)
-			vaultClient.Authentication.SecretId = envVar(t, tt.secretIdUrl)
+			vaultClient.Authentication.Token = SecretSource(tt.tokenUrl)
+			vaultClient.Authentication.RoleId = SecretSource(tt.roleIdUrl)
+			vaultClient.Authentication.SecretId = SecretSource(tt.secretIdUrl)
 			vaultClient.Authentication.ApprolePath = tt.approlePath
 
 			gotErr := vaultClient.Validate()
@@ -217,7 +484,7 @@ func TestVaultClient_Validate_Authentication_Valid(t *testing.T) {
 }
 
 func TestVaultClient_Validate_Authentication_Invalid(t *testing.T) {
-	wantErrMsg := "authentication must contain roleId, secretId and approlePath OR only token, and the given environment variables must be set"
+	wantErrMsg := "authentication must contain exactly one complete method (token, approle: roleId, secretId and approlePath, kubernetes: role and path, jwt: role, path and jwt, cert: role or path, or tokenFile), and the given environment variables must be set"
 
 	var auths = map[string]struct {
 		tokenUrl    string
@@ -268,33 +535,92 @@ func TestVaultClient_Validate_Authentication_Invalid(t *testing.T) {
 			approlePath: "myapprole",
 			setEnvFuncs: []func(){testutil.SetToken, testutil.SetRoleID, testutil.SetSecretID},
 		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			for _, setEnvFunc := range tt.setEnvFuncs {
+				setEnvFunc()
+			}
+
+			vaultClient := minimumValidClientConfig(t)
+
+			vaultClient.Authentication.Token = SecretSource(tt.tokenUrl)
+			vaultClient.Authentication.RoleId = SecretSource(tt.roleIdUrl)
+			vaultClient.Authentication.SecretId = SecretSource(tt.secretIdUrl)
+			vaultClient.Authentication.ApprolePath = tt.approlePath
+
+			gotErr := vaultClient.Validate()
+
+			testutil.UnsetAll()
+
+			require.EqualError(t, gotErr, wantErrMsg)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_UnresolvableSource(t *testing.T) {
+	var auths = map[string]struct {
+		tokenUrl    string
+		roleIdUrl   string
+		secretIdUrl string
+		approlePath string
+		setEnvFuncs []func()
+		wantErr     string
+	}{
 		"token_no_env": {
 			tokenUrl:    "env://" + testutil.MY_TOKEN,
-			roleIdUrl:   "",
-			secretIdUrl: "",
-			approlePath: "",
 			setEnvFuncs: []func(){},
+			wantErr:     "unable to resolve token from env://MY_TOKEN: environment variable MY_TOKEN not set",
 		},
 		"token_incorrect_env": {
 			tokenUrl:    "env://" + testutil.MY_TOKEN,
-			roleIdUrl:   "",
-			secretIdUrl: "",
-			approlePath: "",
 			setEnvFuncs: []func(){testutil.SetRoleID, testutil.SetSecretID},
+			wantErr:     "unable to resolve token from env://MY_TOKEN: environment variable MY_TOKEN not set",
+		},
+		"token_relative_file": {
+			tokenUrl: "file://relative/path",
+			wantErr:  "unable to resolve token from file://relative/path: file:// source must be an absolute path, e.g. file:///path/to/file",
+		},
+		"token_missing_file": {
+			tokenUrl: "file:///does/not/exist",
+			wantErr:  "unable to resolve token from file:///does/not/exist: open /does/not/exist: no such file or directory",
+		},
+		"token_unsupported_scheme": {
+			tokenUrl: "http://vault:8200",
+			wantErr:  "unable to resolve token from http://vault:8200: unsupported scheme http, must be one of [env file vault-wrapped] or a literal value",
+		},
+		"token_vault_wrapped_literal": {
+			tokenUrl: "vault-wrapped://s.wrapping",
+			wantErr:  "unable to resolve token from vault-wrapped://s.wrapping: vault-wrapped:// must be followed by an env:// or file:// source, e.g. vault-wrapped://file:///path/to/token",
 		},
 		"approle_no_env": {
-			tokenUrl:    "",
 			roleIdUrl:   "env://" + testutil.MY_ROLE_ID,
 			secretIdUrl: "env://" + testutil.MY_SECRET_ID,
 			approlePath: "myapprole",
 			setEnvFuncs: []func(){},
+			wantErr:     "unable to resolve roleId from env://MY_ROLE_ID: environment variable MY_ROLE_ID not set",
 		},
 		"approle_incorrect_env": {
-			tokenUrl:    "",
 			roleIdUrl:   "env://" + testutil.MY_ROLE_ID,
 			secretIdUrl: "env://" + testutil.MY_SECRET_ID,
 			approlePath: "myapprole",
 			setEnvFuncs: []func(){testutil.SetToken},
+			wantErr:     "unable to resolve roleId from env://MY_ROLE_ID: environment variable MY_ROLE_ID not set",
+		},
+		"approle_no_secret_id_env": {
+			roleIdUrl:   "env://" + testutil.MY_ROLE_ID,
+			secretIdUrl: "env://" + testutil.MY_SECRET_ID,
+			approlePath: "myapprole",
+			setEnvFuncs: []func(){testutil.SetRoleID},
+			wantErr:     "unable to resolve secretId from env://MY_SECRET_ID: environment variable MY_SECRET_ID not set",
+		},
+		"approle_vault_wrapped_secret_id_no_env": {
+			roleIdUrl:   "env://" + testutil.MY_ROLE_ID,
+			secretIdUrl: "vault-wrapped://env://" + testutil.MY_SECRET_ID,
+			approlePath: "myapprole",
+			setEnvFuncs: []func(){testutil.SetRoleID},
+			wantErr:     "unable to resolve secretId from vault-wrapped://env://MY_SECRET_ID: environment variable MY_SECRET_ID not set",
 		},
 	}
 
@@ -306,16 +632,488 @@ func TestVaultClient_Validate_Authentication_Invalid(t *testing.T) {
 
 			vaultClient := minimumValidClientConfig(t)
 
-			vaultClient.Authentication.Token = envVar(t, tt.tokenUrl)
-			vaultClient.Authentication.RoleId = envVar(t, tt.roleIdUrl)
-			vaultClient.Authentication.SecretId = envVar(t, tt.secretIdUrl)
+			vaultClient.Authentication.Token = SecretSource(tt.tokenUrl)
+			vaultClient.Authentication.RoleId = SecretSource(tt.roleIdUrl)
+			vaultClient.Authentication.SecretId = SecretSource(tt.secretIdUrl)
 			vaultClient.Authentication.ApprolePath = tt.approlePath
 
 			gotErr := vaultClient.Validate()
 
 			testutil.UnsetAll()
 
-			require.EqualError(t, gotErr, wantErrMsg)
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_Kubernetes_Valid(t *testing.T) {
+	var auths = map[string]VaultClientKubernetesAuthentication{
+		"default_token_file": {
+			Role: "myrole",
+			Path: "kubernetes",
+		},
+		"token_file": {
+			Role:      "myrole",
+			Path:      "kubernetes",
+			TokenFile: &url.URL{Scheme: "file", Path: "/path/to/token"},
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.RoleId = ""
+			vaultClient.Authentication.SecretId = ""
+			vaultClient.Authentication.ApprolePath = ""
+			vaultClient.Authentication.Kubernetes = tt
+
+			gotErr := vaultClient.Validate()
+			require.NoError(t, gotErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_Kubernetes_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var auths = map[string]struct {
+		kubernetes  VaultClientKubernetesAuthentication
+		withApprole bool
+		wantErr     string
+	}{
+		"no_role": {
+			kubernetes: VaultClientKubernetesAuthentication{Path: "kubernetes"},
+			wantErr:    InvalidAuthentication,
+		},
+		"no_path": {
+			kubernetes: VaultClientKubernetesAuthentication{Role: "myrole"},
+			wantErr:    InvalidAuthentication,
+		},
+		"with_approle": {
+			kubernetes:  VaultClientKubernetesAuthentication{Role: "myrole", Path: "kubernetes"},
+			withApprole: true,
+			wantErr:     InvalidAuthentication,
+		},
+		"relative_token_file": {
+			kubernetes: VaultClientKubernetesAuthentication{
+				Role:      "myrole",
+				Path:      "kubernetes",
+				TokenFile: &url.URL{Scheme: "file", Host: "relative", Path: "/path"},
+			},
+			wantErr: InvalidKubernetesTokenFile,
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			if !tt.withApprole {
+				vaultClient.Authentication.RoleId = ""
+				vaultClient.Authentication.SecretId = ""
+				vaultClient.Authentication.ApprolePath = ""
+			}
+			vaultClient.Authentication.Kubernetes = tt.kubernetes
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_JWT_Valid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	jwtFile, cleanup := tempSecretFile(t, "jwtval")
+	defer cleanup()
+
+	var auths = map[string]string{
+		"env":  "env://" + testutil.MY_TOKEN,
+		"file": jwtFile,
+	}
+
+	for name, jwt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.RoleId = ""
+			vaultClient.Authentication.SecretId = ""
+			vaultClient.Authentication.ApprolePath = ""
+			vaultClient.Authentication.JWT = VaultClientJWTAuthentication{
+				Role: "myrole",
+				Path: "jwt",
+				Jwt:  SecretSource(jwt),
+			}
+
+			gotErr := vaultClient.Validate()
+			require.NoError(t, gotErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_JWT_Invalid(t *testing.T) {
+	var auths = map[string]struct {
+		role, path, jwtUrl string
+		wantErr            string
+	}{
+		"no_role": {
+			path:    "jwt",
+			jwtUrl:  "file:///path/to/jwt",
+			wantErr: InvalidAuthentication,
+		},
+		"no_path": {
+			role:    "myrole",
+			jwtUrl:  "file:///path/to/jwt",
+			wantErr: InvalidAuthentication,
+		},
+		"no_jwt": {
+			role:    "myrole",
+			path:    "jwt",
+			wantErr: InvalidAuthentication,
+		},
+		"env_not_set": {
+			role:    "myrole",
+			path:    "jwt",
+			jwtUrl:  "env://" + testutil.MY_TOKEN,
+			wantErr: "unable to resolve jwt.jwt from env://MY_TOKEN: environment variable MY_TOKEN not set",
+		},
+		"relative_file": {
+			role:    "myrole",
+			path:    "jwt",
+			jwtUrl:  "file://relative/path",
+			wantErr: "unable to resolve jwt.jwt from file://relative/path: file:// source must be an absolute path, e.g. file:///path/to/file",
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.RoleId = ""
+			vaultClient.Authentication.SecretId = ""
+			vaultClient.Authentication.ApprolePath = ""
+			vaultClient.Authentication.JWT = VaultClientJWTAuthentication{
+				Role: tt.role,
+				Path: tt.path,
+				Jwt:  SecretSource(tt.jwtUrl),
+			}
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_Cert_Valid(t *testing.T) {
+	var auths = map[string]VaultClientCertAuthentication{
+		"no_role": {
+			Path: "cert",
+		},
+		"role": {
+			Role: "myrole",
+			Path: "cert",
+		},
+		"default_path": {
+			Role: "myrole",
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.RoleId = ""
+			vaultClient.Authentication.SecretId = ""
+			vaultClient.Authentication.ApprolePath = ""
+			vaultClient.Authentication.Cert = tt
+			vaultClient.TLS.ClientCert, _ = url.Parse("file:///path/to/client.cert")
+			vaultClient.TLS.ClientKey, _ = url.Parse("file:///path/to/client.key")
+
+			gotErr := vaultClient.Validate()
+			require.NoError(t, gotErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_Cert_ExplicitMethod(t *testing.T) {
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.RoleId = ""
+	vaultClient.Authentication.SecretId = ""
+	vaultClient.Authentication.ApprolePath = ""
+	vaultClient.Authentication.Method = "cert"
+	vaultClient.TLS.ClientCert, _ = url.Parse("file:///path/to/client.cert")
+	vaultClient.TLS.ClientKey, _ = url.Parse("file:///path/to/client.key")
+
+	gotErr := vaultClient.Validate()
+	require.NoError(t, gotErr)
+}
+
+func TestVaultClient_Validate_Authentication_Cert_Invalid(t *testing.T) {
+	var auths = map[string]struct {
+		cert       VaultClientCertAuthentication
+		clientCert string
+		clientKey  string
+		wantErr    string
+	}{
+		"no_client_cert": {
+			cert:      VaultClientCertAuthentication{Path: "cert"},
+			clientKey: "file:///path/to/client.key",
+			wantErr:   InvalidCertAuthentication,
+		},
+		"no_client_key": {
+			cert:       VaultClientCertAuthentication{Path: "cert"},
+			clientCert: "file:///path/to/client.cert",
+			wantErr:    InvalidCertAuthentication,
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.RoleId = ""
+			vaultClient.Authentication.SecretId = ""
+			vaultClient.Authentication.ApprolePath = ""
+			vaultClient.Authentication.Cert = tt.cert
+			vaultClient.TLS.ClientCert, _ = url.Parse(tt.clientCert)
+			vaultClient.TLS.ClientKey, _ = url.Parse(tt.clientKey)
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_TokenFile_Valid(t *testing.T) {
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.RoleId = ""
+	vaultClient.Authentication.SecretId = ""
+	vaultClient.Authentication.ApprolePath = ""
+	vaultClient.Authentication.TokenFile, _ = url.Parse("file:///path/to/sink")
+
+	gotErr := vaultClient.Validate()
+	require.NoError(t, gotErr)
+}
+
+func TestVaultClient_Validate_Authentication_TokenFile_PollInterval(t *testing.T) {
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.RoleId = ""
+	vaultClient.Authentication.SecretId = ""
+	vaultClient.Authentication.ApprolePath = ""
+	vaultClient.Authentication.TokenFile, _ = url.Parse("file:///path/to/sink")
+
+	vaultClient.Authentication.TokenFilePollInterval = 5 * time.Second
+	require.NoError(t, vaultClient.Validate())
+
+	vaultClient.Authentication.TokenFilePollInterval = -time.Second
+	require.EqualError(t, vaultClient.Validate(), InvalidTokenFilePollInterval)
+}
+
+func TestVaultClient_Validate_Authentication_TokenFile_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var auths = map[string]struct {
+		tokenFile   string
+		tokenUrl    string
+		withApprole bool
+		wantErr     string
+	}{
+		"relative": {
+			tokenFile: "file://relative/path",
+			wantErr:   InvalidTokenFile,
+		},
+		"not_file": {
+			tokenFile: "env://" + testutil.MY_TOKEN,
+			wantErr:   InvalidTokenFile,
+		},
+		"with_token": {
+			tokenFile: "file:///path/to/sink",
+			tokenUrl:  "env://" + testutil.MY_TOKEN,
+			wantErr:   InvalidAuthentication,
+		},
+		"with_approle": {
+			tokenFile:   "file:///path/to/sink",
+			withApprole: true,
+			wantErr:     InvalidAuthentication,
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			if !tt.withApprole {
+				vaultClient.Authentication.RoleId = ""
+				vaultClient.Authentication.SecretId = ""
+				vaultClient.Authentication.ApprolePath = ""
+			}
+			vaultClient.Authentication.Token = SecretSource(tt.tokenUrl)
+			vaultClient.Authentication.TokenFile, _ = url.Parse(tt.tokenFile)
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_WrappedSecretId_Valid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	wrappedFile, cleanup := tempSecretFile(t, "s.wrapping")
+	defer cleanup()
+
+	for _, wrappedUrl := range []string{"env://" + testutil.MY_SECRET_ID, wrappedFile} {
+		t.Run(wrappedUrl, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.SecretId = SecretSource("vault-wrapped://" + wrappedUrl)
+
+			gotErr := vaultClient.Validate()
+			require.NoError(t, gotErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_WrappedSecretId_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+
+	var auths = map[string]struct {
+		wrappedUrl string
+		wantErr    string
+	}{
+		"env_not_set": {
+			wrappedUrl: "vault-wrapped://env://" + testutil.MY_TOKEN,
+			wantErr:    "unable to resolve secretId from vault-wrapped://env://MY_TOKEN: environment variable MY_TOKEN not set",
+		},
+		"relative_file": {
+			wrappedUrl: "vault-wrapped://file://relative/path",
+			wantErr:    "unable to resolve secretId from vault-wrapped://file://relative/path: file:// source must be an absolute path, e.g. file:///path/to/file",
+		},
+	}
+
+	for name, tt := range auths {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.SecretId = SecretSource(tt.wrappedUrl)
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Authentication_ExplicitMethod_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var methods = map[string]struct {
+		method  string
+		wantErr string
+	}{
+		"unknown": {
+			method:  "unknown",
+			wantErr: "unsupported authentication method unknown, must be one of [approle cert jwt kubernetes token tokenFile]",
+		},
+		"other_method_configured": {
+			method:  "kubernetes",
+			wantErr: InvalidAuthentication,
+		},
+	}
+
+	for name, tt := range methods {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.Method = tt.method
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
+		})
+	}
+}
+
+func TestVaultClient_Validate_Retry_Valid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.Retry = VaultClientRetry{
+		InitialInterval: time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2,
+		Jitter:          jitter(0.5),
+		MaxAttempts:     5,
+	}
+
+	gotErr := vaultClient.Validate()
+	require.NoError(t, gotErr)
+}
+
+func jitter(f float64) *float64 {
+	return &f
+}
+
+func TestVaultClient_Validate_Retry_NoJitter(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := minimumValidClientConfig(t)
+	vaultClient.Authentication.Retry = VaultClientRetry{Jitter: jitter(0)}
+
+	gotErr := vaultClient.Validate()
+	require.NoError(t, gotErr)
+}
+
+func TestVaultClient_Validate_Retry_Invalid(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	var retries = map[string]struct {
+		retry   VaultClientRetry
+		wantErr string
+	}{
+		"negative_initial_interval": {
+			retry:   VaultClientRetry{InitialInterval: -time.Second},
+			wantErr: InvalidRetryInterval,
+		},
+		"negative_max_interval": {
+			retry:   VaultClientRetry{MaxInterval: -time.Second},
+			wantErr: InvalidRetryInterval,
+		},
+		"initial_greater_than_max": {
+			retry:   VaultClientRetry{InitialInterval: time.Minute, MaxInterval: time.Second},
+			wantErr: InvalidRetryInterval,
+		},
+		"multiplier_less_than_one": {
+			retry:   VaultClientRetry{Multiplier: 0.5},
+			wantErr: InvalidRetryMultiplier,
+		},
+		"negative_jitter": {
+			retry:   VaultClientRetry{Jitter: jitter(-0.1)},
+			wantErr: InvalidRetryJitter,
+		},
+		"jitter_greater_than_one": {
+			retry:   VaultClientRetry{Jitter: jitter(1.1)},
+			wantErr: InvalidRetryJitter,
+		},
+		"negative_max_attempts": {
+			retry:   VaultClientRetry{MaxAttempts: -1},
+			wantErr: InvalidRetryMaxAttempts,
+		},
+	}
+
+	for name, tt := range retries {
+		t.Run(name, func(t *testing.T) {
+			vaultClient := minimumValidClientConfig(t)
+			vaultClient.Authentication.Retry = tt.retry
+
+			gotErr := vaultClient.Validate()
+			require.EqualError(t, gotErr, tt.wantErr)
 		})
 	}
 }
@@ -1,32 +1,125 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
+// LatestSecretVersion can be used as an account file's VaultAccount.SecretVersion instead of a version number so that
+// the account uses the current version of its secret, which is resolved each time the account is unlocked.  It is
+// stored as SecretVersion 0.
+const LatestSecretVersion = "latest"
+
 type AccountFile struct {
-	Path     string
-	Contents AccountFileJSON
+	Path       string
+	Contents   AccountFileJSON
+	Discovered bool // the account was discovered by listing secrets in Vault and has no account file
 }
 
 type AccountFileJSON struct {
-	Address      string
-	VaultAccount vaultAccountJSON
-	Version      int
+	Address        string
+	Connection     string `json:",omitempty"` // the name of the Vault connection the account is stored in, the default connection if empty
+	VaultAccount   vaultAccountJSON
+	TransitAccount *transitAccountJSON `json:",omitempty"`
+	Version        int
 }
 
 type vaultAccountJSON struct {
 	SecretName    string
-	SecretVersion int64
+	SecretVersion int64  // 0 uses the current version of the secret
+	KVEngineName  string `json:",omitempty"` // the KV engine the secret is stored in, the configured kvEngineName if empty
+}
+
+// UnmarshalJSON accepts a SecretVersion of "latest" as well as a version number
+func (c *vaultAccountJSON) UnmarshalJSON(b []byte) error {
+	type plain vaultAccountJSON
+	aux := struct {
+		*plain
+		SecretVersion json.RawMessage
+	}{
+		plain: (*plain)(c),
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if len(aux.SecretVersion) == 0 || string(aux.SecretVersion) == "null" {
+		return nil
+	}
+
+	var latest string
+	if err := json.Unmarshal(aux.SecretVersion, &latest); err == nil {
+		if latest != LatestSecretVersion {
+			return fmt.Errorf("invalid SecretVersion %q, must be a version number or %q", latest, LatestSecretVersion)
+		}
+		c.SecretVersion = 0
+		return nil
+	}
+	if err := json.Unmarshal(aux.SecretVersion, &c.SecretVersion); err != nil {
+		return fmt.Errorf("invalid SecretVersion %v, must be a version number or %q", string(aux.SecretVersion), LatestSecretVersion)
+	}
+	if c.SecretVersion < 0 {
+		return errors.New("invalid SecretVersion, must not be negative")
+	}
+	return nil
+}
+
+// transitAccountJSON identifies a Transit secret engine key.  The private key for Transit-backed accounts never leaves
+// Vault.
+type transitAccountJSON struct {
+	KeyName    string
+	KeyVersion int64
+}
+
+// IsTransitAccount returns true if the account is backed by a Transit secret engine key instead of a KV secret.
+func (c *AccountFileJSON) IsTransitAccount() bool {
+	return c.TransitAccount != nil
+}
+
+// KVEngine returns the name of the KV engine the account's secret is stored in, or defaultName if the account does not
+// name its own engine
+func (c *AccountFileJSON) KVEngine(defaultName string) string {
+	if c.VaultAccount.KVEngineName != "" {
+		return c.VaultAccount.KVEngineName
+	}
+	return defaultName
+}
+
+// IsLatestSecretVersion returns true if the account uses the current version of its KV secret instead of a fixed
+// version
+func (c *AccountFileJSON) IsLatestSecretVersion() bool {
+	return !c.IsTransitAccount() && c.VaultAccount.SecretVersion == 0
 }
 
-func (c *AccountFileJSON) AccountURL(vaultURL, kvEngineName string) (*url.URL, error) {
+// AccountURL returns the URL of the account's secret or key.  If a Vault Enterprise namespace is provided it is
+// included in the path so that accounts with the same secret name in different namespaces have different URLs.  KV v1
+// secrets are unversioned so, if kvVersion is 1, the URL does not include a version.  Accounts using the current version
+// of their secret have a version of latest.
+func (c *AccountFileJSON) AccountURL(vaultURL, namespace, kvEngineName string, kvVersion int, transitEngineName string) (*url.URL, error) {
 	u, err := url.Parse(vaultURL)
 	if err != nil {
 		return nil, err
 	}
-	acctUrl, err := u.Parse(fmt.Sprintf("v1/%v/data/%v?version=%v", kvEngineName, c.VaultAccount.SecretName, c.VaultAccount.SecretVersion))
+	prefix := "v1/"
+	if namespace = strings.Trim(namespace, "/"); namespace != "" {
+		prefix = fmt.Sprintf("v1/%v/", namespace)
+	}
+	var path string
+	if c.IsTransitAccount() {
+		path = fmt.Sprintf("%v%v/keys/%v?version=%v", prefix, transitEngineName, c.TransitAccount.KeyName, c.TransitAccount.KeyVersion)
+	} else if kvVersion == 1 {
+		path = fmt.Sprintf("%v%v/%v", prefix, kvEngineName, c.VaultAccount.SecretName)
+	} else {
+		version := strconv.FormatInt(c.VaultAccount.SecretVersion, 10)
+		if c.IsLatestSecretVersion() {
+			version = LatestSecretVersion
+		}
+		path = fmt.Sprintf("%v%v/data/%v?version=%v", prefix, kvEngineName, c.VaultAccount.SecretName, version)
+	}
+	acctUrl, err := u.Parse(path)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +128,18 @@ func (c *AccountFileJSON) AccountURL(vaultURL, kvEngineName string) (*url.URL, e
 
 type NewAccount struct {
 	SecretName          string
+	TransitKeyName      string
 	OverwriteProtection OverwriteProtection
+	KVEngineName        string            // the KV engine to store the secret in, the configured kvEngineName if empty
+	Connection          string            // the name of the Vault connection to store the account in, the default connection if empty
+	ExtraFields         map[string]string // additional fields written to the secret's data alongside the key, e.g. createdBy
+	CustomMetadata      map[string]string // written to the secret's KV v2 custom_metadata along with the account's address
+}
+
+// IsTransitAccount returns true if the new account should be created as a Transit secret engine key instead of a KV
+// secret.
+func (c *NewAccount) IsTransitAccount() bool {
+	return c.TransitKeyName != ""
 }
 
 type OverwriteProtection struct {
@@ -47,10 +151,27 @@ func (c *NewAccount) AccountFile(path string, address string, secretVersion int6
 	return AccountFile{
 		Path: path,
 		Contents: AccountFileJSON{
-			Address: address,
+			Address:    address,
+			Connection: c.Connection,
 			VaultAccount: vaultAccountJSON{
 				SecretName:    c.SecretName,
 				SecretVersion: secretVersion,
+				KVEngineName:  c.KVEngineName,
+			},
+			Version: 1,
+		},
+	}
+}
+
+func (c *NewAccount) TransitAccountFile(path string, address string, keyVersion int64) AccountFile {
+	return AccountFile{
+		Path: path,
+		Contents: AccountFileJSON{
+			Address:    address,
+			Connection: c.Connection,
+			TransitAccount: &transitAccountJSON{
+				KeyName:    c.TransitKeyName,
+				KeyVersion: keyVersion,
 			},
 			Version: 1,
 		},
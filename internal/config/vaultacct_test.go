@@ -14,6 +14,12 @@ func TestNewAccount_UnmarshalJSON(t *testing.T) {
 		"overwriteProtection": {
 			"insecureDisable": true,
 			"currentVersion": 10
+		},
+		"extraFields": {
+			"createdBy": "node1"
+		},
+		"customMetadata": {
+			"owner": "team1"
 		}
 	}`)
 
@@ -23,6 +29,8 @@ func TestNewAccount_UnmarshalJSON(t *testing.T) {
 			InsecureDisable: true,
 			CurrentVersion:  10,
 		},
+		ExtraFields:    map[string]string{"createdBy": "node1"},
+		CustomMetadata: map[string]string{"owner": "team1"},
 	}
 
 	var got NewAccount
@@ -47,8 +55,146 @@ func TestAccountFileJSON_AccountURL(t *testing.T) {
 
 	want, _ := url.Parse("http://vault:1111/v1/engine/data/path?version=10")
 
-	got, err := conf.AccountURL(vaultUrl, "engine")
+	got, err := conf.AccountURL(vaultUrl, "", "engine", 2, "transit")
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestAccountFileJSON_UnmarshalJSON_SecretVersion(t *testing.T) {
+	var tests = map[string]struct {
+		secretVersion string
+		want          int64
+		wantErr       string
+	}{
+		"number":   {secretVersion: `4`, want: 4},
+		"latest":   {secretVersion: `"latest"`, want: 0},
+		"zero":     {secretVersion: `0`, want: 0},
+		"null":     {secretVersion: `null`, want: 0},
+		"invalid":  {secretVersion: `"newest"`, wantErr: `invalid SecretVersion "newest", must be a version number or "latest"`},
+		"fraction": {secretVersion: `1.5`, wantErr: `invalid SecretVersion 1.5, must be a version number or "latest"`},
+		"negative": {secretVersion: `-1`, wantErr: "invalid SecretVersion, must not be negative"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := []byte(`{"Address":"hexpubkey","VaultAccount":{"SecretName":"path","SecretVersion":` + tt.secretVersion + `,"KVEngineName":"team-engine"},"Version":1}`)
+
+			var got AccountFileJSON
+			err := json.Unmarshal(b, &got)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, vaultAccountJSON{SecretName: "path", SecretVersion: tt.want, KVEngineName: "team-engine"}, got.VaultAccount)
+			require.Equal(t, tt.want == 0, got.IsLatestSecretVersion())
+		})
+	}
+}
+
+func TestAccountFileJSON_AccountURL_LatestSecretVersion(t *testing.T) {
+	conf := AccountFileJSON{
+		Address: "hexpubkey",
+		VaultAccount: vaultAccountJSON{
+			SecretName: "path",
+		},
+		Version: 1,
+	}
+
+	want, _ := url.Parse("http://vault:1111/v1/engine/data/path?version=latest")
+
+	got, err := conf.AccountURL("http://vault:1111", "", "engine", 2, "transit")
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestAccountFileJSON_AccountURL_TransitAccount(t *testing.T) {
+	conf := AccountFileJSON{
+		Address: "hexpubkey",
+		TransitAccount: &transitAccountJSON{
+			KeyName:    "key",
+			KeyVersion: 2,
+		},
+		Version: 1,
+	}
+
+	vaultUrl := "http://vault:1111"
+
+	want, _ := url.Parse("http://vault:1111/v1/transit/keys/key?version=2")
+
+	got, err := conf.AccountURL(vaultUrl, "", "engine", 2, "transit")
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestAccountFileJSON_AccountURL_Namespace(t *testing.T) {
+	conf := AccountFileJSON{
+		Address: "hexpubkey",
+		VaultAccount: vaultAccountJSON{
+			SecretName:    "path",
+			SecretVersion: 10,
+		},
+		Version: 1,
+	}
+
+	vaultUrl := "http://vault:1111"
 
+	want, _ := url.Parse("http://vault:1111/v1/ns1/network1/engine/data/path?version=10")
+
+	got, err := conf.AccountURL(vaultUrl, "ns1/network1/", "engine", 2, "transit")
 	require.NoError(t, err)
 	require.Equal(t, want, got)
+
+	other, err := conf.AccountURL(vaultUrl, "ns1/network2", "engine", 2, "transit")
+	require.NoError(t, err)
+	require.NotEqual(t, got.String(), other.String())
+}
+
+func TestAccountFileJSON_AccountURL_KVv1(t *testing.T) {
+	conf := AccountFileJSON{
+		Address: "hexpubkey",
+		VaultAccount: vaultAccountJSON{
+			SecretName: "path",
+		},
+		Version: 1,
+	}
+
+	vaultUrl := "http://vault:1111"
+
+	want, _ := url.Parse("http://vault:1111/v1/engine/path")
+
+	got, err := conf.AccountURL(vaultUrl, "", "engine", 1, "transit")
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestAccountFileJSON_KVEngine(t *testing.T) {
+	conf := AccountFileJSON{
+		VaultAccount: vaultAccountJSON{
+			SecretName: "path",
+		},
+	}
+	require.Equal(t, "default", conf.KVEngine("default"))
+
+	conf.VaultAccount.KVEngineName = "team-engine"
+	require.Equal(t, "team-engine", conf.KVEngine("default"))
+}
+
+func TestAccountFileJSON_MarshalJSON_OmitsDefaults(t *testing.T) {
+	newAcct := NewAccount{SecretName: "path"}
+
+	got, err := json.Marshal(newAcct.AccountFile("", "hexpubkey", 1).Contents)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Address":"hexpubkey","VaultAccount":{"SecretName":"path","SecretVersion":1},"Version":1}`, string(got))
+
+	newAcct.KVEngineName = "team-engine"
+	newAcct.Connection = "dr"
+
+	got, err = json.Marshal(newAcct.AccountFile("", "hexpubkey", 1).Contents)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Address":"hexpubkey","Connection":"dr","VaultAccount":{"SecretName":"path","SecretVersion":1,"KVEngineName":"team-engine"},"Version":1}`, string(got))
 }
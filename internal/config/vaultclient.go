@@ -3,45 +3,195 @@ package config
 import (
 	"encoding/json"
 	"net/url"
-	"os"
 	"strings"
+	"time"
 )
 
 type VaultClient struct {
-	Vault            *url.URL
-	KVEngineName     string // the path of the K/V v2 secret engine
-	AccountDirectory *url.URL
-	Unlock           []string
-	Authentication   VaultClientAuthentication
-	TLS              VaultClientTLS
+	Vault                     *url.URL
+	Namespace                 string // the Vault Enterprise namespace used for all requests, optional
+	KVEngineName              string // the path of the K/V secret engine
+	KVVersion                 int    // the version of the K/V secret engine (1 or 2), detected from Vault if not set
+	TransitEngineName         string // the path of the Transit secret engine, only required if using Transit-backed accounts
+	TransitKeyType            string // the key type to use when creating new Transit-backed accounts
+	AccountDirectory          *url.URL
+	Unlock                    []string
+	WarnOnMissingCapabilities bool // log a warning instead of failing if the token is missing required KV capabilities
+	SecretLayout              VaultClientSecretLayout
+	Discovery                 VaultClientDiscovery
+	HealthCheckInterval       time.Duration // how often to check the health of the accounts' secrets, only checked on demand if 0
+	KeyStore                  VaultClientKeyStore
+	InsecureDev               bool // allow insecure development-only features, e.g. the file key store
+	Authentication            VaultClientAuthentication
+	TLS                       VaultClientTLS
+	Connections               []VaultConnection // additional named Vault clusters that account files can reference
+	FailoverAddresses         []*url.URL        // Vault addresses to fail over to, in order, if vault is unavailable
 }
 
-type EnvironmentVariable url.URL
-
-func (e EnvironmentVariable) Get() string {
-	u := url.URL(e)
-	return os.Getenv(u.Host)
+// VaultConnection is an additional named Vault cluster that accounts can be stored in.  Each connection has its own
+// URL, TLS and authentication.  Engine names not set on the connection default to those of the VaultClient.
+type VaultConnection struct {
+	Name              string
+	Vault             *url.URL
+	FailoverAddresses []*url.URL // Vault addresses to fail over to, in order, if vault is unavailable
+	Namespace         string     // the Vault Enterprise namespace used for all requests to the connection, optional
+	KVEngineName      string     // the path of the K/V secret engine, defaults to VaultClient.KVEngineName
+	KVVersion         int        // the version of the K/V secret engine (1 or 2), detected from Vault if not set
+	TransitEngineName string     // the path of the Transit secret engine, defaults to VaultClient.TransitEngineName
+	TransitKeyType    string     // the key type to use when creating new Transit-backed accounts, defaults to VaultClient.TransitKeyType
+	Authentication    VaultClientAuthentication
+	TLS               VaultClientTLS
 }
 
-func (e EnvironmentVariable) IsSet() bool {
-	u := url.URL(e)
-	if u.Host == "" {
-		return false
+// ForConnection returns the config of the client for the named connection: c with the connection's URL, namespace,
+// engines, TLS and authentication.  The returned config has no Connections, accounts to unlock or Discovery.
+func (c VaultClient) ForConnection(conn VaultConnection) VaultClient {
+	c.Vault = conn.Vault
+	c.FailoverAddresses = conn.FailoverAddresses
+	c.Namespace = conn.Namespace
+	c.KVVersion = conn.KVVersion
+	if conn.KVEngineName != "" {
+		c.KVEngineName = conn.KVEngineName
+	}
+	if conn.TransitEngineName != "" {
+		c.TransitEngineName = conn.TransitEngineName
+	}
+	if conn.TransitKeyType != "" {
+		c.TransitKeyType = conn.TransitKeyType
 	}
-	_, b := os.LookupEnv(u.Host)
-	return b
+	c.Authentication = conn.Authentication
+	c.TLS = conn.TLS
+	c.Unlock = nil
+	c.Connections = nil
+	c.Discovery = VaultClientDiscovery{}
+	return c
+}
+
+// Addresses returns the Vault address followed by the FailoverAddresses
+func (c VaultClient) Addresses() []*url.URL {
+	return append([]*url.URL{c.Vault}, c.FailoverAddresses...)
+}
+
+// VaultAccountDirectoryScheme is the scheme of an AccountDirectory that stores account files as secrets in a KV engine,
+// e.g. vault://<engine>/<prefix>
+const VaultAccountDirectoryScheme = "vault"
+
+const (
+	AddressKeyedSecretLayout = "addressKeyed"
+	NamedFieldSecretLayout   = "namedField"
+)
+
+// VaultClientSecretLayout configures how account keys are stored in the data of KV secrets.  Fields in the data not
+// used by the layout are ignored, so secrets can contain additional metadata.
+type VaultClientSecretLayout struct {
+	Type         string // addressKeyed (the default) stores the key under the account's hex address, namedField stores the key under KeyField
+	KeyField     string // the field containing the hex private key when using the namedField layout, defaults to privateKey
+	AddressField string // optional, the field containing the account's hex address when using the namedField layout
 }
 
-func (e EnvironmentVariable) String() string {
-	u := url.URL(e)
-	return u.String()
+// IsNamedField returns true if keys are stored in a named field instead of under the account's address
+func (c VaultClientSecretLayout) IsNamedField() bool {
+	return c.Type == NamedFieldSecretLayout
+}
+
+// VaultClientDiscovery configures the discovery of accounts by listing the secrets in the KV engine.  Discovered
+// accounts are held in memory alongside the accounts in the account directory and do not have account files.
+type VaultClientDiscovery struct {
+	Enabled         bool
+	Prefix          string        // the path in the KV engine to list secrets under, e.g. accounts/, the whole engine if empty
+	RefreshInterval time.Duration // how often to list the secrets again, accounts are only discovered at startup if 0
+	ReadSecretData  bool          // read the address from the secret's data, i.e. alongside the key, if it is not in the secret's custom_metadata
+}
+
+const (
+	VaultKeyStoreType = "vault"
+	FileKeyStoreType  = "file"
+)
+
+// VaultClientKeyStore configures where account keys are stored.  By default keys are stored in the KV engine.  The
+// file key store encrypts keys in local files so that Vault is not needed, and must only be used for development and
+// test networks.
+type VaultClientKeyStore struct {
+	Type       string       // vault (the default) or file
+	Directory  *url.URL     // the absolute file:// directory the file key store writes keys to
+	Passphrase SecretSource // the passphrase the file key store's encryption keys are derived from
+}
+
+// IsFile returns true if keys are stored using the file key store instead of Vault
+func (c VaultClientKeyStore) IsFile() bool {
+	return c.Type == FileKeyStoreType
 }
 
 type VaultClientAuthentication struct {
-	Token       *EnvironmentVariable
-	RoleId      *EnvironmentVariable
-	SecretId    *EnvironmentVariable
-	ApprolePath string
+	Method                string // the name of the registered authentication method to use, inferred from the set fields if empty
+	Namespace             string // the Vault Enterprise namespace of the auth method, if different to VaultClient.Namespace
+	Token                 SecretSource
+	TokenFile             *url.URL      // a token sink file, e.g. written by Vault Agent, that is watched for changes
+	TokenFilePollInterval time.Duration // how often TokenFile is checked for changes, the plugin default if 0
+	RoleId                SecretSource
+	SecretId              SecretSource
+	ApprolePath           string
+	Kubernetes            VaultClientKubernetesAuthentication
+	JWT                   VaultClientJWTAuthentication
+	Cert                  VaultClientCertAuthentication
+	Retry                 VaultClientRetry
+}
+
+// VaultClientRetry configures the exponential backoff used when re-authenticating with Vault fails.  Zero values use
+// the plugin defaults.
+type VaultClientRetry struct {
+	InitialInterval time.Duration // the delay before the first retry
+	MaxInterval     time.Duration // the maximum delay between retries
+	Multiplier      float64       // the factor the delay is increased by after each failed attempt
+	Jitter          *float64      // the randomisation factor applied to each delay, between 0 and 1.  0 disables jitter.
+	MaxAttempts     int           // the number of attempts before giving up, 0 retries indefinitely
+}
+
+// VaultClientCertAuthentication configures login using the Vault TLS certificate auth method.  The client certificate
+// configured in VaultClientTLS is used as the identity.
+type VaultClientCertAuthentication struct {
+	Role string // optional, the name of the certificate role to login as
+	Path string // optional, the path the TLS certificate auth method is mounted at, cert if not set
+}
+
+// IsSet returns true if TLS certificate authentication has been configured
+func (c VaultClientCertAuthentication) IsSet() bool {
+	return c.Role != "" || c.Path != ""
+}
+
+// IsTokenFileSet returns true if a token sink file has been configured
+func (c VaultClientAuthentication) IsTokenFileSet() bool {
+	return c.TokenFile != nil && c.TokenFile.String() != ""
+}
+
+// IsWrappedSecretIdSet returns true if the AppRole secret ID is provided as a response-wrapping token using a
+// vault-wrapped:// SecretId
+func (c VaultClientAuthentication) IsWrappedSecretIdSet() bool {
+	return c.SecretId.IsVaultWrapped()
+}
+
+// VaultClientJWTAuthentication configures login using the Vault JWT/OIDC auth method
+type VaultClientJWTAuthentication struct {
+	Role string
+	Path string       // the path the JWT auth method is mounted at
+	Jwt  SecretSource // the JWT, re-read each time the client authenticates
+}
+
+// IsSet returns true if JWT authentication has been configured
+func (c VaultClientJWTAuthentication) IsSet() bool {
+	return c.Role != "" || c.Path != "" || c.Jwt.IsConfigured()
+}
+
+// VaultClientKubernetesAuthentication configures login using the Vault Kubernetes auth method
+type VaultClientKubernetesAuthentication struct {
+	Role      string
+	Path      string   // the path the Kubernetes auth method is mounted at
+	TokenFile *url.URL // the projected service account token, defaults to the standard in-cluster location
+}
+
+// IsSet returns true if Kubernetes authentication has been configured
+func (c VaultClientKubernetesAuthentication) IsSet() bool {
+	return c.Role != "" || c.Path != "" || (c.TokenFile != nil && c.TokenFile.String() != "")
 }
 
 type VaultClientTLS struct {
@@ -51,19 +201,120 @@ type VaultClientTLS struct {
 }
 
 type vaultClientJSON struct {
-	Vault            string
-	KVEngineName     string
-	AccountDirectory string
-	Unlock           []string
-	Authentication   vaultClientAuthenticationJSON
-	Tls              vaultClientTLSJSON
+	Vault                     string
+	FailoverAddresses         []string `json:",omitempty"`
+	Namespace                 string
+	KVEngineName              string
+	KVVersion                 int
+	TransitEngineName         string
+	TransitKeyType            string
+	AccountDirectory          string
+	Unlock                    []string
+	WarnOnMissingCapabilities bool
+	SecretLayout              vaultClientSecretLayoutJSON
+	Discovery                 vaultClientDiscoveryJSON
+	HealthCheckInterval       string
+	KeyStore                  vaultClientKeyStoreJSON
+	InsecureDev               bool
+	Authentication            vaultClientAuthenticationJSON
+	Tls                       vaultClientTLSJSON
+	Connections               []vaultConnectionJSON `json:",omitempty"`
+}
+
+type vaultConnectionJSON struct {
+	Name              string
+	Vault             string
+	FailoverAddresses []string `json:",omitempty"`
+	Namespace         string
+	KVEngineName      string
+	KVVersion         int
+	TransitEngineName string
+	TransitKeyType    string
+	Authentication    vaultClientAuthenticationJSON
+	Tls               vaultClientTLSJSON
+}
+
+type vaultClientSecretLayoutJSON struct {
+	Type         string
+	KeyField     string
+	AddressField string
+}
+
+type vaultClientDiscoveryJSON struct {
+	Enabled         bool
+	Prefix          string
+	RefreshInterval string
+	ReadSecretData  bool
+}
+
+type vaultClientKeyStoreJSON struct {
+	Type       string
+	Directory  string
+	Passphrase string
 }
 
 type vaultClientAuthenticationJSON struct {
+	Method      string
+	Namespace   string
 	Token       string
+	TokenFile   vaultClientTokenFileJSON
 	RoleId      string
 	SecretId    string
 	ApprolePath string
+	Kubernetes  vaultClientKubernetesAuthenticationJSON
+	Jwt         vaultClientJWTAuthenticationJSON
+	Cert        vaultClientCertAuthenticationJSON
+	Retry       vaultClientRetryJSON
+}
+
+// vaultClientTokenFileJSON is either the URL of the token sink file, or an object with the URL and its poll interval
+type vaultClientTokenFileJSON struct {
+	Path         string
+	PollInterval string
+}
+
+func (c *vaultClientTokenFileJSON) UnmarshalJSON(b []byte) error {
+	var path string
+	if err := json.Unmarshal(b, &path); err == nil {
+		*c = vaultClientTokenFileJSON{Path: path}
+		return nil
+	}
+	type tokenFile vaultClientTokenFileJSON
+	return json.Unmarshal(b, (*tokenFile)(c))
+}
+
+// MarshalJSON uses the URL form unless a poll interval is set
+func (c vaultClientTokenFileJSON) MarshalJSON() ([]byte, error) {
+	if c.PollInterval == "" {
+		return json.Marshal(c.Path)
+	}
+	type tokenFile vaultClientTokenFileJSON
+	return json.Marshal(tokenFile(c))
+}
+
+type vaultClientRetryJSON struct {
+	InitialInterval string
+	MaxInterval     string
+	Multiplier      float64
+	Jitter          *float64
+	MaxAttempts     int
+}
+
+type vaultClientCertAuthenticationJSON struct {
+	Role string
+	Path string
+}
+
+type vaultClientJWTAuthenticationJSON struct {
+	Role string
+	Path string
+	Jwt  string
+}
+
+type vaultClientKubernetesAuthenticationJSON struct {
+	Role      string
+	Path      string
+	TokenFile string
 }
 
 type vaultClientTLSJSON struct {
@@ -99,6 +350,11 @@ func (c vaultClientJSON) vaultClient() (VaultClient, error) {
 		return VaultClient{}, err
 	}
 
+	failoverAddresses, err := parseURLs(c.FailoverAddresses)
+	if err != nil {
+		return VaultClient{}, err
+	}
+
 	if !strings.HasSuffix(c.AccountDirectory, "/") {
 		c.AccountDirectory = c.AccountDirectory + "/"
 	}
@@ -107,6 +363,23 @@ func (c vaultClientJSON) vaultClient() (VaultClient, error) {
 		return VaultClient{}, err
 	}
 
+	discovery, err := c.Discovery.vaultClientDiscovery()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
+	var healthCheckInterval time.Duration
+	if c.HealthCheckInterval != "" {
+		if healthCheckInterval, err = time.ParseDuration(c.HealthCheckInterval); err != nil {
+			return VaultClient{}, err
+		}
+	}
+
+	keyStore, err := c.KeyStore.vaultClientKeyStore()
+	if err != nil {
+		return VaultClient{}, err
+	}
+
 	authentication, err := c.Authentication.vaultClientAuthentication()
 	if err != nil {
 		return VaultClient{}, err
@@ -117,43 +390,200 @@ func (c vaultClientJSON) vaultClient() (VaultClient, error) {
 		return VaultClient{}, err
 	}
 
+	var connections []VaultConnection
+	for _, conn := range c.Connections {
+		vc, err := conn.vaultConnection()
+		if err != nil {
+			return VaultClient{}, err
+		}
+		connections = append(connections, vc)
+	}
+
 	return VaultClient{
-		Vault:            vault,
-		KVEngineName:     c.KVEngineName,
-		AccountDirectory: accountDirectory,
-		Unlock:           c.Unlock,
-		Authentication:   authentication,
-		TLS:              tls,
+		Vault:                     vault,
+		FailoverAddresses:         failoverAddresses,
+		Namespace:                 strings.Trim(c.Namespace, "/"),
+		KVEngineName:              c.KVEngineName,
+		KVVersion:                 c.KVVersion,
+		TransitEngineName:         c.TransitEngineName,
+		TransitKeyType:            c.TransitKeyType,
+		AccountDirectory:          accountDirectory,
+		Unlock:                    c.Unlock,
+		WarnOnMissingCapabilities: c.WarnOnMissingCapabilities,
+		SecretLayout:              c.SecretLayout.vaultClientSecretLayout(),
+		Discovery:                 discovery,
+		HealthCheckInterval:       healthCheckInterval,
+		KeyStore:                  keyStore,
+		InsecureDev:               c.InsecureDev,
+		Authentication:            authentication,
+		TLS:                       tls,
+		Connections:               connections,
+	}, nil
+}
+
+func (c vaultConnectionJSON) vaultConnection() (VaultConnection, error) {
+	vault, err := url.Parse(c.Vault)
+	if err != nil {
+		return VaultConnection{}, err
+	}
+
+	failoverAddresses, err := parseURLs(c.FailoverAddresses)
+	if err != nil {
+		return VaultConnection{}, err
+	}
+
+	authentication, err := c.Authentication.vaultClientAuthentication()
+	if err != nil {
+		return VaultConnection{}, err
+	}
+
+	tls, err := c.Tls.vaultClientTls()
+	if err != nil {
+		return VaultConnection{}, err
+	}
+
+	return VaultConnection{
+		Name:              c.Name,
+		Vault:             vault,
+		FailoverAddresses: failoverAddresses,
+		Namespace:         strings.Trim(c.Namespace, "/"),
+		KVEngineName:      c.KVEngineName,
+		KVVersion:         c.KVVersion,
+		TransitEngineName: c.TransitEngineName,
+		TransitKeyType:    c.TransitKeyType,
+		Authentication:    authentication,
+		TLS:               tls,
+	}, nil
+}
+
+func parseURLs(s []string) ([]*url.URL, error) {
+	var urls []*url.URL
+	for _, v := range s {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+func (c vaultClientSecretLayoutJSON) vaultClientSecretLayout() VaultClientSecretLayout {
+	return VaultClientSecretLayout{
+		Type:         c.Type,
+		KeyField:     c.KeyField,
+		AddressField: c.AddressField,
+	}
+}
+
+func (c vaultClientDiscoveryJSON) vaultClientDiscovery() (VaultClientDiscovery, error) {
+	var refreshInterval time.Duration
+	if c.RefreshInterval != "" {
+		var err error
+		if refreshInterval, err = time.ParseDuration(c.RefreshInterval); err != nil {
+			return VaultClientDiscovery{}, err
+		}
+	}
+	return VaultClientDiscovery{
+		Enabled:         c.Enabled,
+		Prefix:          c.Prefix,
+		RefreshInterval: refreshInterval,
+		ReadSecretData:  c.ReadSecretData,
+	}, nil
+}
+
+func (c vaultClientKeyStoreJSON) vaultClientKeyStore() (VaultClientKeyStore, error) {
+	directory, err := url.Parse(c.Directory)
+	if err != nil {
+		return VaultClientKeyStore{}, err
+	}
+	return VaultClientKeyStore{
+		Type:       c.Type,
+		Directory:  directory,
+		Passphrase: SecretSource(c.Passphrase),
 	}, nil
 }
 
 func (c vaultClientAuthenticationJSON) vaultClientAuthentication() (VaultClientAuthentication, error) {
-	token, err := url.Parse(c.Token)
+	tokenFile, err := url.Parse(c.TokenFile.Path)
 	if err != nil {
 		return VaultClientAuthentication{}, err
 	}
+	var pollInterval time.Duration
+	if c.TokenFile.PollInterval != "" {
+		if pollInterval, err = time.ParseDuration(c.TokenFile.PollInterval); err != nil {
+			return VaultClientAuthentication{}, err
+		}
+	}
 
-	roleId, err := url.Parse(c.RoleId)
+	kubernetes, err := c.Kubernetes.vaultClientKubernetesAuthentication()
 	if err != nil {
 		return VaultClientAuthentication{}, err
 	}
 
-	secretId, err := url.Parse(c.SecretId)
+	retry, err := c.Retry.vaultClientRetry()
 	if err != nil {
 		return VaultClientAuthentication{}, err
 	}
 
+	return VaultClientAuthentication{
+		Method:                c.Method,
+		Namespace:             strings.Trim(c.Namespace, "/"),
+		Token:                 SecretSource(c.Token),
+		TokenFile:             tokenFile,
+		TokenFilePollInterval: pollInterval,
+		RoleId:                SecretSource(c.RoleId),
+		SecretId:              SecretSource(c.SecretId),
+		ApprolePath:           c.ApprolePath,
+		Kubernetes:            kubernetes,
+		JWT: VaultClientJWTAuthentication{
+			Role: c.Jwt.Role,
+			Path: c.Jwt.Path,
+			Jwt:  SecretSource(c.Jwt.Jwt),
+		},
+		Cert: VaultClientCertAuthentication{
+			Role: c.Cert.Role,
+			Path: c.Cert.Path,
+		},
+		Retry: retry,
+	}, nil
+}
+
+func (c vaultClientRetryJSON) vaultClientRetry() (VaultClientRetry, error) {
 	var (
-		tEnv = EnvironmentVariable(*token)
-		rEnv = EnvironmentVariable(*roleId)
-		sEnv = EnvironmentVariable(*secretId)
+		initialInterval, maxInterval time.Duration
+		err                          error
 	)
+	if c.InitialInterval != "" {
+		if initialInterval, err = time.ParseDuration(c.InitialInterval); err != nil {
+			return VaultClientRetry{}, err
+		}
+	}
+	if c.MaxInterval != "" {
+		if maxInterval, err = time.ParseDuration(c.MaxInterval); err != nil {
+			return VaultClientRetry{}, err
+		}
+	}
 
-	return VaultClientAuthentication{
-		Token:       &tEnv,
-		RoleId:      &rEnv,
-		SecretId:    &sEnv,
-		ApprolePath: c.ApprolePath,
+	return VaultClientRetry{
+		InitialInterval: initialInterval,
+		MaxInterval:     maxInterval,
+		Multiplier:      c.Multiplier,
+		Jitter:          c.Jitter,
+		MaxAttempts:     c.MaxAttempts,
+	}, nil
+}
+
+func (c vaultClientKubernetesAuthenticationJSON) vaultClientKubernetesAuthentication() (VaultClientKubernetesAuthentication, error) {
+	tokenFile, err := url.Parse(c.TokenFile)
+	if err != nil {
+		return VaultClientKubernetesAuthentication{}, err
+	}
+
+	return VaultClientKubernetesAuthentication{
+		Role:      c.Role,
+		Path:      c.Path,
+		TokenFile: tokenFile,
 	}, nil
 }
 
@@ -181,22 +611,154 @@ func (c vaultClientTLSJSON) vaultClientTls() (VaultClientTLS, error) {
 }
 
 func (c VaultClient) vaultClientJSON() (vaultClientJSON, error) {
+	var connections []vaultConnectionJSON
+	for _, conn := range c.Connections {
+		connections = append(connections, conn.vaultConnectionJSON())
+	}
+
+	var healthCheckInterval string
+	if c.HealthCheckInterval != 0 {
+		healthCheckInterval = c.HealthCheckInterval.String()
+	}
+
 	return vaultClientJSON{
-		Vault:            c.Vault.String(),
-		KVEngineName:     c.KVEngineName,
-		AccountDirectory: c.AccountDirectory.String(),
-		Unlock:           c.Unlock,
-		Authentication:   c.Authentication.vaultClientAuthenticationJSON(),
-		Tls:              c.TLS.vaultClientTLSJSON(),
+		Vault:                     c.Vault.String(),
+		FailoverAddresses:         urlStrings(c.FailoverAddresses),
+		Namespace:                 c.Namespace,
+		KVEngineName:              c.KVEngineName,
+		KVVersion:                 c.KVVersion,
+		TransitEngineName:         c.TransitEngineName,
+		TransitKeyType:            c.TransitKeyType,
+		AccountDirectory:          c.AccountDirectory.String(),
+		Unlock:                    c.Unlock,
+		WarnOnMissingCapabilities: c.WarnOnMissingCapabilities,
+		SecretLayout:              c.SecretLayout.vaultClientSecretLayoutJSON(),
+		Discovery:                 c.Discovery.vaultClientDiscoveryJSON(),
+		HealthCheckInterval:       healthCheckInterval,
+		KeyStore:                  c.KeyStore.vaultClientKeyStoreJSON(),
+		InsecureDev:               c.InsecureDev,
+		Authentication:            c.Authentication.vaultClientAuthenticationJSON(),
+		Tls:                       c.TLS.vaultClientTLSJSON(),
+		Connections:               connections,
 	}, nil
 }
 
+func (c VaultConnection) vaultConnectionJSON() vaultConnectionJSON {
+	var vault string
+	if c.Vault != nil {
+		vault = c.Vault.String()
+	}
+	return vaultConnectionJSON{
+		Name:              c.Name,
+		Vault:             vault,
+		FailoverAddresses: urlStrings(c.FailoverAddresses),
+		Namespace:         c.Namespace,
+		KVEngineName:      c.KVEngineName,
+		KVVersion:         c.KVVersion,
+		TransitEngineName: c.TransitEngineName,
+		TransitKeyType:    c.TransitKeyType,
+		Authentication:    c.Authentication.vaultClientAuthenticationJSON(),
+		Tls:               c.TLS.vaultClientTLSJSON(),
+	}
+}
+
+func urlStrings(urls []*url.URL) []string {
+	var s []string
+	for _, u := range urls {
+		s = append(s, u.String())
+	}
+	return s
+}
+
+func (c VaultClientSecretLayout) vaultClientSecretLayoutJSON() vaultClientSecretLayoutJSON {
+	return vaultClientSecretLayoutJSON{
+		Type:         c.Type,
+		KeyField:     c.KeyField,
+		AddressField: c.AddressField,
+	}
+}
+
+func (c VaultClientDiscovery) vaultClientDiscoveryJSON() vaultClientDiscoveryJSON {
+	var refreshInterval string
+	if c.RefreshInterval != 0 {
+		refreshInterval = c.RefreshInterval.String()
+	}
+	return vaultClientDiscoveryJSON{
+		Enabled:         c.Enabled,
+		Prefix:          c.Prefix,
+		RefreshInterval: refreshInterval,
+		ReadSecretData:  c.ReadSecretData,
+	}
+}
+
+func (c VaultClientKeyStore) vaultClientKeyStoreJSON() vaultClientKeyStoreJSON {
+	var directory string
+	if c.Directory != nil {
+		directory = c.Directory.String()
+	}
+	return vaultClientKeyStoreJSON{
+		Type:       c.Type,
+		Directory:  directory,
+		Passphrase: string(c.Passphrase),
+	}
+}
+
 func (c VaultClientAuthentication) vaultClientAuthenticationJSON() vaultClientAuthenticationJSON {
+	var tokenFile vaultClientTokenFileJSON
+	if c.TokenFile != nil {
+		tokenFile.Path = c.TokenFile.String()
+	}
+	if c.TokenFilePollInterval != 0 {
+		tokenFile.PollInterval = c.TokenFilePollInterval.String()
+	}
 	return vaultClientAuthenticationJSON{
-		Token:       c.Token.String(),
-		RoleId:      c.RoleId.String(),
-		SecretId:    c.SecretId.String(),
+		Method:      c.Method,
+		Namespace:   c.Namespace,
+		Token:       string(c.Token),
+		TokenFile:   tokenFile,
+		RoleId:      string(c.RoleId),
+		SecretId:    string(c.SecretId),
 		ApprolePath: c.ApprolePath,
+		Kubernetes:  c.Kubernetes.vaultClientKubernetesAuthenticationJSON(),
+		Jwt: vaultClientJWTAuthenticationJSON{
+			Role: c.JWT.Role,
+			Path: c.JWT.Path,
+			Jwt:  string(c.JWT.Jwt),
+		},
+		Cert: vaultClientCertAuthenticationJSON{
+			Role: c.Cert.Role,
+			Path: c.Cert.Path,
+		},
+		Retry: c.Retry.vaultClientRetryJSON(),
+	}
+}
+
+func (c VaultClientRetry) vaultClientRetryJSON() vaultClientRetryJSON {
+	var initialInterval, maxInterval string
+	if c.InitialInterval != 0 {
+		initialInterval = c.InitialInterval.String()
+	}
+	if c.MaxInterval != 0 {
+		maxInterval = c.MaxInterval.String()
+	}
+	return vaultClientRetryJSON{
+		InitialInterval: initialInterval,
+		MaxInterval:     maxInterval,
+		Multiplier:      c.Multiplier,
+		Jitter:          c.Jitter,
+		MaxAttempts:     c.MaxAttempts,
+	}
+}
+
+func (c VaultClientKubernetesAuthentication) vaultClientKubernetesAuthenticationJSON() vaultClientKubernetesAuthenticationJSON {
+	var tokenFile string
+	if c.TokenFile != nil {
+		tokenFile = c.TokenFile.String()
+	}
+	return vaultClientKubernetesAuthenticationJSON{
+		Role:      c.Role,
+		Path:      c.Path,
+		TokenFile: tokenFile,
 	}
 }
 
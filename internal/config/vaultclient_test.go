@@ -3,8 +3,8 @@ package config
 import (
 	"encoding/json"
 	"net/url"
-	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -45,19 +45,13 @@ func TestVaultClient_UnmarshalJSON(t *testing.T) {
 			"0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526",
 		},
 		Authentication: VaultClientAuthentication{
-			Token: &EnvironmentVariable{
-				Scheme: "",
-				Host:   "",
-			},
-			RoleId: &EnvironmentVariable{
-				Scheme: "env",
-				Host:   "MY_ROLE_ID",
-			},
-			SecretId: &EnvironmentVariable{
-				Scheme: "env",
-				Host:   "MY_SECRET_ID",
-			},
+			RoleId:      "env://MY_ROLE_ID",
+			SecretId:    "env://MY_SECRET_ID",
 			ApprolePath: "my-role",
+			TokenFile:   &url.URL{},
+			Kubernetes: VaultClientKubernetesAuthentication{
+				TokenFile: &url.URL{},
+			},
 		},
 		TLS: VaultClientTLS{
 			CaCert: &url.URL{
@@ -123,19 +117,13 @@ func TestVaultClient_UnmarshalJSON_AddsTrailingSlashToAcctDir(t *testing.T) {
 			"0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526",
 		},
 		Authentication: VaultClientAuthentication{
-			Token: &EnvironmentVariable{
-				Scheme: "",
-				Host:   "",
-			},
-			RoleId: &EnvironmentVariable{
-				Scheme: "env",
-				Host:   "MY_ROLE_ID",
-			},
-			SecretId: &EnvironmentVariable{
-				Scheme: "env",
-				Host:   "MY_SECRET_ID",
-			},
+			RoleId:      "env://MY_ROLE_ID",
+			SecretId:    "env://MY_SECRET_ID",
 			ApprolePath: "my-role",
+			TokenFile:   &url.URL{},
+			Kubernetes: VaultClientKubernetesAuthentication{
+				TokenFile: &url.URL{},
+			},
 		},
 		TLS: VaultClientTLS{
 			CaCert: &url.URL{
@@ -165,36 +153,325 @@ func TestVaultClient_UnmarshalJSON_AddsTrailingSlashToAcctDir(t *testing.T) {
 	require.Equal(t, want.TLS, got.TLS)
 }
 
-func TestEnvironmentVariable_IsSet(t *testing.T) {
-	u, err := url.Parse("env://TEST_ENV")
+func TestVaultClient_UnmarshalJSON_Retry(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"authentication": {
+			"roleId": "env://MY_ROLE_ID",
+			"secretId": "env://MY_SECRET_ID",
+			"approlePath": "my-role",
+			"retry": {
+				"initialInterval": "2s",
+				"maxInterval": "1m",
+				"multiplier": 1.5,
+				"jitter": 0.1,
+				"maxAttempts": 10
+			}
+		}
+	}`)
+
+	want := VaultClientRetry{
+		InitialInterval: 2 * time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      1.5,
+		Jitter:          jitter(0.1),
+		MaxAttempts:     10,
+	}
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+	require.Equal(t, want, got.Authentication.Retry)
+
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, want, roundTrip.Authentication.Retry)
+}
+
+func TestVaultClient_UnmarshalJSON_Discovery(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"discovery": {
+			"enabled": true,
+			"prefix": "accounts/",
+			"refreshInterval": "5m",
+			"readSecretData": true
+		}
+	}`)
+
+	want := VaultClientDiscovery{
+		Enabled:         true,
+		Prefix:          "accounts/",
+		RefreshInterval: 5 * time.Minute,
+		ReadSecretData:  true,
+	}
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+	require.Equal(t, want, got.Discovery)
+
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, want, roundTrip.Discovery)
+}
+
+func TestVaultClient_UnmarshalJSON_HealthCheckInterval(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"healthCheckInterval": "10m"
+	}`)
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, got.HealthCheckInterval)
+
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, 10*time.Minute, roundTrip.HealthCheckInterval)
+
+	b = []byte(`{"vault": "http://vault:1111", "healthCheckInterval": "often"}`)
+	require.Error(t, json.Unmarshal(b, &got))
+}
+
+func TestVaultClient_UnmarshalJSON_TokenFile(t *testing.T) {
+	var tokenFiles = map[string]struct {
+		json             string
+		wantPollInterval time.Duration
+	}{
+		"url":    {json: `"file:///path/to/sink"`},
+		"object": {json: `{"path": "file:///path/to/sink", "pollInterval": "5s"}`, wantPollInterval: 5 * time.Second},
+	}
+
+	for name, tt := range tokenFiles {
+		t.Run(name, func(t *testing.T) {
+			b := []byte(`{
+				"vault": "http://vault:1111",
+				"authentication": {"tokenFile": ` + tt.json + `}
+			}`)
+
+			var got VaultClient
+			require.NoError(t, json.Unmarshal(b, &got))
+			require.Equal(t, "file:///path/to/sink", got.Authentication.TokenFile.String())
+			require.Equal(t, tt.wantPollInterval, got.Authentication.TokenFilePollInterval)
+
+			// check the config survives a marshal round trip
+			b, err := json.Marshal(&got)
+			require.NoError(t, err)
+			var roundTrip VaultClient
+			require.NoError(t, json.Unmarshal(b, &roundTrip))
+			require.Equal(t, got.Authentication.TokenFile, roundTrip.Authentication.TokenFile)
+			require.Equal(t, tt.wantPollInterval, roundTrip.Authentication.TokenFilePollInterval)
+		})
+	}
+
+	b := []byte(`{"vault": "http://vault:1111", "authentication": {"tokenFile": {"path": "file:///path/to/sink", "pollInterval": "often"}}}`)
+	var got VaultClient
+	require.Error(t, json.Unmarshal(b, &got))
+}
+
+func TestVaultClient_UnmarshalJSON_KeyStore(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"keyStore": {
+			"type": "file",
+			"directory": "file:///path/to/keys",
+			"passphrase": "env://DEV_PASSPHRASE"
+		},
+		"insecureDev": true
+	}`)
+
+	directory, _ := url.Parse("file:///path/to/keys")
+	want := VaultClientKeyStore{
+		Type:       "file",
+		Directory:  directory,
+		Passphrase: "env://DEV_PASSPHRASE",
+	}
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
 	require.NoError(t, err)
+	require.Equal(t, want, got.KeyStore)
+	require.True(t, got.KeyStore.IsFile())
+	require.True(t, got.InsecureDev)
+
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, want, roundTrip.KeyStore)
+	require.True(t, roundTrip.InsecureDev)
+}
+
+func TestVaultClient_UnmarshalJSON_Namespace(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"namespace": "/ns1/network1/",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"authentication": {
+			"namespace": "ns1",
+			"roleId": "env://MY_ROLE_ID",
+			"secretId": "env://MY_SECRET_ID",
+			"approlePath": "my-role"
+		}
+	}`)
 
-	env := EnvironmentVariable(*u)
-	require.False(t, env.IsSet())
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+	require.Equal(t, "ns1/network1", got.Namespace)
+	require.Equal(t, "ns1", got.Authentication.Namespace)
+}
 
-	os.Setenv("TEST_ENV", "val")
-	defer os.Unsetenv("TEST_ENV")
+func TestVaultClient_UnmarshalJSON_SecretLayout(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"secretLayout": {
+			"type": "namedField",
+			"keyField": "key",
+			"addressField": "address"
+		}
+	}`)
 
-	require.True(t, env.IsSet())
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+	require.Equal(t, VaultClientSecretLayout{Type: NamedFieldSecretLayout, KeyField: "key", AddressField: "address"}, got.SecretLayout)
 }
 
-func TestEnvironmentVariable_IsSet_Empty(t *testing.T) {
-	u, err := url.Parse("env://")
+func TestVaultClient_UnmarshalJSON_Connections(t *testing.T) {
+	b := []byte(`{
+		"vault": "http://vault:1111",
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/",
+		"connections": [
+			{
+				"name": "dr",
+				"vault": "https://dr-vault:8200",
+				"namespace": "/ns1/",
+				"kvEngineName": "dr-engine",
+				"authentication": {
+					"token": "env://DR_TOKEN"
+				},
+				"tls": {
+					"caCert": "file:///path/to/dr-ca.pem"
+				}
+			}
+		]
+	}`)
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
 	require.NoError(t, err)
+	require.Len(t, got.Connections, 1)
+
+	conn := got.Connections[0]
+	require.Equal(t, "dr", conn.Name)
+	require.Equal(t, "https://dr-vault:8200", conn.Vault.String())
+	require.Equal(t, "ns1", conn.Namespace)
+	require.Equal(t, "dr-engine", conn.KVEngineName)
+	require.Equal(t, SecretSource("env://DR_TOKEN"), conn.Authentication.Token)
+	require.Equal(t, "file:///path/to/dr-ca.pem", conn.TLS.CaCert.String())
 
-	env := EnvironmentVariable(*u)
-	require.False(t, env.IsSet())
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
+	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.Connections, roundTrip.Connections)
 }
 
-func TestEnvironmentVariable_Get(t *testing.T) {
-	u, err := url.Parse("env://TEST_ENV")
+func TestVaultClient_UnmarshalJSON_FailoverAddresses(t *testing.T) {
+	b := []byte(`{
+		"vault": "https://vault-1:8200",
+		"failoverAddresses": ["https://vault-2:8200", "https://vault-3:8200"],
+		"kvEngineName": "engine",
+		"accountDirectory": "file:///path/to/dir/"
+	}`)
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.NoError(t, err)
+
+	var addrs []string
+	for _, u := range got.Addresses() {
+		addrs = append(addrs, u.String())
+	}
+	require.Equal(t, []string{"https://vault-1:8200", "https://vault-2:8200", "https://vault-3:8200"}, addrs)
+
+	// check the config survives a marshal round trip
+	b, err = json.Marshal(&got)
 	require.NoError(t, err)
+	var roundTrip VaultClient
+	require.NoError(t, json.Unmarshal(b, &roundTrip))
+	require.Equal(t, got.FailoverAddresses, roundTrip.FailoverAddresses)
+}
+
+func TestVaultClient_ForConnection(t *testing.T) {
+	vault, _ := url.Parse("http://vault:1111")
+	drVault, _ := url.Parse("https://dr-vault:8200")
 
-	env := EnvironmentVariable(*u)
-	require.Empty(t, env.Get())
+	c := VaultClient{
+		Vault:             vault,
+		Namespace:         "ns1",
+		KVEngineName:      "engine",
+		KVVersion:         1,
+		TransitEngineName: "transit",
+		Unlock:            []string{"addr"},
+		Discovery:         VaultClientDiscovery{Enabled: true},
+		Authentication:    VaultClientAuthentication{Token: "env://TOKEN"},
+	}
+	c.Connections = []VaultConnection{{Name: "dr", Vault: drVault, Authentication: VaultClientAuthentication{Token: "env://DR_TOKEN"}}}
 
-	os.Setenv("TEST_ENV", "val")
-	defer os.Unsetenv("TEST_ENV")
+	got := c.ForConnection(c.Connections[0])
+	require.Equal(t, drVault, got.Vault)
+	require.Equal(t, "", got.Namespace)
+	require.Equal(t, "engine", got.KVEngineName)
+	require.Equal(t, 0, got.KVVersion)
+	require.Equal(t, "transit", got.TransitEngineName)
+	require.Equal(t, SecretSource("env://DR_TOKEN"), got.Authentication.Token)
+	require.Nil(t, got.Unlock)
+	require.Nil(t, got.Connections)
+	require.Equal(t, VaultClientDiscovery{}, got.Discovery)
 
-	require.Equal(t, "val", env.Get())
+	got = c.ForConnection(VaultConnection{Name: "dr", Vault: drVault, KVEngineName: "dr-engine", KVVersion: 2, TransitEngineName: "dr-transit"})
+	require.Equal(t, "dr-engine", got.KVEngineName)
+	require.Equal(t, 2, got.KVVersion)
+	require.Equal(t, "dr-transit", got.TransitEngineName)
+}
+
+func TestVaultClient_UnmarshalJSON_InvalidRetryInterval(t *testing.T) {
+	b := []byte(`{
+		"authentication": {
+			"retry": {
+				"initialInterval": "not-a-duration"
+			}
+		}
+	}`)
+
+	var got VaultClient
+	err := json.Unmarshal(b, &got)
+	require.Error(t, err)
 }
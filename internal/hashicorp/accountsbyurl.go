@@ -19,10 +19,10 @@ func (m accountsByURL) HasAccountWithAddress(address account.Address) bool {
 	return false
 }
 
-var (
-	unknownAccountErr   = errors.New("unknown account")
-	ambiguousAccountErr = errors.New("multiple accounts with same address")
-)
+// ErrUnknownAccount is returned when no account has the requested address
+var ErrUnknownAccount = errors.New("unknown account")
+
+var ambiguousAccountErr = errors.New("multiple accounts with same address")
 
 func (m accountsByURL) GetAccountWithAddress(address account.Address) (config.AccountFile, error) {
 	var (
@@ -40,7 +40,7 @@ func (m accountsByURL) GetAccountWithAddress(address account.Address) (config.Ac
 		}
 	}
 	if !isMatched {
-		return config.AccountFile{}, unknownAccountErr
+		return config.AccountFile{}, ErrUnknownAccount
 	}
 	return acct, nil
 }
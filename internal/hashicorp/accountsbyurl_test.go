@@ -211,5 +211,5 @@ func TestAccountsByURL_HasAccountWithAddress_NotFound_Error(t *testing.T) {
 
 	_, err := a.GetAccountWithAddress(toFind)
 
-	require.EqualError(t, err, unknownAccountErr.Error())
+	require.EqualError(t, err, ErrUnknownAccount.Error())
 }
@@ -0,0 +1,220 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// accountDirectory stores the account files that define the plugin's accounts
+type accountDirectory interface {
+	// load returns the account files in the directory
+	load() ([]config.AccountFile, error)
+	// write atomically creates a new account file with the given name and contents, and returns the file's path
+	write(name string, contents config.AccountFileJSON) (string, error)
+	// remove deletes the account file at path
+	remove(path string) error
+}
+
+// newAccountDirectory returns the accountDirectory for the configured accountDirectory URL: a local directory for file://
+// URLs, or secrets in a KV engine of the client's Vault for vault://<engine>/<prefix> URLs
+func newAccountDirectory(c *vaultClient, u *url.URL) accountDirectory {
+	if u.Scheme == config.VaultAccountDirectoryScheme {
+		prefix := strings.TrimPrefix(u.Path, "/")
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix = prefix + "/"
+		}
+		return &vaultAccountDirectory{
+			client:     c,
+			engineName: u.Host,
+			prefix:     prefix,
+		}
+	}
+	return &fileAccountDirectory{root: u}
+}
+
+// fileAccountDirectory stores account files in a local directory
+type fileAccountDirectory struct {
+	root *url.URL
+}
+
+func (d *fileAccountDirectory) load() ([]config.AccountFile, error) {
+	var result []config.AccountFile
+
+	walkFn := filepath.WalkFunc(func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// do nothing with directories
+			return nil
+		}
+		log.Printf("[DEBUG] Loading %v", path)
+		b, err := ioutil.ReadFile(path)
+
+		conf := new(config.AccountFileJSON)
+
+		if err := json.Unmarshal(b, conf); err != nil {
+			return fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+		}
+
+		result = append(result, config.AccountFile{Path: path, Contents: *conf})
+		return nil
+	})
+
+	root := d.root.Host + "/" + d.root.Path
+
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		log.Printf("[DEBUG] Creating empty directory at %v", root)
+		if err := os.Mkdir(root, os.ModeDir+0755); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	log.Printf("[DEBUG] Loading accts from %v", root)
+	if err := filepath.Walk(root, walkFn); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// write writes to a temporary hidden file first then renames once complete so that the write appears atomic.  This
+// will be useful if implementing a watcher on the directory
+func (d *fileAccountDirectory) write(name string, contents config.AccountFileJSON) (string, error) {
+	fullpath, err := d.root.Parse(name)
+	if err != nil {
+		return "", err
+	}
+	filePath := fullpath.Host + "/" + fullpath.Path
+	log.Printf("[DEBUG] writing to file %v", filePath)
+
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[DEBUG] marshalled file contents: %v", b)
+
+	log.Printf("[DEBUG] Creating temp file %v/%v", filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
+	f, err := ioutil.TempFile(filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	f.Close()
+
+	log.Println("[DEBUG] Renaming temp file")
+	if err := os.Rename(f.Name(), filePath); err != nil {
+		return "", err
+	}
+	return fullpath.String(), nil
+}
+
+func (d *fileAccountDirectory) remove(path string) error {
+	return os.Remove(accountFilePath(path))
+}
+
+// accountFilePath returns the filesystem path of an account file.  The Path of accounts created since the plugin
+// started is a file:// URL.
+func accountFilePath(path string) string {
+	if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+		return u.Host + u.Path
+	}
+	return path
+}
+
+// vaultAccountDirectory stores account files as secrets in a KV engine, one secret per account under prefix.  The
+// fields of each account file are the fields of the secret's data.
+type vaultAccountDirectory struct {
+	client     *vaultClient
+	engineName string
+	prefix     string
+}
+
+func (d *vaultAccountDirectory) load() ([]config.AccountFile, error) {
+	ks := d.client.keyStore(d.engineName)
+
+	log.Printf("[DEBUG] Loading accts from %v", d.path(""))
+	names, err := ks.ListKeys(d.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []config.AccountFile
+	for _, name := range names {
+		path := d.path(strings.TrimPrefix(name, d.prefix))
+		log.Printf("[DEBUG] Loading %v", path)
+
+		data, err := ks.ReadKey(name, 0)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %v, err: %v", path, err)
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+		}
+		conf := new(config.AccountFileJSON)
+		if err := json.Unmarshal(b, conf); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+		}
+
+		result = append(result, config.AccountFile{Path: path, Contents: *conf})
+	}
+	return result, nil
+}
+
+// write creates the account file's secret using check-and-set so that an existing secret is never overwritten.  KV v1
+// does not support check-and-set so the secret is written without the check, with a warning.
+func (d *vaultAccountDirectory) write(name string, contents config.AccountFileJSON) (string, error) {
+	path := d.path(name)
+	log.Printf("[DEBUG] writing to %v", path)
+
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return "", err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return "", err
+	}
+
+	if _, err := d.client.keyStore(d.engineName).WriteKey(d.prefix+name, data, new(uint64)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (d *vaultAccountDirectory) remove(path string) error {
+	name, err := d.secretName(path)
+	if err != nil {
+		return err
+	}
+	return d.client.keyStore(d.engineName).RemoveKey(name)
+}
+
+// path returns the vault://<engine>/<prefix><name> path of the named account file
+func (d *vaultAccountDirectory) path(name string) string {
+	return fmt.Sprintf("%v://%v/%v%v", config.VaultAccountDirectoryScheme, d.engineName, d.prefix, name)
+}
+
+// secretName returns the name of the secret of the account file at path
+func (d *vaultAccountDirectory) secretName(path string) (string, error) {
+	root := d.path("")
+	if !strings.HasPrefix(path, root) || path == root {
+		return "", errors.New("account file is not in the account directory")
+	}
+	return d.prefix + strings.TrimPrefix(path, root), nil
+}
@@ -0,0 +1,91 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func vaultAccountDirectoryClient(t *testing.T, resps map[string]map[string]interface{}) (*vaultClient, *[]recordedRequest, func()) {
+	c, reqs, cleanup := pathsClient(t, 2, resps)
+	c.accountDirectory = newAccountDirectory(c, &url.URL{Scheme: "vault", Host: "engine", Path: "/accts"})
+	return c, reqs, cleanup
+}
+
+func TestVaultAccountDirectory_WriteLoadRemove(t *testing.T) {
+	resps := map[string]map[string]interface{}{
+		"/v1/engine/data/accts/acct1": {"version": 1},
+	}
+	c, reqs, cleanup := vaultAccountDirectoryClient(t, resps)
+	defer cleanup()
+
+	newAccount := config.NewAccount{SecretName: "mysecret"}
+	want := newAccount.AccountFile("", layoutTestAddr, 2).Contents
+
+	path, err := c.accountDirectory.write("acct1", want)
+	require.NoError(t, err)
+	require.Equal(t, "vault://engine/accts/acct1", path)
+
+	// check-and-set is used so that existing account files are not overwritten
+	written := (*reqs)[len(*reqs)-1]
+	require.Equal(t, http.MethodPut, written.method)
+	require.Equal(t, "/v1/engine/data/accts/acct1", written.path)
+	require.Equal(t, map[string]interface{}{"cas": float64(0)}, written.body["options"])
+
+	resps["/v1/engine/metadata/accts"] = map[string]interface{}{"keys": []interface{}{"acct1"}}
+	resps["/v1/engine/metadata/accts/acct1"] = currentVersionMetadata(1, "", nil)
+	resps["/v1/engine/data/accts/acct1"] = map[string]interface{}{"data": written.body["data"]}
+
+	got, err := c.accountDirectory.load()
+	require.NoError(t, err)
+	require.Equal(t, []config.AccountFile{{Path: path, Contents: want}}, got)
+
+	accts, err := c.loadAccounts()
+	require.NoError(t, err)
+	require.Len(t, accts, 1)
+	for u := range accts {
+		require.Equal(t, "/v1/engine/data/mysecret", u.Path)
+	}
+
+	require.NoError(t, c.accountDirectory.remove(path))
+	removed := (*reqs)[len(*reqs)-1]
+	require.Equal(t, http.MethodDelete, removed.method)
+	require.Equal(t, "/v1/engine/metadata/accts/acct1", removed.path)
+}
+
+func TestVaultAccountDirectory_Remove_OutsideDirectory(t *testing.T) {
+	c, _, cleanup := vaultAccountDirectoryClient(t, nil)
+	defer cleanup()
+
+	err := c.accountDirectory.remove("vault://engine/other/acct1")
+	require.EqualError(t, err, "account file is not in the account directory")
+}
+
+func TestFileAccountDirectory_WriteLoadRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := newAccountDirectory(nil, &url.URL{Scheme: "file", Path: dir + "/"})
+
+	newAccount := config.NewAccount{SecretName: "mysecret"}
+	want := newAccount.AccountFile("", layoutTestAddr, 2).Contents
+
+	path, err := d.write("acct1", want)
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.Join(dir, "acct1"), path)
+
+	got, err := d.load()
+	require.NoError(t, err)
+	require.Equal(t, []config.AccountFile{{Path: filepath.Join(dir, "acct1"), Contents: want}}, got)
+
+	require.NoError(t, d.remove(path))
+	_, err = os.Stat(filepath.Join(dir, "acct1"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -3,19 +3,13 @@ package hashicorp
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 	"github.com/jpmorganchase/quorum/crypto/secp256k1"
@@ -28,9 +22,8 @@ func NewAccountManager(config config.VaultClient) (AccountManager, error) {
 	}
 
 	a := &accountManager{
-		client:       client,
-		kvEngineName: config.KVEngineName,
-		unlocked:     make(map[string]*lockableKey),
+		client:   client,
+		unlocked: make(map[string]*lockableKey),
 	}
 
 	for _, toUnlock := range config.Unlock {
@@ -56,22 +49,28 @@ type AccountManager interface {
 	Lock(acctAddr account.Address)
 	NewAccount(conf config.NewAccount) (account.Account, error)
 	ImportPrivateKey(privateKeyECDSA *ecdsa.PrivateKey, conf config.NewAccount) (account.Account, error)
+	DeleteAccount(acctAddr account.Address, destroy bool) error
+	CheckAccounts() []AccountHealth
+	Close()
 }
 
 type accountManager struct {
-	client       *vaultClient
-	kvEngineName string
-	unlocked     map[string]*lockableKey
-	mu           sync.Mutex
+	client   *vaultClient
+	unlocked map[string]*lockableKey
+	mu       sync.Mutex
 }
 
+// lockableKey holds an unlocked private key.  For Transit-backed accounts the key never leaves Vault so key is nil and
+// the lockableKey only records that the account is unlocked.
 type lockableKey struct {
 	key    *ecdsa.PrivateKey
 	cancel chan struct{}
 }
 
 func (k *lockableKey) zero() {
-	zeroKey(k.key)
+	if k.key != nil {
+		zeroKey(k.key)
+	}
 }
 
 func (a *accountManager) Status() (string, error) {
@@ -89,12 +88,47 @@ func (a *accountManager) Status() (string, error) {
 		status = fmt.Sprintf("%v: %v", status, unlockedAddrs)
 	}
 
+	for _, c := range a.client.clients() {
+		var prefix string
+		if c.name != "" {
+			prefix = fmt.Sprintf("Vault connection %v: ", c.name)
+		}
+		if reason := c.authStatus.degradedReason(); reason != "" {
+			status = fmt.Sprintf("%v, %vVault authentication degraded: %v", status, prefix, reason)
+		}
+		if err := c.authStatus.err(); err != nil {
+			status = fmt.Sprintf("%v, %v%v", status, prefix, err)
+		}
+		if c.failover != nil {
+			status = fmt.Sprintf("%v, %vusing Vault at %v", status, prefix, c.vaultAddress())
+		}
+		if c.fileKeyStore != nil {
+			status = fmt.Sprintf("%v, %vusing the insecure file key store", status, prefix)
+		}
+	}
+
+	var unhealthy []string
+	for _, r := range a.client.secretHealth.unhealthy() {
+		if a.client.hasAccount(r.Account.Address) {
+			unhealthy = append(unhealthy, fmt.Sprintf("0x%v (%v)", r.Account.Address.ToHexString(), r.Health))
+		}
+	}
+	if len(unhealthy) != 0 {
+		status = fmt.Sprintf("%v, %v account(s) with unhealthy secrets: %v", status, len(unhealthy), unhealthy)
+	}
+
 	return status, nil
 }
 
+// CheckAccounts checks the health of the secret versions of all accounts, which is then reported by Status until the
+// next check.  Transit accounts have no secret and are not checked.
+func (a *accountManager) CheckAccounts() []AccountHealth {
+	return a.client.checkAccountsHealth()
+}
+
 func (a *accountManager) Accounts() ([]account.Account, error) {
 	var (
-		w     = a.client.accts
+		w     = a.client.accounts()
 		accts = make([]account.Account, 0, len(w))
 		acct  account.Account
 	)
@@ -116,8 +150,18 @@ func (a *accountManager) Contains(acctAddr account.Address) bool {
 	return a.client.hasAccount(acctAddr)
 }
 
+// Sign fails while the authentication of the account's Vault connection is unavailable, even if the account is
+// unlocked, so that callers are told the plugin cannot currently use Vault
 func (a *accountManager) Sign(acctAddr account.Address, toSign []byte) ([]byte, error) {
-	if _, err := a.client.getAccount(acctAddr); err != nil {
+	acctFile, err := a.client.getAccount(acctAddr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := a.client.connection(acctFile.Contents.Connection)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authStatus.err(); err != nil {
 		return nil, err
 	}
 	a.mu.Lock()
@@ -126,13 +170,21 @@ func (a *accountManager) Sign(acctAddr account.Address, toSign []byte) ([]byte,
 	if !ok {
 		return nil, errors.New("account locked")
 	}
+	if acctFile.Contents.IsTransitAccount() {
+		return a.transitSign(acctFile, toSign)
+	}
 	return sign(toSign, lockable.key)
 }
 
 func (a *accountManager) UnlockAndSign(acctAddr account.Address, toSign []byte) ([]byte, error) {
-	if _, err := a.client.getAccount(acctAddr); err != nil {
+	acctFile, err := a.client.getAccount(acctAddr)
+	if err != nil {
 		return nil, err
 	}
+	if acctFile.Contents.IsTransitAccount() {
+		// the key never leaves Vault so there is nothing to unlock
+		return a.transitSign(acctFile, toSign)
+	}
 	a.mu.Lock()
 	lockable, unlocked := a.unlocked[acctAddr.ToHexString()]
 	a.mu.Unlock()
@@ -146,61 +198,77 @@ func (a *accountManager) UnlockAndSign(acctAddr account.Address, toSign []byte)
 	return sign(toSign, lockable.key)
 }
 
+// transitSign signs using the account's Transit key in the Vault connection the account is stored in
+func (a *accountManager) transitSign(acctFile config.AccountFile, toSign []byte) ([]byte, error) {
+	c, err := a.client.connection(acctFile.Contents.Connection)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authStatus.err(); err != nil {
+		return nil, err
+	}
+	return c.transitSign(acctFile, toSign)
+}
+
 func (a *accountManager) TimedUnlock(acctAddr account.Address, duration time.Duration) error {
 	acctFile, err := a.client.getAccount(acctAddr)
 	if err != nil {
 		return err
 	}
 
-	conf := acctFile.Contents.VaultAccount
-
-	// get from Vault
-	vaultLocation := fmt.Sprintf("%v/data/%v", a.kvEngineName, conf.SecretName)
-
-	reqData := make(map[string][]string)
-	reqData["version"] = []string{strconv.FormatInt(conf.SecretVersion, 10)}
-
-	resp, err := a.client.Logical().ReadWithData(vaultLocation, reqData)
+	c, err := a.client.connection(acctFile.Contents.Connection)
 	if err != nil {
 		return err
 	}
-	if resp == nil {
-		return errors.New("empty response from Vault")
+	if err := c.authStatus.err(); err != nil {
+		return err
 	}
 
-	respData, ok := resp.Data["data"].(map[string]interface{})
-	if !ok {
-		return errors.New("no secret information returned from Vault")
+	if acctFile.Contents.IsTransitAccount() {
+		// the key never leaves Vault so unlocking only records that the account can be used for signing
+		a.unlock(acctFile.Contents.Address, &lockableKey{}, duration)
+		return nil
 	}
-	if len(respData) != 1 {
-		return errors.New("only one key/value pair is allowed in each Hashicorp Vault secret")
+
+	conf := acctFile.Contents.VaultAccount
+
+	// get from the key store
+	respData, err := c.keyStore(conf.KVEngineName).ReadKey(conf.SecretName, conf.SecretVersion)
+	if err != nil {
+		return a.client.readKeyErr(acctFile, err)
 	}
 
-	// get value regardless of key in map
-	privKey, ok := respData[acctFile.Contents.Address]
-	if !ok {
-		return fmt.Errorf("response does not contain data for account address %v", acctFile.Contents.Address)
+	privKey, err := privateKeyFromSecret(c.secretLayout, respData, acctFile.Contents.Address)
+	if err != nil {
+		return err
 	}
 
-	key, err := account.NewKeyFromHexString(privKey.(string))
+	key, err := account.NewKeyFromHexString(privKey)
 	if err != nil {
 		return err
 	}
 
-	lockableKey := &lockableKey{
-		key: key,
+	// make sure the key is the account's key, as the secret may have been rewritten since the account file was created
+	// (e.g. if the account uses the latest version of the secret), and signing with any other key must be refused
+	addr, err := account.PrivateKeyToAddress(key)
+	if err != nil || !sameAddress(addr.ToHexString(), acctFile.Contents.Address) {
+		zeroKey(key)
+		return fmt.Errorf("secret %v does not contain the private key of account address %v", conf.SecretName, acctFile.Contents.Address)
 	}
 
+	a.unlock(acctFile.Contents.Address, &lockableKey{key: key}, duration)
+
+	return nil
+}
+
+func (a *accountManager) unlock(addr string, lockableKey *lockableKey, duration time.Duration) {
 	if duration > 0 {
-		go a.lockAfter(acctFile.Contents.Address, lockableKey, duration)
+		go a.lockAfter(addr, lockableKey, duration)
 	}
 
 	a.mu.Lock()
-	addr := strings.TrimPrefix(acctFile.Contents.Address, "0x")
-	a.unlocked[addr] = lockableKey
+	a.unlocked[strings.TrimPrefix(addr, "0x")] = lockableKey
 	a.mu.Unlock()
-
-	return nil
 }
 
 func (a *accountManager) lockAfter(addr string, key *lockableKey, duration time.Duration) {
@@ -231,27 +299,145 @@ func (a *accountManager) Lock(acctAddr account.Address) {
 	}
 }
 
+// Close locks all unlocked accounts, zeroing their keys, and stops the renewal of the Vault tokens, revoking them if
+// they were created by the plugin.  The AccountManager should not be used after Close.
+func (a *accountManager) Close() {
+	a.mu.Lock()
+	for addr, key := range a.unlocked {
+		key.zero()
+		delete(a.unlocked, addr)
+	}
+	a.mu.Unlock()
+
+	if a.client != nil {
+		a.client.close()
+	}
+}
+
 func (a *accountManager) NewAccount(conf config.NewAccount) (account.Account, error) {
+	c, err := a.client.connection(conf.Connection)
+	if err != nil {
+		return account.Account{}, err
+	}
+	if err := c.authStatus.err(); err != nil {
+		return account.Account{}, err
+	}
+
+	if conf.IsTransitAccount() {
+		return a.newTransitAccount(c, conf)
+	}
+
 	key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
 	if err != nil {
 		return account.Account{}, err
 	}
 	defer zeroKey(key)
 
-	return a.writeToVaultAndFile(key, conf)
+	return a.writeToVaultAndFile(c, key, conf)
 }
 
 func (a *accountManager) ImportPrivateKey(key *ecdsa.PrivateKey, conf config.NewAccount) (account.Account, error) {
 	defer zeroKey(key)
-	return a.writeToVaultAndFile(key, conf)
+	if conf.IsTransitAccount() {
+		return account.Account{}, errors.New("importing private keys is not supported for transit accounts")
+	}
+	c, err := a.client.connection(conf.Connection)
+	if err != nil {
+		return account.Account{}, err
+	}
+	return a.writeToVaultAndFile(c, key, conf)
 }
 
-func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.NewAccount) (account.Account, error) {
+// DeleteAccount locks the account, zeroing its key, deletes the account's secret version from Vault and removes the
+// account file.  If destroy is true the secret version is permanently destroyed, otherwise it is soft-deleted and can
+// be undeleted in Vault.  Transit keys are not deleted.  The account file is only removed once the secret has been
+// deleted, so a failed delete can be retried.
+func (a *accountManager) DeleteAccount(acctAddr account.Address, destroy bool) error {
+	acctFile, err := a.client.getAccount(acctAddr)
+	if err != nil {
+		return err
+	}
+	addrHex := acctAddr.ToHexString()
+	log.Printf("[INFO] deleting account 0x%v, account file = %v, destroy = %v", addrHex, acctFile.Path, destroy)
+
+	a.Lock(acctAddr)
+	log.Printf("[INFO] locked account 0x%v", addrHex)
+
+	c, err := a.client.connection(acctFile.Contents.Connection)
+	if err != nil {
+		return err
+	}
+
+	if acctFile.Contents.IsTransitAccount() {
+		log.Printf("[INFO] account 0x%v is a transit account, transit key %v has not been deleted from Vault", addrHex, acctFile.Contents.TransitAccount.KeyName)
+	} else {
+		if err := c.authStatus.err(); err != nil {
+			return err
+		}
+		// if the account uses the latest version of the secret the current version is deleted
+		conf := acctFile.Contents.VaultAccount
+		version, err := c.keyStore(conf.KVEngineName).DeleteKey(conf.SecretName, conf.SecretVersion, destroy)
+		if err != nil {
+			log.Printf("[ERROR] unable to delete version %v of secret %v for account 0x%v, err = %v", conf.SecretVersion, conf.SecretName, addrHex, err)
+			return fmt.Errorf("unable to delete secret from Vault: %w", err)
+		}
+		deleted := "soft-deleted"
+		if destroy {
+			deleted = "destroyed"
+		}
+		log.Printf("[INFO] %v version %v of secret %v for account 0x%v", deleted, version, conf.SecretName, addrHex)
+	}
+
+	if err := a.client.removeAccount(acctFile); err != nil {
+		log.Printf("[ERROR] unable to remove account file %v for account 0x%v, err = %v", acctFile.Path, addrHex, err)
+		return fmt.Errorf("unable to remove account file: %v", err)
+	}
+	if acctFile.Discovered {
+		log.Printf("[INFO] account 0x%v deleted", addrHex)
+		return nil
+	}
+	log.Printf("[INFO] removed account file %v, account 0x%v deleted", acctFile.Path, addrHex)
+
+	return nil
+}
+
+func (a *accountManager) newTransitAccount(c *vaultClient, conf config.NewAccount) (account.Account, error) {
+	if c.transitEngineName == "" {
+		return account.Account{}, errors.New("transitEngineName is not configured")
+	}
+
+	log.Println("[DEBUG] Creating new Transit key in Vault")
+	addr, keyVersion, err := c.createTransitKey(conf.TransitKeyName)
+	if err != nil {
+		return account.Account{}, fmt.Errorf("unable to create transit key in Vault: %v", err)
+	}
+	log.Println("[INFO] New Transit key created in Vault")
+
+	// the address is only known once the key has been created, so a key for an existing account is deleted rather
+	// than left unused in Vault
+	if a.Contains(addr) {
+		if err := c.deleteTransitKey(conf.TransitKeyName); err != nil {
+			log.Printf("[WARN] unable to delete transit key %v, err = %v", conf.TransitKeyName, err)
+		} else {
+			log.Printf("[INFO] deleted transit key %v", conf.TransitKeyName)
+		}
+		return account.Account{}, errors.New("account already exists")
+	}
+
+	return a.writeToFileAndAdd(c, addr, keyVersion, conf)
+}
+
+// writeToVaultAndFile writes the new account's key to the Vault connection c and writes the account's config file
+func (a *accountManager) writeToVaultAndFile(c *vaultClient, key *ecdsa.PrivateKey, conf config.NewAccount) (account.Account, error) {
 	addr, err := account.PrivateKeyToAddress(key)
 	if err != nil {
 		return account.Account{}, err
 	}
 
+	if err := c.authStatus.err(); err != nil {
+		return account.Account{}, err
+	}
+
 	if a.Contains(addr) {
 		return account.Account{}, errors.New("account already exists")
 	}
@@ -263,34 +449,63 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 		return account.Account{}, err
 	}
 
-	resp, err := a.writeToVault(addrHex, keyHex, conf)
+	secretVersion, err := a.writeToVault(c, addrHex, keyHex, conf)
 	if err != nil {
 		return account.Account{}, fmt.Errorf("unable to write secret to Vault: %v", err)
 	}
 	log.Println("[INFO] New account data written to Vault")
 
-	log.Println("[DEBUG] Getting new secret version number from response")
-	secretVersion, err := a.getVersionFromResponse(resp)
-	if err != nil {
-		return account.Account{}, fmt.Errorf("unable to write new account config file: %v", err)
+	// the address is written to KV v2 metadata, if supported, so that the account can be identified without reading its
+	// key
+	if len(conf.CustomMetadata) > 0 || (c.keyStoreKVVersion(conf.KVEngineName) != kvVersion1 && c.customMetadataSupported(conf.KVEngineName)) {
+		a.writeCustomMetadata(c, addrHex, conf)
 	}
 	log.Printf("[DEBUG] New secret version number = %v", secretVersion)
 
+	return a.writeToFileAndAdd(c, addr, secretVersion, conf)
+}
+
+// writeCustomMetadata writes the new account's custom metadata, along with its address, to the secret's KV metadata.
+// The account has already been written to Vault so failures are logged rather than returned.  If the KV engine does not
+// support custom metadata this is only logged once.
+func (a *accountManager) writeCustomMetadata(c *vaultClient, addrHex string, conf config.NewAccount) {
+	metadata := make(map[string]string, len(conf.CustomMetadata)+1)
+	for k, v := range conf.CustomMetadata {
+		metadata[k] = v
+	}
+	metadata[customMetadataAddressKey] = addrHex
+
+	err := c.keyStore(conf.KVEngineName).WriteMetadata(conf.SecretName, metadata)
+	if errors.Is(err, errCustomMetadataUnsupported) {
+		if c.setCustomMetadataUnsupported(conf.KVEngineName) {
+			log.Printf("[WARN] unable to write custom metadata for secret %v, addresses will not be written to the metadata of new secrets in this KV engine: %v", conf.SecretName, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("[WARN] unable to write custom metadata for secret %v, err = %v", conf.SecretName, err)
+		return
+	}
+	log.Println("[INFO] New account custom metadata written to Vault")
+}
+
+// writeToFileAndAdd writes the new account's config file and adds the account to the internal list of accounts
+func (a *accountManager) writeToFileAndAdd(c *vaultClient, addr account.Address, version int64, conf config.NewAccount) (account.Account, error) {
 	log.Println("[DEBUG] Writing new account data to file in account config directory")
-	fileData, err := a.writeToFile(addrHex, secretVersion, conf)
+	fileData, err := a.writeToFile(addr.ToHexString(), version, conf)
 	if err != nil {
 		return account.Account{}, fmt.Errorf("unable to write new account config file, err: %v", err)
 	}
 	log.Printf("[INFO] New account data written to %v", fileData.Path)
 
 	// prepare return value
-	accountURL, err := fileData.Contents.AccountURL(a.client.Address(), a.kvEngineName)
+	accountURL, err := c.accountURL(&fileData.Contents)
 	if err != nil {
 		return account.Account{}, err
 	}
 
 	// update the internal list of accts
-	a.client.accts[accountURL] = fileData
+	a.client.addAccount(accountURL, fileData)
 
 	return account.Account{
 		Address: addr,
@@ -298,76 +513,41 @@ func (a *accountManager) writeToVaultAndFile(key *ecdsa.PrivateKey, conf config.
 	}, nil
 }
 
-func (a *accountManager) writeToVault(addrHex string, keyHex string, conf config.NewAccount) (*api.Secret, error) {
-	data := make(map[string]interface{})
-	data["data"] = map[string]interface{}{
-		addrHex: keyHex,
+// writeToVault writes the new account's key to the key store of its KV engine and returns the new version of the
+// secret.  KV v1 does not support overwrite protection so it is disabled, with a warning, if the engine is KV v1.
+func (a *accountManager) writeToVault(c *vaultClient, addrHex string, keyHex string, conf config.NewAccount) (int64, error) {
+	data, err := secretData(c.secretLayout, addrHex, keyHex, conf.ExtraFields)
+	if err != nil {
+		return 0, err
 	}
 
+	var cas *uint64
 	if !conf.OverwriteProtection.InsecureDisable {
-		data["options"] = map[string]interface{}{
-			"cas": conf.OverwriteProtection.CurrentVersion,
-		}
+		cas = &conf.OverwriteProtection.CurrentVersion
 	}
-	vaultLocation := fmt.Sprintf("%v/data/%v", a.kvEngineName, conf.SecretName)
 
-	return a.client.Logical().Write(vaultLocation, data)
+	return c.keyStore(conf.KVEngineName).WriteKey(conf.SecretName, data, cas)
 }
 
-func (a *accountManager) getVersionFromResponse(resp *api.Secret) (int64, error) {
-	v, ok := resp.Data["version"]
-	if !ok {
-		return 0, errors.New("no version information returned from Vault")
-	}
-	vJson, ok := v.(json.Number)
-	if !ok {
-		return 0, errors.New("invalid version information returned from Vault")
-	}
-	secretVersion, err := vJson.Int64()
-	if err != nil {
-		return 0, fmt.Errorf("invalid version information returned from Vault, %v", err)
-	}
-	return secretVersion, nil
-}
-
-// writeToFile writes to a temporary hidden file first then renames once complete so that the write appears atomic.  This will be useful if implementing a watcher on the directory
-func (a *accountManager) writeToFile(addrHex string, secretVersion int64, conf config.NewAccount) (config.AccountFile, error) {
+// writeToFile atomically writes the new account's config file to the account directory
+func (a *accountManager) writeToFile(addrHex string, version int64, conf config.NewAccount) (config.AccountFile, error) {
 	now := time.Now().UTC()
 	nowISO8601 := now.Format("2006-01-02T15-04-05.000000000Z")
 	filename := fmt.Sprintf("UTC--%v--%v", nowISO8601, addrHex)
 
-	fullpath, err := a.client.accountDirectory.Parse(filename)
-	if err != nil {
-		return config.AccountFile{}, err
+	var fileData config.AccountFile
+	if conf.IsTransitAccount() {
+		fileData = conf.TransitAccountFile("", addrHex, version)
+	} else {
+		fileData = conf.AccountFile("", addrHex, version)
 	}
-	filePath := fullpath.Host + "/" + fullpath.Path
-	log.Printf("[DEBUG] writing to file %v", filePath)
 
-	fileData := conf.AccountFile(fullpath.String(), addrHex, secretVersion)
-
-	log.Printf("[DEBUG] marshalling file contents: %v", fileData)
-	contents, err := json.Marshal(fileData.Contents)
+	log.Printf("[DEBUG] writing file contents: %v", fileData.Contents)
+	path, err := a.client.accountDirectory.write(filename, fileData.Contents)
 	if err != nil {
 		return config.AccountFile{}, err
 	}
-	log.Printf("[DEBUG] marshalled file contents: %v", contents)
-
-	log.Printf("[DEBUG] Creating temp file %v/%v", filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
-	f, err := ioutil.TempFile(filepath.Dir(filePath), fmt.Sprintf(".%v*.tmp", filepath.Base(fullpath.String())))
-	if err != nil {
-		return config.AccountFile{}, err
-	}
-	if _, err := f.Write(contents); err != nil {
-		f.Close()
-		os.Remove(f.Name())
-		return config.AccountFile{}, err
-	}
-	f.Close()
-
-	log.Println("[DEBUG] Renaming temp file")
-	if err := os.Rename(f.Name(), filePath); err != nil {
-		return config.AccountFile{}, err
-	}
+	fileData.Path = path
 	return fileData, nil
 }
 
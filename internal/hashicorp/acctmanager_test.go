@@ -3,9 +3,17 @@ package hashicorp
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 	"github.com/stretchr/testify/require"
@@ -41,3 +49,227 @@ func TestSign(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, wantSig, got)
 }
+
+func TestAccountManager_Close_ZerosUnlockedKeys(t *testing.T) {
+	byt, _ := hex.DecodeString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
+	privKey := &ecdsa.PrivateKey{
+		D: new(big.Int).SetBytes(byt),
+	}
+	a := &accountManager{
+		client:   &vaultClient{},
+		unlocked: map[string]*lockableKey{"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {key: privKey}},
+	}
+
+	a.Close()
+
+	require.Empty(t, a.unlocked)
+	require.Empty(t, privKey.D.Bytes())
+}
+
+func TestAccountManager_WriteCustomMetadata_AddsAddress(t *testing.T) {
+	c, gotReq, gotBody, cleanup := kvClient(t, 2, nil)
+	defer cleanup()
+	a := &accountManager{client: c}
+
+	conf := config.NewAccount{
+		SecretName:     "mysecret",
+		CustomMetadata: map[string]string{"owner": "team1", "network": "net1"},
+	}
+	a.writeCustomMetadata(c, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", conf)
+
+	require.Equal(t, "/v1/engine/metadata/mysecret", gotReq.URL.Path)
+	require.Equal(t, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{
+			"owner":   "team1",
+			"network": "net1",
+			"address": "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5",
+		},
+	}, *gotBody)
+	// the account's metadata is not modified
+	require.Len(t, conf.CustomMetadata, 2)
+}
+
+func TestAccountManager_NewAccount_WritesAddressMetadata(t *testing.T) {
+	var tests = map[string]struct {
+		kvVersion    int
+		resps        map[string]map[string]interface{}
+		wantMetadata bool
+	}{
+		"kv_v2": {kvVersion: 2, resps: map[string]map[string]interface{}{"/v1/engine/data/mysecret": {"version": 1}}, wantMetadata: true},
+		"kv_v1": {kvVersion: 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "accts")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			c, reqs, cleanup := pathsClient(t, tt.kvVersion, tt.resps)
+			defer cleanup()
+			c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}}
+			a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+
+			// no customMetadata is configured
+			acct, err := a.NewAccount(config.NewAccount{SecretName: "mysecret", OverwriteProtection: config.OverwriteProtection{InsecureDisable: true}})
+			require.NoError(t, err)
+
+			var gotMetadata map[string]interface{}
+			for _, r := range *reqs {
+				if r.path == "/v1/engine/metadata/mysecret" {
+					gotMetadata = r.body
+				}
+			}
+			if !tt.wantMetadata {
+				require.Nil(t, gotMetadata)
+				return
+			}
+			require.Equal(t, map[string]interface{}{
+				"custom_metadata": map[string]interface{}{"address": acct.Address.ToHexString()},
+			}, gotMetadata)
+		})
+	}
+}
+
+func TestAccountManager_NewAccount_CustomMetadataUnsupported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var metadataWrites int
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp api.Secret
+		if r.URL.Path == "/v1/engine/metadata/mysecret" {
+			metadataWrites++
+			resp.Warnings = []string{"Endpoint ignored these unrecognized parameters: [custom_metadata]"}
+		} else {
+			resp.Data = map[string]interface{}{"version": 1}
+		}
+		b, _ := json.Marshal(&resp)
+		_, _ = w.Write(b)
+	}))
+	defer cleanup()
+	c.kvVersion = 2
+	c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}}
+	a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+
+	// the address metadata is not written again once Vault is known not to support custom_metadata
+	for i := 0; i < 2; i++ {
+		_, err := a.NewAccount(config.NewAccount{SecretName: "mysecret", OverwriteProtection: config.OverwriteProtection{InsecureDisable: true}})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, metadataWrites)
+	require.False(t, c.customMetadataSupported(""))
+}
+
+func TestAccountManager_DeleteAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "acct")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{}"), 0600))
+
+	c, gotReq, gotBody, cleanup := kvClient(t, 2, nil)
+	defer cleanup()
+	c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}}
+	newAcct := config.NewAccount{SecretName: "mysecret"}
+	c.accts = accountsByURL{
+		&url.URL{Path: "acct"}: newAcct.AccountFile("file://"+path, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", 2),
+	}
+
+	byt, _ := hex.DecodeString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
+	privKey := &ecdsa.PrivateKey{
+		D: new(big.Int).SetBytes(byt),
+	}
+	a := &accountManager{
+		client:   c,
+		unlocked: map[string]*lockableKey{"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5": {key: privKey}},
+	}
+
+	addr, err := account.NewAddressFromHexString("4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	require.NoError(t, err)
+
+	err = a.DeleteAccount(addr, true)
+	require.NoError(t, err)
+
+	require.Empty(t, a.unlocked)
+	require.Empty(t, privKey.D.Bytes())
+	require.Equal(t, "/v1/engine/destroy/mysecret", gotReq.URL.Path)
+	require.Equal(t, map[string]interface{}{"versions": []interface{}{float64(2)}}, *gotBody)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+	require.False(t, a.Contains(addr))
+}
+
+func TestAccountManager_TimedUnlock_LatestSecretVersion(t *testing.T) {
+	const (
+		acctAddr = "6038dc01869425004ca0b8370f6c81cf464213b3" // the address of layoutTestKey
+		otherKey = "0000000000000000000000000000000000000000000000000000000000000001"
+	)
+
+	var tests = map[string]struct {
+		metadata    map[string]interface{}
+		data        map[string]interface{}
+		wantVersion string
+		wantErr     string
+	}{
+		"latest": {
+			metadata:    map[string]interface{}{"current_version": 3, "versions": map[string]interface{}{"3": map[string]interface{}{"deletion_time": "", "destroyed": false}}},
+			data:        map[string]interface{}{acctAddr: layoutTestKey},
+			wantVersion: "3",
+		},
+		"latest_deleted": {
+			metadata: map[string]interface{}{"current_version": 3, "versions": map[string]interface{}{"3": map[string]interface{}{"deletion_time": "2020-01-01T00:00:00Z", "destroyed": false}}},
+			wantErr:  "account secret deleted: version 3 of secret mysecret has been deleted, undelete it in Vault to use the account",
+		},
+		"latest_no_versions": {
+			metadata: map[string]interface{}{"current_version": 0},
+			wantErr:  "account secret not found: secret mysecret does not exist",
+		},
+		"key_does_not_derive_address": {
+			metadata:    map[string]interface{}{"current_version": 4, "versions": map[string]interface{}{"4": map[string]interface{}{"deletion_time": "", "destroyed": false}}},
+			data:        map[string]interface{}{acctAddr: otherKey},
+			wantVersion: "4",
+			wantErr:     "secret mysecret does not contain the private key of account address " + acctAddr,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, reqs, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+				"/v1/engine/metadata/mysecret": tt.metadata,
+				"/v1/engine/data/mysecret":     {"data": tt.data},
+			})
+			defer cleanup()
+
+			newAcct := config.NewAccount{SecretName: "mysecret"}
+			c.accts = accountsByURL{
+				&url.URL{Path: "acct"}: newAcct.AccountFile("", acctAddr, 0),
+			}
+			a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+
+			addr, err := account.NewAddressFromHexString(acctAddr)
+			require.NoError(t, err)
+
+			err = a.TimedUnlock(addr, 0)
+			var gotVersion string
+			for _, r := range *reqs {
+				if r.path == "/v1/engine/data/mysecret" {
+					gotVersion = r.query.Get("version")
+				}
+			}
+			require.Equal(t, tt.wantVersion, gotVersion)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Empty(t, a.unlocked)
+
+				// signing is refused
+				_, err = a.UnlockAndSign(addr, make([]byte, 32))
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Contains(t, a.unlocked, acctAddr)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package hashicorp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// Authenticator is a method of authenticating with Vault.  Implementations are registered with RegisterAuthenticator
+// and selected by the authentication.method config field.
+type Authenticator interface {
+	// AuthMethod checks the method's configuration when the plugin config is validated
+	config.AuthMethod
+
+	// Login returns a secret containing the token the client should use, either in Auth (e.g. from an auth method
+	// login) or as the ID in Data (e.g. from a token lookup).  Login is called again each time the client needs to
+	// re-authenticate, so any credentials should be re-read.
+	Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error)
+
+	// RenewalPolicy determines how the client keeps its token valid after Login
+	RenewalPolicy() RenewalPolicy
+
+	// Describe returns a description of the configured method for logging.  It must not include credentials.
+	Describe(conf config.VaultClientAuthentication) string
+}
+
+// RenewalPolicy determines how the client keeps the token returned by an Authenticator's Login valid
+type RenewalPolicy int
+
+const (
+	// RenewAndReauthenticate renews the token for as long as possible and then calls Login again to get a new token.
+	// The token is created by the plugin so is revoked when the plugin is shut down.
+	RenewAndReauthenticate RenewalPolicy = iota
+
+	// RenewOnly renews the token for as long as possible.  The method has no way of getting a new token, so once the
+	// token can no longer be renewed the authentication is reported as degraded.
+	RenewOnly
+
+	// Poll calls Login periodically and switches to the returned token if it has changed.  Used for tokens that are
+	// managed externally (e.g. by Vault Agent).
+	Poll
+)
+
+var (
+	authenticators   = make(map[string]func() Authenticator)
+	authenticatorsMu sync.RWMutex
+)
+
+func init() {
+	RegisterAuthenticator("token", func() Authenticator { return &tokenAuthenticator{} })
+	RegisterAuthenticator("tokenFile", func() Authenticator { return &tokenFileAuthenticator{} })
+	RegisterAuthenticator("approle", func() Authenticator { return &approleAuthenticator{} })
+	RegisterAuthenticator("kubernetes", func() Authenticator { return &kubernetesAuthenticator{} })
+	RegisterAuthenticator("jwt", func() Authenticator { return &jwtAuthenticator{} })
+	RegisterAuthenticator("cert", func() Authenticator { return &certAuthenticator{} })
+}
+
+// RegisterAuthenticator makes an authentication method available for use with the given authentication.method
+// config value, registering it with the config package so that its configuration is validated with the rest of the
+// plugin config.  newFn is called to create a new Authenticator for each client, so implementations may hold state
+// between logins.  Registering the same method twice replaces the previous registration.
+func RegisterAuthenticator(method string, newFn func() Authenticator) {
+	authenticatorsMu.Lock()
+	defer authenticatorsMu.Unlock()
+
+	authenticators[method] = newFn
+	config.RegisterAuthMethod(method, newFn())
+}
+
+// newAuthenticator returns the Authenticator for the method configured in conf, after validating that exactly one
+// method has been configured and that its configuration is valid.  If authentication.method is not set then the
+// method is inferred from the fields that have been set.
+func newAuthenticator(conf config.VaultClient) (Authenticator, error) {
+	method, err := conf.ValidateAuthentication()
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorsMu.RLock()
+	newFn, ok := authenticators[method]
+	authenticatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no authenticator registered for authentication method %v", method)
+	}
+	return newFn(), nil
+}
@@ -0,0 +1,92 @@
+package hashicorp
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuthenticator_InfersMethod(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := config.VaultClient{
+		Authentication: config.VaultClientAuthentication{
+			RoleId:      config.SecretSource("env://" + testutil.MY_ROLE_ID),
+			SecretId:    config.SecretSource("env://" + testutil.MY_SECRET_ID),
+			ApprolePath: "myapprole",
+		},
+	}
+
+	got, err := newAuthenticator(vaultClient)
+	require.NoError(t, err)
+	require.IsType(t, &approleAuthenticator{}, got)
+
+	vaultClient.Authentication = config.VaultClientAuthentication{
+		Token: config.SecretSource("env://" + testutil.MY_TOKEN),
+	}
+
+	got, err = newAuthenticator(vaultClient)
+	require.NoError(t, err)
+	require.IsType(t, &tokenAuthenticator{}, got)
+}
+
+func TestNewAuthenticator_ExplicitMethod(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	vaultClient := config.VaultClient{
+		Authentication: config.VaultClientAuthentication{
+			Method:      "approle",
+			RoleId:      config.SecretSource("env://" + testutil.MY_ROLE_ID),
+			SecretId:    config.SecretSource("env://" + testutil.MY_SECRET_ID),
+			ApprolePath: "myapprole",
+		},
+	}
+
+	got, err := newAuthenticator(vaultClient)
+	require.NoError(t, err)
+	require.IsType(t, &approleAuthenticator{}, got)
+}
+
+type stubAuthenticator struct{}
+
+func (a *stubAuthenticator) IsConfigured(config.VaultClientAuthentication) bool { return false }
+
+func (a *stubAuthenticator) Validate(config.VaultClient) error { return nil }
+
+func (a *stubAuthenticator) Login(*api.Client, config.VaultClientAuthentication) (*api.Secret, error) {
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "stub"}}, nil
+}
+
+func (a *stubAuthenticator) RenewalPolicy() RenewalPolicy { return RenewOnly }
+
+func (a *stubAuthenticator) Describe(config.VaultClientAuthentication) string { return "stub" }
+
+func TestRegisterAuthenticator(t *testing.T) {
+	RegisterAuthenticator("stub", func() Authenticator { return &stubAuthenticator{} })
+	defer func() {
+		authenticatorsMu.Lock()
+		delete(authenticators, "stub")
+		authenticatorsMu.Unlock()
+	}()
+
+	vaultClient := config.VaultClient{
+		Authentication: config.VaultClientAuthentication{Method: "stub"},
+	}
+
+	// the method is also registered for validation of the plugin config
+	method, err := vaultClient.ValidateAuthentication()
+	require.NoError(t, err)
+	require.Equal(t, "stub", method)
+
+	got, err := newAuthenticator(vaultClient)
+	require.NoError(t, err)
+	require.IsType(t, &stubAuthenticator{}, got)
+}
@@ -0,0 +1,208 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const (
+	defaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultCertPath            = "cert" // the default mount path of the TLS certificate auth method
+)
+
+// tokenAuthenticator uses a token provided directly by a SecretSource
+type tokenAuthenticator struct {
+	config.TokenAuthMethod
+}
+
+func (a *tokenAuthenticator) Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	token, err := resolveSecret(client, "token", conf.Token, "")
+	if err != nil {
+		return nil, err
+	}
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: token}}, nil
+}
+
+func (a *tokenAuthenticator) RenewalPolicy() RenewalPolicy {
+	return RenewOnly
+}
+
+func (a *tokenAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("token = %v", conf.Token.String())
+}
+
+// tokenFileAuthenticator uses a token read from a token sink file (e.g. written by Vault Agent)
+type tokenFileAuthenticator struct {
+	config.TokenFileAuthMethod
+	f *tokenFile
+}
+
+func (a *tokenFileAuthenticator) Login(_ *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	if a.f == nil {
+		f, err := newTokenFile(conf.TokenFile.Host + conf.TokenFile.Path)
+		if err != nil {
+			return nil, err
+		}
+		a.f = f
+	} else if _, err := a.f.read(); err != nil {
+		return nil, err
+	}
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: a.f.token}}, nil
+}
+
+func (a *tokenFileAuthenticator) RenewalPolicy() RenewalPolicy {
+	return Poll
+}
+
+func (a *tokenFileAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("tokenFile = %v", conf.TokenFile)
+}
+
+// approleAuthenticator logs in to the AppRole auth method
+type approleAuthenticator struct {
+	config.ApproleAuthMethod
+	usedWrappingToken string // the last response-wrapping token unwrapped to get a secret ID
+}
+
+func (a *approleAuthenticator) Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	return authMethodLogin(client, a.loginRequest, conf)
+}
+
+func (a *approleAuthenticator) loginRequest(client *api.Client, conf config.VaultClientAuthentication) (string, map[string]interface{}, error) {
+	roleId, err := resolveSecret(client, "roleId", conf.RoleId, "role_id")
+	if err != nil {
+		return "", nil, err
+	}
+	var secretId string
+	if conf.IsWrappedSecretIdSet() {
+		secretId, err = a.unwrapSecretId(client, conf)
+	} else {
+		secretId, err = resolveSecret(client, "secretId", conf.SecretId, "secret_id")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	body := map[string]interface{}{"role_id": roleId, "secret_id": secretId}
+	return fmt.Sprintf("auth/%s/login", conf.ApprolePath), body, nil
+}
+
+func (a *approleAuthenticator) RenewalPolicy() RenewalPolicy {
+	return RenewAndReauthenticate
+}
+
+func (a *approleAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("approle = %v", conf.ApprolePath)
+}
+
+// kubernetesAuthenticator logs in to the Kubernetes auth method using the pod's service account token
+type kubernetesAuthenticator struct {
+	config.KubernetesAuthMethod
+}
+
+func (a *kubernetesAuthenticator) Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	return authMethodLogin(client, a.loginRequest, conf)
+}
+
+func (a *kubernetesAuthenticator) loginRequest(_ *api.Client, conf config.VaultClientAuthentication) (string, map[string]interface{}, error) {
+	path := defaultKubernetesTokenFile
+	if config.IsUrlSet(conf.Kubernetes.TokenFile) {
+		path = conf.Kubernetes.TokenFile.Host + conf.Kubernetes.TokenFile.Path
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to read Kubernetes service account token: %v", err)
+	}
+	body := map[string]interface{}{"role": conf.Kubernetes.Role, "jwt": strings.TrimSpace(string(b))}
+	return fmt.Sprintf("auth/%s/login", conf.Kubernetes.Path), body, nil
+}
+
+func (a *kubernetesAuthenticator) RenewalPolicy() RenewalPolicy {
+	return RenewAndReauthenticate
+}
+
+func (a *kubernetesAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("kubernetes = %v, role = %v", conf.Kubernetes.Path, conf.Kubernetes.Role)
+}
+
+// jwtAuthenticator logs in to the JWT/OIDC auth method
+type jwtAuthenticator struct {
+	config.JWTAuthMethod
+}
+
+func (a *jwtAuthenticator) Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	return authMethodLogin(client, a.loginRequest, conf)
+}
+
+func (a *jwtAuthenticator) loginRequest(client *api.Client, conf config.VaultClientAuthentication) (string, map[string]interface{}, error) {
+	jwt, err := resolveSecret(client, "jwt.jwt", conf.JWT.Jwt, "jwt")
+	if err != nil {
+		return "", nil, err
+	}
+	body := map[string]interface{}{"role": conf.JWT.Role, "jwt": jwt}
+	return fmt.Sprintf("auth/%s/login", conf.JWT.Path), body, nil
+}
+
+func (a *jwtAuthenticator) RenewalPolicy() RenewalPolicy {
+	return RenewAndReauthenticate
+}
+
+func (a *jwtAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("jwt = %v, role = %v", conf.JWT.Path, conf.JWT.Role)
+}
+
+// certAuthenticator logs in to the TLS certificate auth method using the client certificate configured for TLS
+type certAuthenticator struct {
+	config.CertAuthMethod
+}
+
+func (a *certAuthenticator) Login(client *api.Client, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	return authMethodLogin(client, a.loginRequest, conf)
+}
+
+func (a *certAuthenticator) loginRequest(_ *api.Client, conf config.VaultClientAuthentication) (string, map[string]interface{}, error) {
+	// the client certificate is presented as part of the TLS handshake so only the role is included in the body
+	body := make(map[string]interface{})
+	if conf.Cert.Role != "" {
+		body["name"] = conf.Cert.Role
+	}
+	return fmt.Sprintf("auth/%s/login", certPath(conf)), body, nil
+}
+
+func (a *certAuthenticator) RenewalPolicy() RenewalPolicy {
+	return RenewAndReauthenticate
+}
+
+func (a *certAuthenticator) Describe(conf config.VaultClientAuthentication) string {
+	return fmt.Sprintf("cert = %v, role = %v", certPath(conf), conf.Cert.Role)
+}
+
+// certPath returns the configured path of the TLS certificate auth method, or its default mount path if not set
+func certPath(conf config.VaultClientAuthentication) string {
+	if conf.Cert.Path == "" {
+		return defaultCertPath
+	}
+	return conf.Cert.Path
+}
+
+type loginRequestFunc func(client *api.Client, conf config.VaultClientAuthentication) (string, map[string]interface{}, error)
+
+// authMethodLogin logs in to a Vault auth method using the path and request body returned by loginRequest
+func authMethodLogin(client *api.Client, loginRequest loginRequestFunc, conf config.VaultClientAuthentication) (*api.Secret, error) {
+	path, body, err := loginRequest(client, conf)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Write(path, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("empty response from Vault")
+	}
+	return resp, nil
+}
@@ -0,0 +1,185 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginRequest_Approle(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+
+	conf := config.VaultClientAuthentication{
+		RoleId:      config.SecretSource("env://" + testutil.MY_ROLE_ID),
+		SecretId:    config.SecretSource("env://" + testutil.MY_SECRET_ID),
+		ApprolePath: "myapprole",
+	}
+
+	path, body, err := new(approleAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/myapprole/login", path)
+	require.Equal(t, map[string]interface{}{"role_id": "roleidval", "secret_id": "secretidval"}, body)
+}
+
+func TestLoginRequest_Kubernetes(t *testing.T) {
+	f, err := ioutil.TempFile("", "k8s-token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("jwtval\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	conf := config.VaultClientAuthentication{
+		Kubernetes: config.VaultClientKubernetesAuthentication{
+			Role:      "myrole",
+			Path:      "kubernetes",
+			TokenFile: &url.URL{Scheme: "file", Path: f.Name()},
+		},
+	}
+
+	path, body, err := new(kubernetesAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/kubernetes/login", path)
+	require.Equal(t, map[string]interface{}{"role": "myrole", "jwt": "jwtval"}, body)
+}
+
+func TestLoginRequest_Kubernetes_TokenFileNotFound(t *testing.T) {
+	conf := config.VaultClientAuthentication{
+		Kubernetes: config.VaultClientKubernetesAuthentication{
+			Role:      "myrole",
+			Path:      "kubernetes",
+			TokenFile: &url.URL{Scheme: "file", Path: "/does/not/exist"},
+		},
+	}
+
+	_, _, err := new(kubernetesAuthenticator).loginRequest(nil, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to read Kubernetes service account token")
+}
+
+func TestLoginRequest_JWT_ReadsFileOnEachLogin(t *testing.T) {
+	f, err := ioutil.TempFile("", "jwt")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("jwt1"), 0600))
+
+	conf := config.VaultClientAuthentication{
+		JWT: config.VaultClientJWTAuthentication{
+			Role: "myrole",
+			Path: "jwt",
+			Jwt:  config.SecretSource("file://" + f.Name()),
+		},
+	}
+
+	path, body, err := new(jwtAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/jwt/login", path)
+	require.Equal(t, map[string]interface{}{"role": "myrole", "jwt": "jwt1"}, body)
+
+	// simulate the JWT being rotated on disk
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("jwt2"), 0600))
+
+	_, body, err = new(jwtAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"role": "myrole", "jwt": "jwt2"}, body)
+}
+
+func TestLoginRequest_JWT_Env(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	conf := config.VaultClientAuthentication{
+		JWT: config.VaultClientJWTAuthentication{
+			Role: "myrole",
+			Path: "jwt",
+			Jwt:  config.SecretSource("env://" + testutil.MY_TOKEN),
+		},
+	}
+
+	_, body, err := new(jwtAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"role": "myrole", "jwt": "tokenval"}, body)
+}
+
+func TestLoginRequest_Cert(t *testing.T) {
+	conf := config.VaultClientAuthentication{
+		Cert: config.VaultClientCertAuthentication{
+			Role: "myrole",
+			Path: "cert",
+		},
+	}
+
+	path, body, err := new(certAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/cert/login", path)
+	require.Equal(t, map[string]interface{}{"name": "myrole"}, body)
+}
+
+func TestLoginRequest_Cert_NoRole(t *testing.T) {
+	conf := config.VaultClientAuthentication{
+		Cert: config.VaultClientCertAuthentication{
+			Path: "cert",
+		},
+	}
+
+	path, body, err := new(certAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/cert/login", path)
+	require.Empty(t, body)
+}
+
+func TestLoginRequest_Cert_DefaultPath(t *testing.T) {
+	conf := config.VaultClientAuthentication{
+		Method: "cert",
+	}
+
+	path, body, err := new(certAuthenticator).loginRequest(nil, conf)
+	require.NoError(t, err)
+	require.Equal(t, "auth/cert/login", path)
+	require.Empty(t, body)
+	require.Equal(t, "cert = cert, role = ", new(certAuthenticator).Describe(conf))
+}
+
+func TestTokenFileAuthenticator_Login_RereadsFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "token-sink")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("token1\n"), 0600))
+
+	conf := config.VaultClientAuthentication{
+		TokenFile: &url.URL{Scheme: "file", Path: f.Name()},
+	}
+	a := new(tokenFileAuthenticator)
+
+	resp, err := a.Login(nil, conf)
+	require.NoError(t, err)
+	got, err := resp.TokenID()
+	require.NoError(t, err)
+	require.Equal(t, "token1", got)
+
+	rewriteTokenFile(t, f.Name(), "token2")
+
+	resp, err = a.Login(nil, conf)
+	require.NoError(t, err)
+	got, err = resp.TokenID()
+	require.NoError(t, err)
+	require.Equal(t, "token2", got)
+}
+
+func TestTokenAuthenticator_Login(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	resp, err := new(tokenAuthenticator).Login(nil, config.VaultClientAuthentication{Token: config.SecretSource("env://" + testutil.MY_TOKEN)})
+	require.NoError(t, err)
+	got, err := resp.TokenID()
+	require.NoError(t, err)
+	require.Equal(t, "tokenval", got)
+}
@@ -1,6 +1,7 @@
 package hashicorp
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -8,12 +9,20 @@ import (
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 )
 
+// defaultPollInterval is how often the client logs in when using an Authenticator with the Poll RenewalPolicy, if no
+// TokenFilePollInterval is configured
+const defaultPollInterval = time.Second
+
 type renewable struct {
 	*api.Secret
 }
 
 func (r *renewable) startAuthenticationRenewal(client *vaultClient, conf config.VaultClientAuthentication) error {
 	if isRenewable, _ := r.TokenIsRenewable(); !isRenewable {
+		// there is no renewal loop to re-authenticate after failing over
+		if client.failover != nil {
+			go client.awaitFailover(conf)
+		}
 		return nil
 	}
 
@@ -27,41 +36,131 @@ func (r *renewable) startAuthenticationRenewal(client *vaultClient, conf config.
 }
 
 // renewalLoop starts the background process for renewing the auth token.  If the renewal fails, reauthentication will
-// be attempted indefinitely.
+// be attempted with exponential backoff until it succeeds or the configured number of attempts is reached.  While
+// reauthentication is failing the client's authentication is recorded as unavailable.  The client also
+// re-authenticates when it fails over to another Vault address.  The loop exits when the client is closed.
 func (r *renewable) renewalLoop(renewer *api.Renewer, client *vaultClient, conf config.VaultClientAuthentication) {
 	go renewer.Renew()
 
 	for {
 		select {
+		case <-client.stop:
+			renewer.Stop()
+			return
+
 		case _ = <-renewer.RenewCh():
-			log.Printf("[DEBUG] successfully renewed Vault auth token: approle = %v", conf.ApprolePath)
+			log.Printf("[DEBUG] successfully renewed Vault auth token: %v", client.authenticator.Describe(conf))
+
+		case <-client.reauth:
+			// the client has failed over to another Vault node which may not accept the current token
+			renewer.Stop()
+			log.Printf("[DEBUG] re-authenticating after Vault failover: %v", client.authenticator.Describe(conf))
+			client.reauthenticate(conf)
+			return
 
 		case err := <-renewer.DoneCh():
 			// Renewal has stopped either due to an unexpected reason (i.e. some error) or an expected reason
 			// (e.g. token TTL exceeded).  Either way we must re-authenticate and get a new token.
 			switch err {
 			case nil:
-				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: approle = %v", conf.ApprolePath)
+				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: %v", client.authenticator.Describe(conf))
 			default:
-				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: approle = %v, err = %v", conf.ApprolePath, err)
+				log.Printf("[DEBUG] renewal of Vault auth token failed, attempting re-authentication: %v, err = %v", client.authenticator.Describe(conf), err)
 			}
 
-			for i := 1; ; i++ {
-				renewable, err := client.authenticateWithApprole(conf)
-				if err != nil {
-					log.Printf("[ERROR] unable to reauthenticate with Vault (attempt %v): approle = %v, err = %v", i, conf.ApprolePath, err)
-					time.Sleep(reauthRetryInterval)
-					continue
-				}
-				log.Printf("[DEBUG] successfully re-authenticated with Vault: approle = %v", conf.ApprolePath)
-
-				if err := renewable.startAuthenticationRenewal(client, conf); err != nil {
-					log.Printf("[ERROR] unable to start renewal of authentication with Vault: approle = %v, err = %v", conf.ApprolePath, err)
-					time.Sleep(reauthRetryInterval)
-					continue
-				}
-				return
+			client.reauthenticate(conf)
+			return
+		}
+	}
+}
+
+// reauthenticate logs in again, retrying with backoff on failure.  Once successful, renewal of the new token is started.
+// If the configured number of attempts is reached the client's authentication stays unavailable until the plugin is
+// reloaded.
+func (c *vaultClient) reauthenticate(conf config.VaultClientAuthentication) {
+	for i := 1; ; i++ {
+		renewable, err := c.login(conf)
+		if err == nil {
+			log.Printf("[DEBUG] successfully re-authenticated with Vault: %v", c.authenticator.Describe(conf))
+			if err = renewable.startAuthenticationRenewal(c, conf); err != nil {
+				err = fmt.Errorf("unable to start renewal of authentication: %v", err)
 			}
 		}
+		if err == nil {
+			c.authStatus.setHealthy()
+			return
+		}
+
+		if c.reauthBackoff.exhausted(i) {
+			// no more attempts are made so the plugin's status must tell the user to reload the plugin
+			c.authStatus.setUnavailable(fmt.Errorf("gave up re-authenticating after %v attempts, reload the plugin to retry: %v", i, err))
+			log.Printf("[ERROR] unable to reauthenticate with Vault, giving up after %v attempts: %v, err = %v", i, c.authenticator.Describe(conf), err)
+			return
+		}
+		c.authStatus.setUnavailable(err)
+		wait := c.reauthBackoff.interval(i)
+		log.Printf("[ERROR] unable to reauthenticate with Vault (attempt %v, retrying in %v): %v, err = %v", i, wait, c.authenticator.Describe(conf), err)
+		select {
+		case <-time.After(wait):
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// awaitFailover handles the re-authentication requested after the client fails over to another Vault address when there
+// is no renewal loop to do so.  A token created by the plugin is replaced by logging in again, after which it returns.  A
+// directly provided token cannot be replaced so a warning is logged each time the client fails over.  It also returns
+// when the client is closed.
+func (c *vaultClient) awaitFailover(conf config.VaultClientAuthentication) {
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-c.reauth:
+		}
+
+		if c.authenticator.RenewalPolicy() == RenewAndReauthenticate {
+			log.Printf("[DEBUG] re-authenticating after Vault failover: %v", c.authenticator.Describe(conf))
+			c.reauthenticate(conf)
+			return
+		}
+		log.Printf("[WARN] failed over to Vault at %v, the Vault token cannot be re-obtained and must be valid on the new node: %v", c.vaultAddress(), c.authenticator.Describe(conf))
+	}
+}
+
+// pollLoop periodically logs in using the configured Authenticator, switching to the returned token if it has changed.
+// Used for tokens that are managed externally, e.g. by Vault Agent.  The token is also reloaded straight away after the
+// client fails over to another Vault address.  The loop exits when the client is closed.
+func (c *vaultClient) pollLoop(conf config.VaultClientAuthentication) {
+	pollInterval := defaultPollInterval
+	if conf.TokenFilePollInterval > 0 {
+		pollInterval = conf.TokenFilePollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		case <-c.reauth:
+		}
+
+		resp, err := c.authenticator.Login(c.authClient, conf)
+		if err != nil {
+			log.Printf("[ERROR] unable to reload Vault token: %v, err = %v", c.authenticator.Describe(conf), err)
+			continue
+		}
+		t, err := resp.TokenID()
+		if err != nil {
+			log.Printf("[ERROR] unable to reload Vault token: %v, err = %v", c.authenticator.Describe(conf), err)
+			continue
+		}
+		if t != c.Token() {
+			c.SetToken(t)
+			log.Printf("[DEBUG] reloaded Vault token: %v", c.authenticator.Describe(conf))
+		}
 	}
 }
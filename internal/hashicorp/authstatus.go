@@ -0,0 +1,64 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ErrAuthUnavailable is returned (wrapped) by operations that require Vault when the client is unable to authenticate
+var ErrAuthUnavailable = errors.New("Vault authentication unavailable")
+
+// authStatus records the health of the client's Vault authentication so that it can be reported to the user through
+// the plugin's Status.  Authentication is degraded if it still works but will soon fail (e.g. the token will expire and
+// cannot be renewed), and unavailable if the client has been unable to get a valid token.
+type authStatus struct {
+	degraded    string
+	unavailable error
+	mu          sync.RWMutex
+}
+
+func (s *authStatus) setDegraded(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("[WARN] Vault authentication degraded: %v", reason)
+	s.degraded = reason
+}
+
+// setUnavailable records the error that prevented the client from authenticating
+func (s *authStatus) setUnavailable(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unavailable = err
+}
+
+func (s *authStatus) setHealthy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.degraded = ""
+	s.unavailable = nil
+}
+
+// degradedReason returns the reason the authentication is degraded, or an empty string if it is healthy
+func (s *authStatus) degradedReason() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.degraded
+}
+
+// err returns an error wrapping ErrAuthUnavailable and the last authentication error if the client is unable to
+// authenticate, or nil otherwise
+func (s *authStatus) err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.unavailable == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: last error = %v", ErrAuthUnavailable, s.unavailable)
+}
@@ -0,0 +1,125 @@
+package hashicorp
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const unavailableAcctAddr = "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+
+func unavailableAccountManager() *accountManager {
+	a := &accountManager{
+		client: &vaultClient{
+			accts: accountsByURL{
+				&url.URL{Path: "acct"}: {Contents: config.AccountFileJSON{Address: unavailableAcctAddr}},
+			},
+		},
+		unlocked: make(map[string]*lockableKey),
+	}
+	a.client.authStatus.setUnavailable(errors.New("permission denied"))
+	return a
+}
+
+func TestAuthStatus_Err(t *testing.T) {
+	var s authStatus
+	require.NoError(t, s.err())
+
+	s.setUnavailable(errors.New("permission denied"))
+	err := s.err()
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+	require.EqualError(t, err, "Vault authentication unavailable: last error = permission denied")
+
+	s.setHealthy()
+	require.NoError(t, s.err())
+}
+
+type failingAuthenticator struct {
+	stubAuthenticator
+}
+
+func (a *failingAuthenticator) Login(*api.Client, config.VaultClientAuthentication) (*api.Secret, error) {
+	return nil, errors.New("permission denied")
+}
+
+func TestVaultClient_Reauthenticate_GivesUp(t *testing.T) {
+	c := &vaultClient{
+		authenticator: &failingAuthenticator{},
+		reauthBackoff: newBackoff(config.VaultClientRetry{InitialInterval: time.Millisecond, MaxAttempts: 2}),
+		stop:          make(chan struct{}),
+	}
+
+	c.reauthenticate(config.VaultClientAuthentication{})
+
+	// the status tells the user that no more attempts will be made
+	err := c.authStatus.err()
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+	require.EqualError(t, err, "Vault authentication unavailable: last error = gave up re-authenticating after 2 attempts, reload the plugin to retry: permission denied")
+}
+
+func TestAccountManager_Status_AuthUnavailable(t *testing.T) {
+	a := unavailableAccountManager()
+
+	got, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), Vault authentication unavailable: last error = permission denied", got)
+}
+
+func TestAccountManager_TimedUnlock_AuthUnavailable(t *testing.T) {
+	a := unavailableAccountManager()
+
+	addr, err := account.NewAddressFromHexString(unavailableAcctAddr)
+	require.NoError(t, err)
+
+	err = a.TimedUnlock(addr, 0)
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+}
+
+func TestAccountManager_Sign_AuthUnavailable(t *testing.T) {
+	a := unavailableAccountManager()
+	a.unlocked[unavailableAcctAddr] = &lockableKey{}
+
+	addr, err := account.NewAddressFromHexString(unavailableAcctAddr)
+	require.NoError(t, err)
+
+	// unlocked accounts cannot sign either
+	_, err = a.Sign(addr, make([]byte, 32))
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+}
+
+func TestAccountManager_AuthUnavailable_NamedConnection(t *testing.T) {
+	dr := &vaultClient{name: "dr"}
+	dr.authStatus.setUnavailable(errors.New("permission denied"))
+	a := unavailableAccountManager()
+	a.client.authStatus.setHealthy()
+	a.client.connections = map[string]*vaultClient{"dr": dr}
+
+	got, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), Vault connection dr: Vault authentication unavailable: last error = permission denied", got)
+
+	for u, acct := range a.client.accts {
+		acct.Contents.Connection = "dr"
+		a.client.accts[u] = acct
+	}
+	addr, err := account.NewAddressFromHexString(unavailableAcctAddr)
+	require.NoError(t, err)
+	err = a.TimedUnlock(addr, 0)
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+
+	_, err = a.NewAccount(config.NewAccount{SecretName: "mysecret", Connection: "dr"})
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+}
+
+func TestAccountManager_NewAccount_AuthUnavailable(t *testing.T) {
+	a := unavailableAccountManager()
+
+	_, err := a.NewAccount(config.NewAccount{})
+	require.True(t, errors.Is(err, ErrAuthUnavailable))
+}
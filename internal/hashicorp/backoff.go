@@ -0,0 +1,71 @@
+package hashicorp
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const (
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 5 * time.Minute
+	defaultRetryMultiplier      = 2
+	defaultRetryJitter          = 0.2
+)
+
+// backoff determines the delay between re-authentication attempts.  The delay grows exponentially from initial up to
+// max, and each delay is randomised by +/- jitter to prevent many clients retrying at the same time.
+type backoff struct {
+	initial     time.Duration
+	max         time.Duration
+	multiplier  float64
+	jitter      float64
+	maxAttempts int // 0 retries indefinitely
+	random      func() float64
+}
+
+func newBackoff(conf config.VaultClientRetry) backoff {
+	b := backoff{
+		initial:     conf.InitialInterval,
+		max:         conf.MaxInterval,
+		multiplier:  conf.Multiplier,
+		jitter:      defaultRetryJitter,
+		maxAttempts: conf.MaxAttempts,
+		random:      rand.Float64,
+	}
+	if b.initial == 0 {
+		b.initial = defaultRetryInitialInterval
+	}
+	if b.max == 0 {
+		b.max = defaultRetryMaxInterval
+	}
+	if b.max < b.initial {
+		b.max = b.initial
+	}
+	if b.multiplier == 0 {
+		b.multiplier = defaultRetryMultiplier
+	}
+	if conf.Jitter != nil {
+		b.jitter = *conf.Jitter
+	}
+	return b
+}
+
+// interval returns the delay to wait after the given failed attempt (starting from 1) before trying again
+func (b backoff) interval(attempt int) time.Duration {
+	d := float64(b.initial) * math.Pow(b.multiplier, float64(attempt-1))
+	if d > float64(b.max) {
+		d = float64(b.max)
+	}
+	if b.jitter > 0 {
+		d = d * (1 - b.jitter + 2*b.jitter*b.random())
+	}
+	return time.Duration(d)
+}
+
+// exhausted returns true if no more attempts should be made after the given failed attempt
+func (b backoff) exhausted(attempt int) bool {
+	return b.maxAttempts > 0 && attempt >= b.maxAttempts
+}
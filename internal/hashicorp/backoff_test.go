@@ -0,0 +1,59 @@
+package hashicorp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackoff_Defaults(t *testing.T) {
+	b := newBackoff(config.VaultClientRetry{})
+
+	require.Equal(t, defaultRetryInitialInterval, b.initial)
+	require.Equal(t, defaultRetryMaxInterval, b.max)
+	require.Equal(t, float64(defaultRetryMultiplier), b.multiplier)
+	require.Equal(t, defaultRetryJitter, b.jitter)
+	require.Equal(t, 0, b.maxAttempts)
+}
+
+func TestBackoff_Interval(t *testing.T) {
+	b := newBackoff(config.VaultClientRetry{
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+	})
+	// remove the randomness so that the jitter bounds can be checked
+	b.random = func() float64 { return 0.5 }
+
+	require.Equal(t, time.Second, b.interval(1))
+	require.Equal(t, 2*time.Second, b.interval(2))
+	require.Equal(t, 4*time.Second, b.interval(3))
+	require.Equal(t, 5*time.Second, b.interval(4))
+	require.Equal(t, 5*time.Second, b.interval(10))
+
+	b.random = func() float64 { return 0 }
+	require.Equal(t, 800*time.Millisecond, b.interval(1))
+
+	b.random = func() float64 { return 1 }
+	require.Equal(t, 1200*time.Millisecond, b.interval(1))
+}
+
+func TestBackoff_Interval_NoJitter(t *testing.T) {
+	noJitter := 0.0
+	b := newBackoff(config.VaultClientRetry{InitialInterval: time.Second, Jitter: &noJitter})
+	require.Equal(t, float64(0), b.jitter)
+
+	b.random = func() float64 { return 1 }
+	require.Equal(t, time.Second, b.interval(1))
+}
+
+func TestBackoff_Exhausted(t *testing.T) {
+	unlimited := newBackoff(config.VaultClientRetry{})
+	require.False(t, unlimited.exhausted(1000))
+
+	limited := newBackoff(config.VaultClientRetry{MaxAttempts: 3})
+	require.False(t, limited.exhausted(2))
+	require.True(t, limited.exhausted(3))
+}
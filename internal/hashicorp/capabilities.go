@@ -0,0 +1,151 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// ErrMissingCapabilities is returned (wrapped) by NewAccountManager if the client's token does not have the Vault
+// policy capabilities required to use the KV engine
+var ErrMissingCapabilities = errors.New("Vault token is missing required capabilities")
+
+// requiredCapabilities are the capabilities the plugin needs on the KV engine's data and metadata paths to read
+// existing accounts and create new ones
+var requiredCapabilities = []string{"read", "create", "update"}
+
+// capabilityCheck is a Vault path and the capabilities the plugin needs on it
+type capabilityCheck struct {
+	path     string
+	required []string
+}
+
+// checkCapabilities uses sys/capabilities-self to check that the client's token has the requiredCapabilities on the
+// <engine>/data/* and <engine>/metadata/* paths (or <engine>/* for KV v1).  The health of KV v1 secrets is checked by
+// listing the secrets alongside them, so if healthChecks is true the token also needs the list capability on KV v1
+// engines.  If discovery is enabled the token also needs the list capability on the discovery prefix of the client's
+// engine.  An error wrapping ErrMissingCapabilities and listing the missing capabilities for each path is returned if
+// any are missing.
+func (c *vaultClient) checkCapabilities(engine kvEngine, healthChecks bool) error {
+	checks := []capabilityCheck{
+		{path: fmt.Sprintf("%v/data/*", engine.name), required: requiredCapabilities},
+		{path: fmt.Sprintf("%v/metadata/*", engine.name), required: requiredCapabilities},
+	}
+	if engine.version == kvVersion1 {
+		required := requiredCapabilities
+		if healthChecks {
+			required = append(required[:len(required):len(required)], "list")
+		}
+		checks = []capabilityCheck{{path: fmt.Sprintf("%v/*", engine.name), required: required}}
+	}
+	if c.discovery.Enabled && engine.name == c.kvEngineName {
+		prefix := c.discovery.Prefix
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix = prefix + "/"
+		}
+		listPath := engine.metadataPath(prefix)
+		if engine.version == kvVersion1 {
+			listPath = engine.dataPath(prefix)
+		}
+		checks = append(checks, capabilityCheck{path: listPath, required: []string{"list"}})
+	}
+
+	var missing []string
+	for _, check := range checks {
+		got, err := c.Sys().CapabilitiesSelf(check.path)
+		if err != nil {
+			return fmt.Errorf("unable to check Vault token capabilities for %v: %v", check.path, err)
+		}
+		if m := missingCapabilities(got, check.required); len(m) > 0 {
+			missing = append(missing, fmt.Sprintf("%v: %v", check.path, m))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %v", ErrMissingCapabilities, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkedEngine identifies a KV engine whose capabilities have been checked for a Vault connection
+type checkedEngine struct {
+	connection string
+	engine     string
+}
+
+// checkEngineCapabilities checks the tokens' policies on every KV engine used with each Vault connection that is not
+// in checked, and adds the engines to checked.  Missing capabilities are logged instead of returned if
+// WarnOnMissingCapabilities is configured.  The file key store does not use Vault so there is nothing to check.  c
+// must be the default client.
+func (c *vaultClient) checkEngineCapabilities(conf config.VaultClient, checked map[checkedEngine]bool) error {
+	for _, conn := range c.clients() {
+		if conn.fileKeyStore != nil {
+			continue
+		}
+		for _, engineName := range c.kvEngineNames(conn) {
+			key := checkedEngine{connection: conn.name, engine: engineName}
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			if err := conn.checkCapabilities(conn.kvEngine(engineName), conf.HealthCheckInterval > 0); err != nil {
+				if conn.name != "" {
+					err = fmt.Errorf("Vault connection %v: %w", conn.name, err)
+				}
+				if !conf.WarnOnMissingCapabilities {
+					return err
+				}
+				log.Printf("[WARN] %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// kvEngineNames returns the names of the KV engines used with the Vault connection conn, in name order: conn's
+// configured engine, the engines named by the accounts stored using conn and, for the default connection, the engine
+// of a vault:// account directory.  c must be the default client.
+func (c *vaultClient) kvEngineNames(conn *vaultClient) []string {
+	names := map[string]bool{conn.kvEngineName: true}
+	for _, acctFile := range c.accounts() {
+		conf := acctFile.Contents
+		if conf.Connection == conn.name && !conf.IsTransitAccount() {
+			names[conf.KVEngine(conn.kvEngineName)] = true
+		}
+	}
+	if d, ok := c.accountDirectory.(*vaultAccountDirectory); ok && conn.name == "" {
+		names[d.engineName] = true
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// missingCapabilities returns the required capabilities not in got
+func missingCapabilities(got, required []string) []string {
+	has := make(map[string]bool, len(got))
+	for _, c := range got {
+		if c == "root" {
+			// root grants all capabilities
+			return nil
+		}
+		has[c] = true
+	}
+
+	var missing []string
+	for _, c := range required {
+		if !has[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,182 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingCapabilities(t *testing.T) {
+	var caps = map[string]struct {
+		got  []string
+		want []string
+	}{
+		"all":           {got: []string{"create", "read", "update", "delete", "list"}},
+		"root":          {got: []string{"root"}},
+		"read_only":     {got: []string{"read", "list"}, want: []string{"create", "update"}},
+		"deny":          {got: []string{"deny"}, want: []string{"read", "create", "update"}},
+		"none_returned": {want: []string{"read", "create", "update"}},
+	}
+
+	for name, tt := range caps {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, missingCapabilities(tt.got, requiredCapabilities))
+		})
+	}
+}
+
+// capabilitiesClient returns a client for a Vault server that responds to sys/capabilities-self requests with the
+// capabilities of the requested path.  The requests received are also returned, in order.
+func capabilitiesClient(t *testing.T, capabilities map[string][]string) (*vaultClient, *[]recordedRequest, func()) {
+	var (
+		mu   sync.Mutex
+		reqs = new([]recordedRequest)
+	)
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		req := recordedRequest{method: r.Method, path: r.URL.Path, query: r.URL.Query()}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+		*reqs = append(*reqs, req)
+
+		path, _ := req.body["path"].(string)
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{path: capabilities[path]}})
+		_, _ = w.Write(b)
+	}))
+	return c, reqs, cleanup
+}
+
+// requireCapabilitiesChecked checks that reqs are sys/capabilities-self requests for paths, in order
+func requireCapabilitiesChecked(t *testing.T, reqs []recordedRequest, paths ...string) {
+	var got []string
+	for _, req := range reqs {
+		require.Equal(t, http.MethodPost, req.method)
+		require.Equal(t, "/v1/sys/capabilities-self", req.path)
+		got = append(got, req.body["path"].(string))
+	}
+	require.Equal(t, paths, got)
+}
+
+func TestVaultClient_CheckCapabilities(t *testing.T) {
+	c, reqs, cleanup := capabilitiesClient(t, map[string][]string{
+		"engine/data/*":     {"create", "read", "update"},
+		"engine/metadata/*": {"create", "read", "update", "delete"},
+	})
+	defer cleanup()
+
+	require.NoError(t, c.checkCapabilities(c.kvEngine(""), false))
+	requireCapabilitiesChecked(t, *reqs, "engine/data/*", "engine/metadata/*")
+}
+
+func TestVaultClient_CheckCapabilities_Missing(t *testing.T) {
+	c, reqs, cleanup := capabilitiesClient(t, map[string][]string{
+		"engine/data/*":     {"read"},
+		"engine/metadata/*": {"list"},
+	})
+	defer cleanup()
+
+	err := c.checkCapabilities(c.kvEngine(""), false)
+	require.True(t, errors.Is(err, ErrMissingCapabilities))
+	require.EqualError(t, err, "Vault token is missing required capabilities: engine/data/*: [create update], engine/metadata/*: [read create update]")
+	requireCapabilitiesChecked(t, *reqs, "engine/data/*", "engine/metadata/*")
+}
+
+func TestVaultClient_CheckCapabilities_KVv1HealthChecks(t *testing.T) {
+	c, reqs, cleanup := capabilitiesClient(t, map[string][]string{
+		"engine/*": {"create", "read", "update"},
+	})
+	defer cleanup()
+	c.kvVersion = kvVersion1
+
+	require.NoError(t, c.checkCapabilities(c.kvEngine(""), false))
+
+	err := c.checkCapabilities(c.kvEngine(""), true)
+	require.True(t, errors.Is(err, ErrMissingCapabilities))
+	require.EqualError(t, err, "Vault token is missing required capabilities: engine/*: [list]")
+	requireCapabilitiesChecked(t, *reqs, "engine/*", "engine/*")
+}
+
+func TestVaultClient_CheckCapabilities_Discovery(t *testing.T) {
+	c, reqs, cleanup := capabilitiesClient(t, map[string][]string{
+		"engine/data/*":     {"create", "read", "update"},
+		"engine/metadata/*": {"create", "read", "update"},
+		"other/data/*":      {"create", "read", "update"},
+		"other/metadata/*":  {"create", "read", "update"},
+	})
+	defer cleanup()
+	c.discovery = config.VaultClientDiscovery{Enabled: true, Prefix: "accounts"}
+
+	err := c.checkCapabilities(c.kvEngine(""), false)
+	require.True(t, errors.Is(err, ErrMissingCapabilities))
+	require.EqualError(t, err, "Vault token is missing required capabilities: engine/metadata/accounts/: [list]")
+
+	// only the client's engine is discovered
+	require.NoError(t, c.checkCapabilities(kvEngine{name: "other", version: kvVersion2}, false))
+	requireCapabilitiesChecked(t, *reqs, "engine/data/*", "engine/metadata/*", "engine/metadata/accounts/", "other/data/*", "other/metadata/*")
+}
+
+func TestVaultClient_KVEngineNames(t *testing.T) {
+	conn := &vaultClient{name: "other", kvEngineName: "other-engine"}
+	c := &vaultClient{
+		kvEngineName: "engine",
+		connections:  map[string]*vaultClient{"other": conn},
+	}
+	c.accountDirectory = newAccountDirectory(c, &url.URL{Scheme: "vault", Host: "acct-engine", Path: "/accts"})
+
+	var (
+		defaultAcct = config.NewAccount{SecretName: "default"}
+		teamAcct    = config.NewAccount{SecretName: "team", KVEngineName: "team-engine"}
+		connAcct    = config.NewAccount{SecretName: "conn", KVEngineName: "conn-engine", Connection: "other"}
+		transitAcct = config.NewAccount{TransitKeyName: "key"}
+	)
+	c.accts = accountsByURL{
+		&url.URL{Path: "default"}: defaultAcct.AccountFile("", layoutTestAddr, 1),
+		&url.URL{Path: "team"}:    teamAcct.AccountFile("", layoutTestAddr, 1),
+		&url.URL{Path: "conn"}:    connAcct.AccountFile("", layoutTestAddr, 1),
+		&url.URL{Path: "transit"}: transitAcct.TransitAccountFile("", layoutTestAddr, 1),
+	}
+
+	require.Equal(t, []string{"acct-engine", "engine", "team-engine"}, c.kvEngineNames(c))
+	require.Equal(t, []string{"conn-engine", "other-engine"}, c.kvEngineNames(conn))
+}
+
+func TestNewVaultClient_ChecksCapabilitiesBeforeLoadingAccounts(t *testing.T) {
+	defer testutil.UnsetAll()
+	testutil.SetToken()
+
+	vault, reqs, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/sys/capabilities-self": {"capabilities": []interface{}{"read"}},
+	})
+	defer cleanup()
+	vaultURL, err := url.Parse(vault.Address())
+	require.NoError(t, err)
+
+	_, err = newVaultClient(config.VaultClient{
+		Vault:            vaultURL,
+		KVEngineName:     "engine",
+		KVVersion:        2,
+		AccountDirectory: &url.URL{Scheme: "vault", Host: "engine", Path: "/accts"},
+		TLS:              config.VaultClientTLS{CaCert: &url.URL{}, ClientCert: &url.URL{}, ClientKey: &url.URL{}},
+		Authentication: config.VaultClientAuthentication{
+			Token: config.SecretSource("env://" + testutil.MY_TOKEN),
+		},
+		HealthCheckInterval: time.Millisecond,
+	})
+	require.True(t, errors.Is(err, ErrMissingCapabilities))
+
+	// neither the account directory nor any account secrets were read
+	time.Sleep(10 * time.Millisecond)
+	for _, req := range *reqs {
+		require.NotContains(t, req.path, "/v1/engine/")
+	}
+}
@@ -0,0 +1,139 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// customMetadataAddressKey is the KV v2 custom_metadata key the account's address is written to when an account is
+// created, and read from when accounts are discovered
+const customMetadataAddressKey = "address"
+
+// discoverAccounts lists the secrets under the discovery prefix of the client's key store and returns an account for
+// the current version of each.  Secrets whose current version has been deleted or destroyed are skipped, as are
+// secrets whose account address cannot be determined and the account file secrets of a vault:// account directory in
+// the same engine.
+func (c *vaultClient) discoverAccounts() (accountsByURL, error) {
+	ks := c.keyStore("")
+
+	names, err := ks.ListKeys(c.discovery.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var acctDirPrefix *string
+	if d, ok := c.accountDirectory.(*vaultAccountDirectory); ok && d.engineName == c.kvEngineName {
+		acctDirPrefix = &d.prefix
+	}
+
+	result := make(accountsByURL, len(names))
+	for _, name := range names {
+		if acctDirPrefix != nil && strings.HasPrefix(name, *acctDirPrefix) {
+			log.Printf("[DEBUG] skipping secret %v: it is in the account directory", name)
+			continue
+		}
+		conf, ok, err := c.discoverAccount(ks, name)
+		if err != nil {
+			log.Printf("[WARN] unable to discover account from secret %v: %v", name, err)
+			continue
+		}
+		if !ok {
+			log.Printf("[DEBUG] skipping secret %v: current version is deleted", name)
+			continue
+		}
+		acctURL, err := c.accountURL(&conf)
+		if err != nil {
+			log.Printf("[WARN] unable to parse account URL for secret %v: %v", name, err)
+			continue
+		}
+		result[acctURL] = config.AccountFile{Contents: conf, Discovered: true}
+	}
+	return result, nil
+}
+
+// discoverAccount returns the account stored in the current version of the secret, or false if the current version
+// has been deleted or destroyed.  The address is read from the secret's custom_metadata if set, otherwise from the
+// secret's data if ReadSecretData is enabled, as the data includes the account's key.
+func (c *vaultClient) discoverAccount(ks KeyStore, secretName string) (config.AccountFileJSON, bool, error) {
+	md, err := ks.ReadMetadata(secretName)
+	if err != nil {
+		return config.AccountFileJSON{}, false, err
+	}
+	if md.Health != SecretHealthy {
+		return config.AccountFileJSON{}, false, nil
+	}
+
+	addrHex := md.CustomMetadata[customMetadataAddressKey]
+	if addrHex == "" {
+		if !c.discovery.ReadSecretData {
+			return config.AccountFileJSON{}, false, fmt.Errorf("secret has no %v custom_metadata", customMetadataAddressKey)
+		}
+		data, err := ks.ReadKey(secretName, md.Version)
+		if err != nil {
+			return config.AccountFileJSON{}, false, err
+		}
+		if addrHex, err = addressFromSecret(c.secretLayout, data); err != nil {
+			return config.AccountFileJSON{}, false, err
+		}
+	}
+
+	newAccount := config.NewAccount{SecretName: secretName}
+	return newAccount.AccountFile("", addrHex, md.Version).Contents, true, nil
+}
+
+// refreshDiscoveredAccounts replaces the previously discovered accounts with the accounts currently in Vault.  Accounts
+// with the same address as an account in the account directory, or another discovered account, are ignored.
+func (c *vaultClient) refreshDiscoveredAccounts() error {
+	discovered, err := c.discoverAccounts()
+	if err != nil {
+		return err
+	}
+
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
+
+	for u, file := range c.accts {
+		if file.Discovered {
+			delete(c.accts, u)
+		}
+	}
+	var n int
+	for u, file := range discovered {
+		addr, err := account.NewAddressFromHexString(file.Contents.Address)
+		if err != nil {
+			log.Printf("[WARN] ignoring account discovered in secret %v: %v", file.Contents.VaultAccount.SecretName, err)
+			continue
+		}
+		if c.accts.HasAccountWithAddress(addr) {
+			log.Printf("[WARN] ignoring account %v discovered in secret %v: an account with the same address is already loaded", file.Contents.Address, file.Contents.VaultAccount.SecretName)
+			continue
+		}
+		c.accts[u] = file
+		n++
+	}
+	log.Printf("[DEBUG] discovered %v accounts in Vault", n)
+	return nil
+}
+
+// discoveryLoop refreshes the discovered accounts every interval until the client is closed
+func (c *vaultClient) discoveryLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.refreshDiscoveredAccounts(); err != nil {
+			log.Printf("[ERROR] unable to refresh discovered accounts, keeping previously discovered accounts: %v", err)
+		}
+	}
+}
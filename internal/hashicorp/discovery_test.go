@@ -0,0 +1,175 @@
+package hashicorp
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const otherTestAddr = "dc99ddec13457de6c0f6bb8e6cf3955c86f55526"
+
+// discoveryClient returns a client with discovery enabled for a Vault server that responds to requests for each path in
+// resps with the corresponding data
+func discoveryClient(t *testing.T, resps map[string]map[string]interface{}) (*vaultClient, func()) {
+	c, _, cleanup := pathsClient(t, 2, resps)
+	c.discovery = config.VaultClientDiscovery{Enabled: true, Prefix: "accounts", ReadSecretData: true}
+	return c, cleanup
+}
+
+func currentVersionMetadata(version int, deletionTime string, customMetadata map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"current_version": version,
+		"custom_metadata": customMetadata,
+		"versions": map[string]interface{}{
+			"1": map[string]interface{}{"deletion_time": "", "destroyed": false},
+			"2": map[string]interface{}{"deletion_time": deletionTime, "destroyed": false},
+		},
+	}
+}
+
+func TestVaultClient_DiscoverAccounts(t *testing.T) {
+	c, cleanup := discoveryClient(t, map[string]map[string]interface{}{
+		"/v1/engine/metadata/accounts":     {"keys": []interface{}{"withMetadata", "sub/", "deleted"}},
+		"/v1/engine/metadata/accounts/sub": {"keys": []interface{}{"withoutMetadata"}},
+
+		"/v1/engine/metadata/accounts/withMetadata": currentVersionMetadata(2, "", map[string]interface{}{"address": layoutTestAddr}),
+
+		"/v1/engine/metadata/accounts/sub/withoutMetadata": currentVersionMetadata(1, "", nil),
+		"/v1/engine/data/accounts/sub/withoutMetadata":     {"data": map[string]interface{}{otherTestAddr: layoutTestKey}},
+
+		"/v1/engine/metadata/accounts/deleted": currentVersionMetadata(2, "2020-01-01T00:00:00Z", map[string]interface{}{"address": layoutTestAddr}),
+	})
+	defer cleanup()
+
+	got, err := c.discoverAccounts()
+	require.NoError(t, err)
+
+	byName := make(map[string]config.AccountFile)
+	for u, file := range got {
+		require.True(t, file.Discovered)
+		require.Empty(t, file.Path)
+		byName[file.Contents.VaultAccount.SecretName] = file
+
+		wantURL, err := c.accountURL(&file.Contents)
+		require.NoError(t, err)
+		require.Equal(t, wantURL.String(), u.String())
+	}
+	require.Len(t, byName, 2)
+
+	require.Equal(t, layoutTestAddr, byName["accounts/withMetadata"].Contents.Address)
+	require.Equal(t, int64(2), byName["accounts/withMetadata"].Contents.VaultAccount.SecretVersion)
+
+	require.Equal(t, otherTestAddr, byName["accounts/sub/withoutMetadata"].Contents.Address)
+	require.Equal(t, int64(1), byName["accounts/sub/withoutMetadata"].Contents.VaultAccount.SecretVersion)
+}
+
+func TestVaultClient_DiscoverAccounts_DoesNotReadSecretData(t *testing.T) {
+	c, reqs, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/engine/metadata/accounts":                 {"keys": []interface{}{"withMetadata", "withoutMetadata"}},
+		"/v1/engine/metadata/accounts/withMetadata":    currentVersionMetadata(2, "", map[string]interface{}{"address": layoutTestAddr}),
+		"/v1/engine/metadata/accounts/withoutMetadata": currentVersionMetadata(1, "", nil),
+		"/v1/engine/data/accounts/withoutMetadata":     {"data": map[string]interface{}{otherTestAddr: layoutTestKey}},
+	})
+	defer cleanup()
+	c.discovery = config.VaultClientDiscovery{Enabled: true, Prefix: "accounts"}
+
+	got, err := c.discoverAccounts()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	for _, file := range got {
+		require.Equal(t, layoutTestAddr, file.Contents.Address)
+	}
+
+	for _, req := range *reqs {
+		require.NotContains(t, req.path, "/data/")
+	}
+}
+
+func TestVaultClient_DiscoverAccounts_SkipsAccountDirectory(t *testing.T) {
+	c, reqs, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/engine/metadata/accounts":                {"keys": []interface{}{"discovered", "files/"}},
+		"/v1/engine/metadata/accounts/files":          {"keys": []interface{}{"acctfile"}},
+		"/v1/engine/metadata/accounts/discovered":     currentVersionMetadata(1, "", map[string]interface{}{"address": layoutTestAddr}),
+		"/v1/engine/metadata/accounts/files/acctfile": currentVersionMetadata(1, "", nil),
+	})
+	defer cleanup()
+	c.discovery = config.VaultClientDiscovery{Enabled: true, Prefix: "accounts", ReadSecretData: true}
+	c.accountDirectory = newAccountDirectory(c, &url.URL{Scheme: "vault", Host: "engine", Path: "/accounts/files"})
+
+	got, err := c.discoverAccounts()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	for _, req := range *reqs {
+		require.NotEqual(t, "/v1/engine/metadata/accounts/files/acctfile", req.path)
+		require.NotEqual(t, "/v1/engine/data/accounts/files/acctfile", req.path)
+	}
+}
+
+func TestVaultClient_DiscoverAccounts_NoSecrets(t *testing.T) {
+	c, cleanup := discoveryClient(t, nil)
+	defer cleanup()
+
+	got, err := c.discoverAccounts()
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
+
+func TestVaultClient_RefreshDiscoveredAccounts(t *testing.T) {
+	resps := map[string]map[string]interface{}{
+		"/v1/engine/metadata/accounts":            {"keys": []interface{}{"fromFile", "discovered"}},
+		"/v1/engine/metadata/accounts/fromFile":   currentVersionMetadata(1, "", map[string]interface{}{"address": layoutTestAddr}),
+		"/v1/engine/metadata/accounts/discovered": currentVersionMetadata(1, "", map[string]interface{}{"address": otherTestAddr}),
+	}
+	c, cleanup := discoveryClient(t, resps)
+	defer cleanup()
+
+	fileURL, err := url.Parse("http://vault/v1/engine/data/accounts/fromFile?version=1")
+	require.NoError(t, err)
+	newAccount := config.NewAccount{SecretName: "accounts/fromFile"}
+	fromFile := newAccount.AccountFile("/path/to/file", layoutTestAddr, 1)
+	c.accts[fileURL] = fromFile
+
+	require.NoError(t, c.refreshDiscoveredAccounts())
+
+	// the account in the account directory is kept in place of the discovered account with the same address
+	require.Len(t, c.accts, 2)
+	got, err := c.getAccount(mustAddress(t, layoutTestAddr))
+	require.NoError(t, err)
+	require.Equal(t, fromFile, got)
+
+	got, err = c.getAccount(mustAddress(t, otherTestAddr))
+	require.NoError(t, err)
+	require.True(t, got.Discovered)
+
+	// accounts no longer in Vault are removed on refresh
+	resps["/v1/engine/metadata/accounts"] = map[string]interface{}{"keys": []interface{}{"fromFile"}}
+	require.NoError(t, c.refreshDiscoveredAccounts())
+
+	require.Len(t, c.accts, 1)
+	require.False(t, c.hasAccount(mustAddress(t, otherTestAddr)))
+}
+
+func TestVaultClient_RemoveAccount_Discovered(t *testing.T) {
+	c, cleanup := discoveryClient(t, map[string]map[string]interface{}{
+		"/v1/engine/metadata/accounts":            {"keys": []interface{}{"discovered"}},
+		"/v1/engine/metadata/accounts/discovered": currentVersionMetadata(1, "", map[string]interface{}{"address": otherTestAddr}),
+	})
+	defer cleanup()
+
+	require.NoError(t, c.refreshDiscoveredAccounts())
+	acct, err := c.getAccount(mustAddress(t, otherTestAddr))
+	require.NoError(t, err)
+
+	require.NoError(t, c.removeAccount(acct))
+	require.Empty(t, c.accts)
+}
+
+func mustAddress(t *testing.T, hex string) account.Address {
+	addr, err := account.NewAddressFromHexString(hex)
+	require.NoError(t, err)
+	return addr
+}
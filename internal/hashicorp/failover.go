@@ -0,0 +1,115 @@
+package hashicorp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// failoverTransport sends the client's requests to the Vault address currently in use.  If the node at that address
+// cannot be reached, or responds that it is sealed or is a standby unable to service the request, the request is sent
+// to each of the other addresses in order until one succeeds.  The address that succeeded is used for subsequent
+// requests.
+type failoverTransport struct {
+	base      http.RoundTripper
+	addresses []*url.URL
+	current   int
+	mu        sync.RWMutex
+	onSwitch  func(addr *url.URL) // called after the transport switches to a new address
+}
+
+func newFailoverTransport(base http.RoundTripper, addresses []*url.URL) *failoverTransport {
+	return &failoverTransport{
+		base:      base,
+		addresses: addresses,
+	}
+}
+
+// address returns the Vault address currently in use
+func (t *failoverTransport) address() *url.URL {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.addresses[t.current]
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	primary := t.addresses[0]
+	if req.URL.Scheme != primary.Scheme || req.URL.Host != primary.Host {
+		// e.g. a standby's redirect to the active node, which is followed as-is
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	t.mu.RLock()
+	start := t.current
+	t.mu.RUnlock()
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := 0; i < len(t.addresses); i++ {
+		n := (start + i) % len(t.addresses)
+		addr := t.addresses[n]
+
+		r := req.Clone(req.Context())
+		r.URL.Scheme = addr.Scheme
+		r.URL.Host = addr.Host
+		r.Host = addr.Host
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err = t.base.RoundTrip(r)
+		if !shouldFailover(resp, err) || req.Context().Err() != nil {
+			if n != start {
+				t.switchTo(n)
+			}
+			return resp, err
+		}
+		if i == len(t.addresses)-1 {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		log.Printf("[WARN] Vault at %v is unavailable, trying %v: %v", addr, t.addresses[(n+1)%len(t.addresses)], failoverReason(resp, err))
+	}
+	return resp, err
+}
+
+func (t *failoverTransport) switchTo(n int) {
+	t.mu.Lock()
+	t.current = n
+	t.mu.Unlock()
+
+	log.Printf("[WARN] failed over to Vault at %v", t.addresses[n])
+	if t.onSwitch != nil {
+		t.onSwitch(t.addresses[n])
+	}
+}
+
+// shouldFailover returns true if the node could not be reached or is sealed or a standby unable to service requests
+func shouldFailover(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+func failoverReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
@@ -0,0 +1,201 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// failoverServer returns a server that responds with status and records the bodies of the requests it receives
+func failoverServer(status int, gotBodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		*gotBodies = append(*gotBodies, string(b))
+		w.WriteHeader(status)
+	}))
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	return u
+}
+
+func TestFailoverTransport_FailsOver(t *testing.T) {
+	var sealedBodies, activeBodies []string
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	sealed := failoverServer(http.StatusServiceUnavailable, &sealedBodies)
+	defer sealed.Close()
+	active := failoverServer(http.StatusOK, &activeBodies)
+	defer active.Close()
+
+	transport := newFailoverTransport(http.DefaultTransport, []*url.URL{
+		mustParseURL(t, down.URL),
+		mustParseURL(t, sealed.URL),
+		mustParseURL(t, active.URL),
+	})
+	var switchedTo []string
+	transport.onSwitch = func(addr *url.URL) {
+		switchedTo = append(switchedTo, addr.String())
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(down.URL+"/v1/engine/data/mysecret", "application/json", strings.NewReader(`{"data":{}}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{`{"data":{}}`}, sealedBodies)
+	require.Equal(t, []string{`{"data":{}}`}, activeBodies)
+	require.Equal(t, active.URL, transport.address().String())
+	require.Equal(t, []string{active.URL}, switchedTo)
+
+	// subsequent requests go straight to the address in use
+	_, err = client.Get(down.URL + "/v1/engine/data/mysecret")
+	require.NoError(t, err)
+	require.Len(t, sealedBodies, 1)
+	require.Len(t, activeBodies, 2)
+	require.Len(t, switchedTo, 1)
+}
+
+func TestFailoverTransport_AllUnavailable(t *testing.T) {
+	var bodies1, bodies2 []string
+
+	sealed1 := failoverServer(http.StatusServiceUnavailable, &bodies1)
+	defer sealed1.Close()
+	sealed2 := failoverServer(http.StatusServiceUnavailable, &bodies2)
+	defer sealed2.Close()
+
+	transport := newFailoverTransport(http.DefaultTransport, []*url.URL{
+		mustParseURL(t, sealed1.URL),
+		mustParseURL(t, sealed2.URL),
+	})
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(sealed1.URL + "/v1/sys/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Len(t, bodies1, 1)
+	require.Len(t, bodies2, 1)
+	require.Equal(t, sealed1.URL, transport.address().String())
+}
+
+func TestFailoverTransport_OtherHostsNotRewritten(t *testing.T) {
+	var primaryBodies, otherBodies []string
+
+	primary := failoverServer(http.StatusServiceUnavailable, &primaryBodies)
+	defer primary.Close()
+	other := failoverServer(http.StatusServiceUnavailable, &otherBodies)
+	defer other.Close()
+
+	transport := newFailoverTransport(http.DefaultTransport, []*url.URL{
+		mustParseURL(t, primary.URL),
+		mustParseURL(t, primary.URL),
+	})
+	client := &http.Client{Transport: transport}
+
+	// e.g. a redirect from a standby to the active node
+	_, err := client.Get(other.URL + "/v1/engine/data/mysecret")
+	require.NoError(t, err)
+	require.Len(t, otherBodies, 1)
+	require.Empty(t, primaryBodies)
+}
+
+func TestVaultClient_Switched_RequestsReauthentication(t *testing.T) {
+	c := &vaultClient{reauth: make(chan struct{}, 1)}
+
+	c.switched(nil)
+	c.switched(nil) // does not block if re-authentication has already been requested
+
+	select {
+	case <-c.reauth:
+	default:
+		t.Fatal("re-authentication not requested")
+	}
+}
+
+func TestAccountManager_Status_ReportsVaultAddress(t *testing.T) {
+	transport := newFailoverTransport(http.DefaultTransport, []*url.URL{
+		mustParseURL(t, "https://vault-1:8200"),
+		mustParseURL(t, "https://vault-2:8200"),
+	})
+	transport.current = 1
+	a := &accountManager{
+		client:   &vaultClient{failover: transport},
+		unlocked: make(map[string]*lockableKey),
+	}
+
+	got, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), using Vault at https://vault-2:8200", got)
+}
+
+func TestVaultClient_AwaitFailover_Token(t *testing.T) {
+	c := &vaultClient{
+		authenticator: &tokenAuthenticator{},
+		failover: newFailoverTransport(http.DefaultTransport, []*url.URL{
+			mustParseURL(t, "https://vault-1:8200"),
+			mustParseURL(t, "https://vault-2:8200"),
+		}),
+		reauth: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	done := make(chan struct{})
+	go func() {
+		c.awaitFailover(config.VaultClientAuthentication{Token: "env://TOKEN"})
+		close(done)
+	}()
+
+	// the token cannot be re-obtained so each request is handled by logging a warning
+	for i := 0; i < 2; i++ {
+		c.switched(nil)
+		require.Eventually(t, func() bool { return len(c.reauth) == 0 }, time.Second, 10*time.Millisecond)
+	}
+	require.NoError(t, c.authStatus.err())
+
+	close(c.stop)
+	<-done
+}
+
+// countingAuthenticator creates a non-renewable token each time it logs in
+type countingAuthenticator struct {
+	stubAuthenticator
+	logins int32
+}
+
+func (a *countingAuthenticator) Login(*api.Client, config.VaultClientAuthentication) (*api.Secret, error) {
+	atomic.AddInt32(&a.logins, 1)
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "new", Renewable: false}}, nil
+}
+
+func (a *countingAuthenticator) RenewalPolicy() RenewalPolicy { return RenewAndReauthenticate }
+
+func TestVaultClient_AwaitFailover_NonRenewableToken(t *testing.T) {
+	c, _, cleanup := pathsClient(t, 2, nil)
+	defer cleanup()
+	authenticator := &countingAuthenticator{}
+	c.authenticator = authenticator
+	c.failover = newFailoverTransport(http.DefaultTransport, []*url.URL{mustParseURL(t, "https://vault-1:8200")})
+	c.reauth = make(chan struct{}, 1)
+	c.stop = make(chan struct{})
+	defer close(c.stop)
+
+	r, err := c.login(config.VaultClientAuthentication{})
+	require.NoError(t, err)
+	require.NoError(t, r.startAuthenticationRenewal(c, config.VaultClientAuthentication{}))
+
+	// a new token is obtained after each failover
+	for i := int32(2); i <= 3; i++ {
+		c.switched(nil)
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&authenticator.logins) == i }, time.Second, 10*time.Millisecond)
+	}
+}
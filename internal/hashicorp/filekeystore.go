@@ -0,0 +1,364 @@
+package hashicorp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileKeyStoreExt = ".json"
+
+	// scrypt parameters used to derive the encryption key of each secret version from the key store passphrase
+	fileKeyStoreScryptN      = 1 << 15
+	fileKeyStoreScryptR      = 8
+	fileKeyStoreScryptP      = 1
+	fileKeyStoreScryptKeyLen = 32
+	fileKeyStoreSaltLen      = 32
+)
+
+// fileKeyStore is an insecure KeyStore for development and test networks that stores secrets in local files so that
+// Vault is not needed.  Each secret is a file containing all of its versions.  The data of each version is encrypted
+// with AES-256-GCM using a key derived from the key store passphrase and a random salt using scrypt.
+type fileKeyStore struct {
+	dir        string
+	passphrase []byte
+	mu         *sync.Mutex // shared by the key stores of all engines
+	err        error       // set if the key store's engine name is invalid, and returned by every operation
+}
+
+// fileKeyStoreSecret is the contents of a secret's file
+type fileKeyStoreSecret struct {
+	Versions       []fileKeyStoreVersion
+	CustomMetadata map[string]string `json:",omitempty"`
+}
+
+type fileKeyStoreVersion struct {
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+	Deleted    bool `json:",omitempty"` // the version has been soft-deleted and can be undeleted
+	Destroyed  bool `json:",omitempty"` // the version's data has been permanently removed
+}
+
+// health returns the health of the given version of the secret, or of its current version if version is 0, along with
+// the version
+func (s fileKeyStoreSecret) health(version int64) (SecretHealth, int64) {
+	if version == 0 {
+		version = int64(len(s.Versions))
+	}
+	switch {
+	case version < 1 || version > int64(len(s.Versions)):
+		return SecretMissing, version
+	case s.Versions[version-1].Destroyed:
+		return SecretDestroyed, version
+	case s.Versions[version-1].Deleted:
+		return SecretDeleted, version
+	}
+	return SecretHealthy, version
+}
+
+func newFileKeyStore(dir, passphrase string) (*fileKeyStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("file key store passphrase is empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &fileKeyStore{
+		dir:        dir,
+		passphrase: []byte(passphrase),
+		mu:         new(sync.Mutex),
+	}, nil
+}
+
+// forEngine returns a key store for the secrets of the named KV engine, which are kept in a sub-directory so that
+// secrets with the same name in different engines are distinct.  Engine names are read from account files so the
+// sub-directory must not be outside the key store's directory.
+func (s *fileKeyStore) forEngine(engineName string) *fileKeyStore {
+	ks := &fileKeyStore{
+		dir:        filepath.Join(s.dir, engineName),
+		passphrase: s.passphrase,
+		mu:         s.mu,
+	}
+	if engineName == "" || filepath.IsAbs(engineName) || strings.Contains(engineName, "..") || strings.ContainsAny(engineName, `/\`) {
+		ks.err = fmt.Errorf("invalid KV engine name %v", engineName)
+	}
+	return ks
+}
+
+func (s *fileKeyStore) ReadKey(name string, version int64) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return nil, err
+	}
+	health, version := secret.health(version)
+	if err := health.err(name, version); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.decrypt(secret.Versions[version-1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt version %v of secret %v, check the key store passphrase: %v", version, name, err)
+	}
+	defer zero(plaintext)
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *fileKeyStore) WriteKey(name string, data map[string]interface{}, cas *uint64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return 0, err
+	}
+	current := uint64(len(secret.Versions))
+	if cas != nil && *cas != current {
+		return 0, fmt.Errorf("check-and-set parameter did not match the current version %v of secret %v", current, name)
+	}
+
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	defer zero(plaintext)
+
+	version, err := s.encrypt(plaintext)
+	if err != nil {
+		return 0, err
+	}
+	secret.Versions = append(secret.Versions, version)
+
+	if err := s.write(name, secret); err != nil {
+		return 0, err
+	}
+	return int64(len(secret.Versions)), nil
+}
+
+func (s *fileKeyStore) DeleteKey(name string, version int64, destroy bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return 0, err
+	}
+	health, version := secret.health(version)
+	if health == SecretMissing {
+		return 0, health.err(name, version)
+	}
+
+	v := &secret.Versions[version-1]
+	if destroy {
+		*v = fileKeyStoreVersion{Destroyed: true}
+	} else {
+		v.Deleted = true
+	}
+	return version, s.write(name, secret)
+}
+
+func (s *fileKeyStore) RemoveKey(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileKeyStore) ListKeys(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	root := filepath.Join(s.dir, filepath.FromSlash(prefix))
+	if root != s.dir && !strings.HasPrefix(root, s.dir+string(filepath.Separator)) {
+		return nil, fmt.Errorf("invalid secret prefix %v", prefix)
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var names []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, fileKeyStoreExt) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), fileKeyStoreExt))
+		return nil
+	})
+	return names, err
+}
+
+func (s *fileKeyStore) KeyHealth(name string, version int64) (SecretHealth, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return "", 0, err
+	}
+	health, version := secret.health(version)
+	return health, version, nil
+}
+
+func (s *fileKeyStore) ReadMetadata(name string) (KeyMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	health, version := secret.health(0)
+	return KeyMetadata{Version: version, Health: health, CustomMetadata: secret.CustomMetadata}, nil
+}
+
+func (s *fileKeyStore) WriteMetadata(name string, customMetadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.read(name)
+	if err != nil {
+		return err
+	}
+	secret.CustomMetadata = customMetadata
+	return s.write(name, secret)
+}
+
+// path returns the path of the named secret's file
+func (s *fileKeyStore) path(name string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(name)+fileKeyStoreExt)
+	if !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid secret name %v", name)
+	}
+	return path, nil
+}
+
+// read returns the contents of the named secret's file, or a secret with no versions if the file does not exist
+func (s *fileKeyStore) read(name string) (fileKeyStoreSecret, error) {
+	var secret fileKeyStoreSecret
+
+	path, err := s.path(name)
+	if err != nil {
+		return secret, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return secret, nil
+	}
+	if err != nil {
+		return secret, err
+	}
+	if err := json.Unmarshal(b, &secret); err != nil {
+		return secret, fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+	}
+	return secret, nil
+}
+
+// write writes the named secret's file to a temporary file first then renames it so that the write appears atomic
+func (s *fileKeyStore) write(name string, secret fileKeyStoreSecret) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%v*.tmp", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+
+	return os.Rename(f.Name(), path)
+}
+
+func (s *fileKeyStore) encrypt(plaintext []byte) (fileKeyStoreVersion, error) {
+	salt := make([]byte, fileKeyStoreSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return fileKeyStoreVersion{}, err
+	}
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return fileKeyStoreVersion{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fileKeyStoreVersion{}, err
+	}
+	return fileKeyStoreVersion{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+func (s *fileKeyStore) decrypt(v fileKeyStoreVersion) ([]byte, error) {
+	gcm, err := s.cipher(v.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, v.Nonce, v.Ciphertext, nil)
+}
+
+// cipher returns the AES-256-GCM cipher using the key derived from the passphrase and salt
+func (s *fileKeyStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(s.passphrase, salt, fileKeyStoreScryptN, fileKeyStoreScryptR, fileKeyStoreScryptP, fileKeyStoreScryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
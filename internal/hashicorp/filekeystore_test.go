@@ -0,0 +1,309 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testFileKeyStore(t *testing.T) (*fileKeyStore, func()) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+
+	ks, err := newFileKeyStore(dir, "passphrase")
+	require.NoError(t, err)
+	return ks.forEngine("engine"), func() { os.RemoveAll(dir) }
+}
+
+func TestFileKeyStore_WriteReadKey(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	v1, err := ks.WriteKey("accts/mysecret", map[string]interface{}{layoutTestAddr: "key1"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), v1)
+
+	v2, err := ks.WriteKey("accts/mysecret", map[string]interface{}{layoutTestAddr: "key2"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v2)
+
+	got, err := ks.ReadKey("accts/mysecret", 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{layoutTestAddr: "key1"}, got)
+
+	got, err = ks.ReadKey("accts/mysecret", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{layoutTestAddr: "key2"}, got)
+
+	_, err = ks.ReadKey("accts/mysecret", 3)
+	require.EqualError(t, err, "account secret not found: version 3 of secret accts/mysecret does not exist")
+
+	_, err = ks.ReadKey("accts/other", 0)
+	require.EqualError(t, err, "account secret not found: secret accts/other does not exist")
+
+	// the key is not stored in plaintext
+	b, err := ioutil.ReadFile(filepath.Join(ks.dir, "accts", "mysecret.json"))
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "key1")
+	require.NotContains(t, string(b), layoutTestAddr)
+}
+
+func TestFileKeyStore_WriteKey_CheckAndSet(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	cas := uint64(0)
+	_, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "b"}, &cas)
+	require.NoError(t, err)
+
+	_, err = ks.WriteKey("mysecret", map[string]interface{}{"a": "c"}, &cas)
+	require.EqualError(t, err, "check-and-set parameter did not match the current version 1 of secret mysecret")
+
+	cas = 1
+	v, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "c"}, &cas)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v)
+}
+
+func TestFileKeyStore_ListKeys(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	for _, name := range []string{"accts/a", "accts/sub/b", "other"} {
+		_, err := ks.WriteKey(name, map[string]interface{}{"a": "b"}, nil)
+		require.NoError(t, err)
+	}
+
+	got, err := ks.ListKeys("accts")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"accts/a", "accts/sub/b"}, got)
+
+	got, err = ks.ListKeys("")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"accts/a", "accts/sub/b", "other"}, got)
+
+	got, err = ks.ListKeys("missing")
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
+
+func TestFileKeyStore_DeleteKey(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		_, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "b"}, nil)
+		require.NoError(t, err)
+	}
+
+	v, err := ks.DeleteKey("mysecret", 1, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), v)
+
+	// version 0 deletes the current version
+	v, err = ks.DeleteKey("mysecret", 0, false)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), v)
+
+	var tests = map[string]struct {
+		version int64
+		want    SecretHealth
+	}{
+		"destroyed": {version: 1, want: SecretDestroyed},
+		"healthy":   {version: 2, want: SecretHealthy},
+		"deleted":   {version: 3, want: SecretDeleted},
+		"latest":    {version: 0, want: SecretDeleted},
+		"missing":   {version: 4, want: SecretMissing},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, _, err := ks.KeyHealth("mysecret", tt.version)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = ks.ReadKey("mysecret", 1)
+	require.EqualError(t, err, "account secret destroyed: version 1 of secret mysecret has been destroyed")
+	_, err = ks.ReadKey("mysecret", 0)
+	require.EqualError(t, err, "account secret deleted: version 3 of secret mysecret has been deleted, undelete it in Vault to use the account")
+
+	// the destroyed version's data is removed
+	secret, err := ks.read("mysecret")
+	require.NoError(t, err)
+	require.Empty(t, secret.Versions[0].Ciphertext)
+
+	_, err = ks.DeleteKey("other", 0, true)
+	require.EqualError(t, err, "account secret not found: secret other does not exist")
+}
+
+func TestFileKeyStore_RemoveKey(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	_, err := ks.WriteKey("accts/mysecret", map[string]interface{}{"a": "b"}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, ks.RemoveKey("accts/mysecret"))
+	got, err := ks.ListKeys("")
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	// removing a secret that does not exist is not an error
+	require.NoError(t, ks.RemoveKey("accts/mysecret"))
+}
+
+func TestFileKeyStore_Metadata(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	md, err := ks.ReadMetadata("mysecret")
+	require.NoError(t, err)
+	require.Equal(t, KeyMetadata{Health: SecretMissing}, md)
+
+	for i := 0; i < 2; i++ {
+		_, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "b"}, nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, ks.WriteMetadata("mysecret", map[string]string{"address": layoutTestAddr}))
+
+	md, err = ks.ReadMetadata("mysecret")
+	require.NoError(t, err)
+	require.Equal(t, KeyMetadata{Version: 2, Health: SecretHealthy, CustomMetadata: map[string]string{"address": layoutTestAddr}}, md)
+
+	// the custom metadata is kept by later writes
+	_, err = ks.WriteKey("mysecret", map[string]interface{}{"a": "c"}, nil)
+	require.NoError(t, err)
+	md, err = ks.ReadMetadata("mysecret")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), md.Version)
+	require.Equal(t, map[string]string{"address": layoutTestAddr}, md.CustomMetadata)
+}
+
+func TestFileKeyStore_WrongPassphrase(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	_, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "b"}, nil)
+	require.NoError(t, err)
+
+	wrong := &fileKeyStore{dir: ks.dir, passphrase: []byte("wrong"), mu: ks.mu}
+	_, err = wrong.ReadKey("mysecret", 1)
+	require.EqualError(t, err, "unable to decrypt version 1 of secret mysecret, check the key store passphrase: cipher: message authentication failed")
+}
+
+func TestFileKeyStore_InvalidName(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	_, err := ks.WriteKey("../../escape", map[string]interface{}{"a": "b"}, nil)
+	require.EqualError(t, err, "invalid secret name ../../escape")
+}
+
+func TestFileKeyStore_InvalidEngineName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	root, err := newFileKeyStore(filepath.Join(dir, "keys"), "passphrase")
+	require.NoError(t, err)
+
+	for _, engineName := range []string{"../../etc", "..", "/etc", "engine/sub", `engine\sub`} {
+		t.Run(engineName, func(t *testing.T) {
+			ks := root.forEngine(engineName)
+
+			_, err := ks.WriteKey("mysecret", map[string]interface{}{"a": "b"}, nil)
+			require.EqualError(t, err, "invalid KV engine name "+engineName)
+			_, err = ks.ReadKey("mysecret", 0)
+			require.EqualError(t, err, "invalid KV engine name "+engineName)
+			_, err = ks.ListKeys("")
+			require.EqualError(t, err, "invalid KV engine name "+engineName)
+		})
+	}
+
+	// nothing is written outside the key store's directory
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestFileKeyStore_ListKeys_InvalidPrefix(t *testing.T) {
+	ks, cleanup := testFileKeyStore(t)
+	defer cleanup()
+
+	_, err := ks.ListKeys("../other")
+	require.EqualError(t, err, "invalid secret prefix ../other")
+}
+
+func TestAccountManager_FileKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filekeystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := config.VaultClient{
+		// nothing is listening at the Vault address as Vault is not used
+		Vault:            &url.URL{Scheme: "http", Host: "localhost:1"},
+		KVEngineName:     "engine",
+		AccountDirectory: &url.URL{Scheme: "file", Path: filepath.Join(dir, "accts") + "/"},
+		KeyStore: config.VaultClientKeyStore{
+			Type:       config.FileKeyStoreType,
+			Directory:  &url.URL{Scheme: "file", Path: filepath.Join(dir, "keys")},
+			Passphrase: "passphrase",
+		},
+		InsecureDev: true,
+	}
+
+	am, err := NewAccountManager(conf)
+	require.NoError(t, err)
+	defer am.Close()
+
+	acct, err := am.NewAccount(config.NewAccount{SecretName: "mysecret", CustomMetadata: map[string]string{"team": "a"}})
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:1/v1/engine/data/mysecret?version=1", acct.URL.String())
+
+	ks, err := newFileKeyStore(filepath.Join(dir, "keys"), "passphrase")
+	require.NoError(t, err)
+	md, err := ks.forEngine("engine").ReadMetadata("mysecret")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "a", "address": acct.Address.ToHexString()}, md.CustomMetadata)
+
+	status, err := am.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), using the insecure file key store", status)
+
+	// an account manager using the same key store can use the account
+	am.Close()
+	am, err = NewAccountManager(conf)
+	require.NoError(t, err)
+
+	require.True(t, am.Contains(acct.Address))
+	require.NoError(t, am.TimedUnlock(acct.Address, 0))
+	sig, err := am.Sign(acct.Address, make([]byte, 32))
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+
+	require.NoError(t, am.DeleteAccount(acct.Address, false))
+	require.False(t, am.Contains(acct.Address))
+	health, _, err := ks.forEngine("engine").KeyHealth("mysecret", 1)
+	require.NoError(t, err)
+	require.Equal(t, SecretDeleted, health)
+}
+
+func TestNewAccountManager_FileKeyStore_RequiresInsecureDev(t *testing.T) {
+	conf := config.VaultClient{
+		Vault:        &url.URL{Scheme: "http", Host: "localhost:1"},
+		KVEngineName: "engine",
+		KeyStore: config.VaultClientKeyStore{
+			Type:       config.FileKeyStoreType,
+			Directory:  &url.URL{Scheme: "file", Path: "/tmp/keys"},
+			Passphrase: "passphrase",
+		},
+	}
+
+	_, err := NewAccountManager(conf)
+	require.EqualError(t, err, config.InvalidInsecureDev)
+}
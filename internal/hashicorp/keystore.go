@@ -0,0 +1,149 @@
+package hashicorp
+
+import (
+	"fmt"
+	"log"
+)
+
+// KeyStore stores the versioned secrets containing account keys.  Each write of a secret creates a new version.
+type KeyStore interface {
+	// ReadKey returns the data of the given version of the named secret, or of the latest version if version is 0
+	ReadKey(name string, version int64) (map[string]interface{}, error)
+	// WriteKey writes data as a new version of the named secret and returns the new version.  If cas is not nil the
+	// write only succeeds if the current version of the secret is *cas, where 0 means the secret must not exist.
+	WriteKey(name string, data map[string]interface{}, cas *uint64) (int64, error)
+	// DeleteKey deletes the given version of the named secret, or the current version if version is 0, and returns the
+	// deleted version.  If destroy is true the version's data is permanently removed, otherwise it is soft-deleted and
+	// can be undeleted.
+	DeleteKey(name string, version int64, destroy bool) (int64, error)
+	// RemoveKey permanently deletes all versions and the metadata of the named secret
+	RemoveKey(name string) error
+	// ListKeys returns the names of the secrets under prefix, including those under any sub-paths
+	ListKeys(prefix string) ([]string, error)
+	// KeyHealth returns the health of the given version of the named secret, or of the current version if version is
+	// 0, along with the version
+	KeyHealth(name string, version int64) (SecretHealth, int64, error)
+	// ReadMetadata returns the metadata of the current version of the named secret
+	ReadMetadata(name string) (KeyMetadata, error)
+	// WriteMetadata sets the custom metadata of the named secret
+	WriteMetadata(name string, customMetadata map[string]string) error
+}
+
+// KeyMetadata is the metadata of the current version of a secret
+type KeyMetadata struct {
+	Version        int64        // the current version, or 0 if the secret is unversioned
+	Health         SecretHealth // the health of the current version
+	CustomMetadata map[string]string
+}
+
+// kvKeyStore is the KeyStore of a Vault KV secret engine.  KV v1 secrets are unversioned and do not support
+// check-and-set, so versions and cas are ignored.
+type kvKeyStore struct {
+	client *vaultClient
+	engine kvEngine
+}
+
+// ReadKey resolves version 0 to the current version of KV v2 secrets using the secret's metadata, so that the version
+// that is read is known and a missing, deleted or destroyed current version is reported as such
+func (s *kvKeyStore) ReadKey(name string, version int64) (map[string]interface{}, error) {
+	if version == 0 && s.engine.version != kvVersion1 {
+		latest, err := s.latestVersion(name)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[DEBUG] using latest version %v of secret %v/%v", latest, s.engine.name, name)
+		version = latest
+	}
+	return s.client.readKVSecret(s.engine, name, version)
+}
+
+func (s *kvKeyStore) WriteKey(name string, data map[string]interface{}, cas *uint64) (int64, error) {
+	if cas != nil && s.engine.version == kvVersion1 {
+		log.Printf("[WARN] overwrite protection is not supported by KV v1 engines, any existing secret %v will be overwritten", name)
+	}
+	return s.client.writeKVSecret(s.engine, name, data, cas)
+}
+
+// DeleteKey deletes the whole secret of KV v1 engines, which are unversioned and cannot be undeleted, so destroy must
+// be true.  A soft-deleted current version of a KV v2 secret can still be destroyed.
+func (s *kvKeyStore) DeleteKey(name string, version int64, destroy bool) (int64, error) {
+	if version == 0 && s.engine.version != kvVersion1 {
+		health, latest, err := s.client.kvSecretHealth(s.engine, name, 0)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get the latest version of secret %v: %v", name, err)
+		}
+		if health == SecretMissing {
+			return 0, health.err(name, latest)
+		}
+		version = latest
+	}
+	return version, s.client.deleteKVSecretVersion(s.engine, name, version, destroy)
+}
+
+func (s *kvKeyStore) RemoveKey(name string) error {
+	return s.client.deleteKVSecret(s.engine, name)
+}
+
+func (s *kvKeyStore) ListKeys(prefix string) ([]string, error) {
+	return s.client.listKVSecrets(s.engine, prefix)
+}
+
+func (s *kvKeyStore) KeyHealth(name string, version int64) (SecretHealth, int64, error) {
+	return s.client.kvSecretHealth(s.engine, name, version)
+}
+
+// ReadMetadata reads the KV v2 metadata of the secret.  KV v1 secrets have no metadata so only their health is
+// returned.
+func (s *kvKeyStore) ReadMetadata(name string) (KeyMetadata, error) {
+	if s.engine.version == kvVersion1 {
+		health, _, err := s.client.kvSecretHealth(s.engine, name, 0)
+		return KeyMetadata{Health: health}, err
+	}
+	md, err := s.client.readKVMetadata(s.engine, name)
+	if err == errKVSecretNotFound {
+		return KeyMetadata{Health: SecretMissing}, nil
+	}
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	health, version := md.health(0)
+	return KeyMetadata{Version: version, Health: health, CustomMetadata: md.customMetadata}, nil
+}
+
+func (s *kvKeyStore) WriteMetadata(name string, customMetadata map[string]string) error {
+	return s.client.writeKVCustomMetadata(s.engine, name, customMetadata)
+}
+
+// latestVersion returns the current version of the KV v2 secret, or an error wrapping the ErrSecret error if the
+// current version is missing, deleted or destroyed
+func (s *kvKeyStore) latestVersion(name string) (int64, error) {
+	health, latest, err := s.client.kvSecretHealth(s.engine, name, 0)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the latest version of secret %v: %v", name, err)
+	}
+	if err := health.err(name, latest); err != nil {
+		return 0, err
+	}
+	return latest, nil
+}
+
+// keyStore returns the KeyStore of the named KV engine, or of the client's configured engine if name is empty.  If the
+// file key store is configured it is used for all engines.
+func (c *vaultClient) keyStore(engineName string) KeyStore {
+	if c.fileKeyStore != nil {
+		if engineName == "" {
+			engineName = c.kvEngineName
+		}
+		return c.fileKeyStore.forEngine(engineName)
+	}
+	return &kvKeyStore{client: c, engine: c.kvEngine(engineName)}
+}
+
+// keyStoreKVVersion returns the version of the KV engine API the named engine's secrets are addressed with in account
+// URLs.  The file key store is versioned like a KV v2 engine.
+func (c *vaultClient) keyStoreKVVersion(engineName string) int {
+	if c.fileKeyStore != nil {
+		return kvVersion2
+	}
+	return c.kvEngine(engineName).version
+}
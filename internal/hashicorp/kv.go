@@ -0,0 +1,395 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kvVersion1 = 1
+	kvVersion2 = 2
+)
+
+// errCustomMetadataUnsupported is returned when Vault ignores a write of custom_metadata, which requires Vault 1.9+
+var errCustomMetadataUnsupported = errors.New("custom_metadata is not supported by this version of Vault, Vault 1.9+ is required")
+
+// kvEngine is a KV secret engine and its version
+type kvEngine struct {
+	name    string
+	version int
+}
+
+// dataPath returns the path of the secret's data.  KV v2 data is under the data/ prefix, KV v1 secrets are unversioned
+// and stored directly under the engine's path.
+func (e kvEngine) dataPath(secretName string) string {
+	if e.version == kvVersion1 {
+		return fmt.Sprintf("%v/%v", e.name, secretName)
+	}
+	return fmt.Sprintf("%v/data/%v", e.name, secretName)
+}
+
+// versionsPath returns the path of a KV v2 operation on versions of the secret, e.g. delete or destroy
+func (e kvEngine) versionsPath(operation, secretName string) string {
+	return fmt.Sprintf("%v/%v/%v", e.name, operation, secretName)
+}
+
+// metadataPath returns the path of the secret's KV v2 metadata
+func (e kvEngine) metadataPath(secretName string) string {
+	return fmt.Sprintf("%v/metadata/%v", e.name, secretName)
+}
+
+// detectKVVersion uses sys/internal/ui/mounts to get the version of the KV secret engine.  Mounts without a version
+// option are KV v1.
+func (c *vaultClient) detectKVVersion(engineName string) (int, error) {
+	resp, err := c.Logical().Read(fmt.Sprintf("sys/internal/ui/mounts/%v", engineName))
+	if err != nil {
+		return 0, err
+	}
+	if resp == nil || resp.Data == nil {
+		return 0, errors.New("empty response from Vault")
+	}
+	options, _ := resp.Data["options"].(map[string]interface{})
+	if v, _ := options["version"].(string); v == strconv.Itoa(kvVersion2) {
+		return kvVersion2, nil
+	}
+	return kvVersion1, nil
+}
+
+// detectKVVersionOrDefault detects the version of the KV secret engine, falling back to KV v2 if detection fails
+func (c *vaultClient) detectKVVersionOrDefault(engineName string) int {
+	v, err := c.detectKVVersion(engineName)
+	if err != nil {
+		log.Printf("[WARN] unable to detect version of KV engine %v, using KV v2: set kvVersion to skip detection, err = %v", engineName, err)
+		v = kvVersion2
+	}
+	log.Printf("[DEBUG] using KV v%v engine %v", v, engineName)
+	return v
+}
+
+// setKVVersion sets the version of the client's KV secret engine.  If no version is configured it is detected from
+// Vault, falling back to KV v2 if detection fails.
+func (c *vaultClient) setKVVersion(configured int) {
+	if configured != 0 {
+		c.kvVersion = configured
+		return
+	}
+	c.kvVersion = c.detectKVVersionOrDefault(c.kvEngineName)
+}
+
+// kvEngine returns the named KV secret engine, or the client's configured engine if name is empty.  The versions of
+// other engines are detected the first time they are used.
+func (c *vaultClient) kvEngine(name string) kvEngine {
+	if name == "" || name == c.kvEngineName {
+		return kvEngine{name: c.kvEngineName, version: c.kvVersion}
+	}
+
+	c.kvVersionsMu.Lock()
+	defer c.kvVersionsMu.Unlock()
+
+	v, ok := c.kvVersions[name]
+	if !ok {
+		v = c.detectKVVersionOrDefault(name)
+		if c.kvVersions == nil {
+			c.kvVersions = make(map[string]int)
+		}
+		c.kvVersions[name] = v
+	}
+	return kvEngine{name: name, version: v}
+}
+
+// readKVSecret returns the data of the given version of the secret.  KV v1 secrets are unversioned so version is
+// ignored.
+func (c *vaultClient) readKVSecret(engine kvEngine, secretName string, version int64) (map[string]interface{}, error) {
+	if engine.version == kvVersion1 {
+		resp, err := c.Logical().Read(engine.dataPath(secretName))
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, errors.New("empty response from Vault")
+		}
+		if resp.Data == nil {
+			return nil, errors.New("no secret information returned from Vault")
+		}
+		return resp.Data, nil
+	}
+
+	reqData := make(map[string][]string)
+	reqData["version"] = []string{strconv.FormatInt(version, 10)}
+
+	resp, err := c.Logical().ReadWithData(engine.dataPath(secretName), reqData)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("empty response from Vault")
+	}
+
+	respData, ok := resp.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no secret information returned from Vault")
+	}
+	return respData, nil
+}
+
+// writeKVSecret writes data to the secret and returns the new version of the secret.  If cas is not nil the write only
+// succeeds if the current version of the secret is *cas.  KV v1 secrets are unversioned and do not support
+// check-and-set, so cas is ignored and 0 is returned.
+func (c *vaultClient) writeKVSecret(engine kvEngine, secretName string, data map[string]interface{}, cas *uint64) (int64, error) {
+	if engine.version == kvVersion1 {
+		_, err := c.Logical().Write(engine.dataPath(secretName), data)
+		return 0, err
+	}
+
+	body := make(map[string]interface{})
+	body["data"] = data
+	if cas != nil {
+		body["options"] = map[string]interface{}{
+			"cas": *cas,
+		}
+	}
+
+	resp, err := c.Logical().Write(engine.dataPath(secretName), body)
+	if err != nil {
+		return 0, err
+	}
+	if resp == nil {
+		return 0, errors.New("empty response from Vault")
+	}
+	return getVersionFromResponse(resp.Data)
+}
+
+// writeKVCustomMetadata sets the custom_metadata of the secret.  Only KV v2 secrets have metadata.  Vault < 1.9 ignores
+// custom_metadata with a warning, in which case errCustomMetadataUnsupported is returned.
+func (c *vaultClient) writeKVCustomMetadata(engine kvEngine, secretName string, customMetadata map[string]string) error {
+	if engine.version == kvVersion1 {
+		return errors.New("custom metadata is not supported by KV v1 engines")
+	}
+	body := map[string]interface{}{
+		"custom_metadata": customMetadata,
+	}
+	resp, err := c.Logical().Write(engine.metadataPath(secretName), body)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		for _, w := range resp.Warnings {
+			if strings.Contains(w, "custom_metadata") {
+				return errCustomMetadataUnsupported
+			}
+		}
+	}
+	return nil
+}
+
+// customMetadataSupported returns false if writing custom_metadata to the KV engine has been found to be unsupported
+func (c *vaultClient) customMetadataSupported(engineName string) bool {
+	c.noCustomMetadataMu.Lock()
+	defer c.noCustomMetadataMu.Unlock()
+
+	if engineName == "" {
+		engineName = c.kvEngineName
+	}
+	return !c.noCustomMetadata[engineName]
+}
+
+// setCustomMetadataUnsupported records that the KV engine does not support custom_metadata, returning false if this
+// was already known
+func (c *vaultClient) setCustomMetadataUnsupported(engineName string) bool {
+	c.noCustomMetadataMu.Lock()
+	defer c.noCustomMetadataMu.Unlock()
+
+	if engineName == "" {
+		engineName = c.kvEngineName
+	}
+	if c.noCustomMetadata[engineName] {
+		return false
+	}
+	if c.noCustomMetadata == nil {
+		c.noCustomMetadata = make(map[string]bool)
+	}
+	c.noCustomMetadata[engineName] = true
+	return true
+}
+
+// deleteKVSecretVersion deletes the version of the secret.  If destroy is true the version's data is permanently
+// removed, otherwise the version is soft-deleted and can be undeleted.  KV v1 secrets are unversioned and cannot be
+// undeleted, so the whole secret is deleted and destroy must be true.
+func (c *vaultClient) deleteKVSecretVersion(engine kvEngine, secretName string, version int64, destroy bool) error {
+	if engine.version == kvVersion1 {
+		if !destroy {
+			return errors.New("soft-delete is not supported by KV v1 engines, destroy must be set to delete the secret")
+		}
+		_, err := c.Logical().Delete(engine.dataPath(secretName))
+		return err
+	}
+
+	if version < 1 {
+		return fmt.Errorf("invalid secret version %v", version)
+	}
+	operation := "delete"
+	if destroy {
+		operation = "destroy"
+	}
+	body := map[string]interface{}{
+		"versions": []int64{version},
+	}
+	_, err := c.Logical().Write(engine.versionsPath(operation, secretName), body)
+	return err
+}
+
+// deleteKVSecret permanently deletes the secret.  All versions and the metadata of KV v2 secrets are deleted.
+func (c *vaultClient) deleteKVSecret(engine kvEngine, secretName string) error {
+	path := engine.metadataPath(secretName)
+	if engine.version == kvVersion1 {
+		path = engine.dataPath(secretName)
+	}
+	_, err := c.Logical().Delete(path)
+	return err
+}
+
+// listKVSecrets returns the names of the secrets under path, including those under any sub-paths.  The names are
+// relative to the engine, i.e. they include path.
+func (c *vaultClient) listKVSecrets(engine kvEngine, path string) ([]string, error) {
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+	listPath := engine.metadataPath(path)
+	if engine.version == kvVersion1 {
+		listPath = engine.dataPath(path)
+	}
+
+	resp, err := c.Logical().List(listPath)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		// there are no secrets under path
+		return nil, nil
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+
+	var names []string
+	for _, k := range keys {
+		key, _ := k.(string)
+		if key == "" {
+			continue
+		}
+		if !strings.HasSuffix(key, "/") {
+			names = append(names, path+key)
+			continue
+		}
+		sub, err := c.listKVSecrets(engine, path+key)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, sub...)
+	}
+	return names, nil
+}
+
+// kvMetadata is the KV v2 metadata of a secret
+type kvMetadata struct {
+	currentVersion int64
+	customMetadata map[string]string
+	versions       map[int64]kvVersionMetadata
+}
+
+// kvVersionMetadata is the KV v2 metadata of a version of a secret
+type kvVersionMetadata struct {
+	deleted   bool // the version has been soft-deleted and can be undeleted
+	destroyed bool // the version's data has been permanently removed
+}
+
+// errKVSecretNotFound is returned by readKVMetadata if the secret does not exist
+var errKVSecretNotFound = errors.New("secret not found")
+
+// readKVMetadata returns the metadata of the secret.  Only KV v2 secrets have metadata.
+func (c *vaultClient) readKVMetadata(engine kvEngine, secretName string) (kvMetadata, error) {
+	if engine.version == kvVersion1 {
+		return kvMetadata{}, errors.New("metadata is not supported by KV v1 engines")
+	}
+	resp, err := c.Logical().Read(engine.metadataPath(secretName))
+	if err != nil {
+		return kvMetadata{}, err
+	}
+	if resp == nil {
+		return kvMetadata{}, errKVSecretNotFound
+	}
+	if resp.Data == nil {
+		return kvMetadata{}, errors.New("empty response from Vault")
+	}
+
+	currentVersion, err := getInt64(resp.Data["current_version"])
+	if err != nil {
+		return kvMetadata{}, fmt.Errorf("invalid current_version returned from Vault, %v", err)
+	}
+
+	md := kvMetadata{
+		currentVersion: currentVersion,
+		versions:       make(map[int64]kvVersionMetadata),
+	}
+	if custom, ok := resp.Data["custom_metadata"].(map[string]interface{}); ok {
+		md.customMetadata = make(map[string]string, len(custom))
+		for k, v := range custom {
+			md.customMetadata[k], _ = v.(string)
+		}
+	}
+	versions, _ := resp.Data["versions"].(map[string]interface{})
+	for k, v := range versions {
+		version, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return kvMetadata{}, fmt.Errorf("invalid version %v returned from Vault", k)
+		}
+		vMetadata, _ := v.(map[string]interface{})
+		deletionTime, _ := vMetadata["deletion_time"].(string)
+		destroyed, _ := vMetadata["destroyed"].(bool)
+		md.versions[version] = kvVersionMetadata{
+			deleted:   isDeleted(deletionTime),
+			destroyed: destroyed,
+		}
+	}
+	return md, nil
+}
+
+// isDeleted returns true if the KV v2 deletion_time of a version is set and has passed.  Versions can be scheduled for
+// deletion in the future using the engine's delete_version_after setting.
+func isDeleted(deletionTime string) bool {
+	if deletionTime == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, deletionTime)
+	if err != nil {
+		return true
+	}
+	return !t.After(time.Now())
+}
+
+func getVersionFromResponse(data map[string]interface{}) (int64, error) {
+	v, ok := data["version"]
+	if !ok {
+		return 0, errors.New("no version information returned from Vault")
+	}
+	vJson, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.New("invalid version information returned from Vault")
+	}
+	secretVersion, err := vJson.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid version information returned from Vault, %v", err)
+	}
+	return secretVersion, nil
+}
+
+func getInt64(v interface{}) (int64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+	return n.Int64()
+}
@@ -0,0 +1,298 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+// kvClient returns a client for a Vault server that responds to all requests with resp, or with no content if resp is
+// nil.  The last request received and its decoded body are also returned.
+func kvClient(t *testing.T, kvVersion int, resp map[string]interface{}) (*vaultClient, *http.Request, *map[string]interface{}, func()) {
+	var (
+		gotReq  = new(http.Request)
+		gotBody = new(map[string]interface{})
+	)
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotReq = *r
+		*gotBody = nil
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(gotBody)
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: resp})
+		_, _ = w.Write(b)
+	}))
+	c.kvVersion = kvVersion
+	return c, gotReq, gotBody, cleanup
+}
+
+// recordedRequest is a request received by a mock Vault server, with its decoded body
+type recordedRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   map[string]interface{}
+}
+
+// pathsClient returns a client for a Vault server that responds to requests for each path in resps with the
+// corresponding data.  Reads and lists of any other path are responded to with 404 Not Found, and writes and deletes
+// with no content.  resps can be changed between requests.  The requests received are also returned, in order.
+func pathsClient(t *testing.T, kvVersion int, resps map[string]map[string]interface{}) (*vaultClient, *[]recordedRequest, func()) {
+	var (
+		mu   sync.Mutex
+		reqs = new([]recordedRequest)
+	)
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		req := recordedRequest{method: r.Method, path: r.URL.Path, query: r.URL.Query()}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+		*reqs = append(*reqs, req)
+
+		resp, ok := resps[r.URL.Path]
+		switch {
+		case ok:
+			b, _ := json.Marshal(&api.Secret{Data: resp})
+			_, _ = w.Write(b)
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	c.kvVersion = kvVersion
+	return c, reqs, cleanup
+}
+
+func TestVaultClient_DetectKVVersion(t *testing.T) {
+	var mounts = map[string]struct {
+		resp map[string]interface{}
+		want int
+	}{
+		"v2":         {resp: map[string]interface{}{"type": "kv", "options": map[string]interface{}{"version": "2"}}, want: 2},
+		"v1":         {resp: map[string]interface{}{"type": "kv", "options": map[string]interface{}{"version": "1"}}, want: 1},
+		"no_options": {resp: map[string]interface{}{"type": "kv", "options": nil}, want: 1},
+	}
+
+	for name, tt := range mounts {
+		t.Run(name, func(t *testing.T) {
+			c, gotReq, _, cleanup := kvClient(t, 0, tt.resp)
+			defer cleanup()
+
+			got, err := c.detectKVVersion("engine")
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, "/v1/sys/internal/ui/mounts/engine", gotReq.URL.Path)
+		})
+	}
+}
+
+func TestVaultClient_SetKVVersion(t *testing.T) {
+	c, _, _, cleanup := kvClient(t, 0, map[string]interface{}{"options": map[string]interface{}{"version": "2"}})
+	defer cleanup()
+
+	c.setKVVersion(1)
+	require.Equal(t, 1, c.kvVersion)
+
+	c.setKVVersion(0)
+	require.Equal(t, 2, c.kvVersion)
+}
+
+func TestVaultClient_SetKVVersion_DetectionFailsDefaultsToV2(t *testing.T) {
+	c, _, _, cleanup := kvClient(t, 0, nil)
+	defer cleanup()
+
+	c.setKVVersion(0)
+	require.Equal(t, 2, c.kvVersion)
+}
+
+func TestVaultClient_KVEngine(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 1, map[string]interface{}{"options": map[string]interface{}{"version": "2"}})
+	defer cleanup()
+
+	require.Equal(t, kvEngine{name: "engine", version: 1}, c.kvEngine(""))
+	require.Equal(t, kvEngine{name: "engine", version: 1}, c.kvEngine("engine"))
+
+	require.Equal(t, kvEngine{name: "other", version: 2}, c.kvEngine("other"))
+	require.Equal(t, "/v1/sys/internal/ui/mounts/other", gotReq.URL.Path)
+
+	// the detected version is cached
+	gotReq.URL.Path = ""
+	require.Equal(t, kvEngine{name: "other", version: 2}, c.kvEngine("other"))
+	require.Empty(t, gotReq.URL.Path)
+}
+
+func TestVaultClient_ReadKVSecret(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 2, map[string]interface{}{"data": map[string]interface{}{"addr": "key"}})
+	defer cleanup()
+
+	got, err := c.readKVSecret(c.kvEngine(""), "mysecret", 3)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"addr": "key"}, got)
+	require.Equal(t, "/v1/engine/data/mysecret", gotReq.URL.Path)
+	require.Equal(t, "3", gotReq.URL.Query().Get("version"))
+}
+
+func TestVaultClient_ReadKVSecret_KVv1(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 1, map[string]interface{}{"addr": "key"})
+	defer cleanup()
+
+	got, err := c.readKVSecret(c.kvEngine(""), "mysecret", 3)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"addr": "key"}, got)
+	require.Equal(t, "/v1/engine/mysecret", gotReq.URL.Path)
+	require.Empty(t, gotReq.URL.Query())
+}
+
+func TestVaultClient_ReadKVSecret_OtherEngine(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 2, map[string]interface{}{"data": map[string]interface{}{"addr": "key"}})
+	defer cleanup()
+
+	_, err := c.readKVSecret(kvEngine{name: "other", version: 2}, "mysecret", 3)
+	require.NoError(t, err)
+	require.Equal(t, "/v1/other/data/mysecret", gotReq.URL.Path)
+}
+
+func TestVaultClient_WriteKVSecret(t *testing.T) {
+	c, gotReq, gotBody, cleanup := kvClient(t, 2, map[string]interface{}{"version": 4})
+	defer cleanup()
+
+	cas := uint64(3)
+	got, err := c.writeKVSecret(c.kvEngine(""), "mysecret", map[string]interface{}{"addr": "key"}, &cas)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), got)
+	require.Equal(t, "/v1/engine/data/mysecret", gotReq.URL.Path)
+	require.Equal(t, map[string]interface{}{
+		"data":    map[string]interface{}{"addr": "key"},
+		"options": map[string]interface{}{"cas": float64(3)},
+	}, *gotBody)
+}
+
+func TestVaultClient_WriteKVSecret_KVv1(t *testing.T) {
+	c, gotReq, gotBody, cleanup := kvClient(t, 1, nil)
+	defer cleanup()
+
+	cas := uint64(3)
+	got, err := c.writeKVSecret(c.kvEngine(""), "mysecret", map[string]interface{}{"addr": "key"}, &cas)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), got)
+	require.Equal(t, "/v1/engine/mysecret", gotReq.URL.Path)
+	require.Equal(t, map[string]interface{}{"addr": "key"}, *gotBody)
+}
+
+func TestVaultClient_WriteKVCustomMetadata(t *testing.T) {
+	c, gotReq, gotBody, cleanup := kvClient(t, 2, nil)
+	defer cleanup()
+
+	err := c.writeKVCustomMetadata(c.kvEngine(""), "mysecret", map[string]string{"owner": "team1"})
+	require.NoError(t, err)
+	require.Equal(t, "/v1/engine/metadata/mysecret", gotReq.URL.Path)
+	require.Equal(t, map[string]interface{}{"custom_metadata": map[string]interface{}{"owner": "team1"}}, *gotBody)
+}
+
+func TestVaultClient_WriteKVCustomMetadata_KVv1(t *testing.T) {
+	c, _, _, cleanup := kvClient(t, 1, nil)
+	defer cleanup()
+
+	err := c.writeKVCustomMetadata(c.kvEngine(""), "mysecret", map[string]string{"owner": "team1"})
+	require.EqualError(t, err, "custom metadata is not supported by KV v1 engines")
+}
+
+func TestVaultClient_WriteKVCustomMetadata_Unsupported(t *testing.T) {
+	// Vault < 1.9 ignores custom_metadata and warns of the unrecognised parameter
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal(&api.Secret{Warnings: []string{"Endpoint ignored these unrecognized parameters: [custom_metadata]"}})
+		_, _ = w.Write(b)
+	}))
+	defer cleanup()
+	c.kvVersion = 2
+
+	err := c.writeKVCustomMetadata(c.kvEngine(""), "mysecret", map[string]string{"owner": "team1"})
+	require.Equal(t, errCustomMetadataUnsupported, err)
+}
+
+func TestVaultClient_DeleteKVSecretVersion(t *testing.T) {
+	var deletes = map[string]struct {
+		destroy  bool
+		wantPath string
+	}{
+		"soft_delete": {destroy: false, wantPath: "/v1/engine/delete/mysecret"},
+		"destroy":     {destroy: true, wantPath: "/v1/engine/destroy/mysecret"},
+	}
+
+	for name, tt := range deletes {
+		t.Run(name, func(t *testing.T) {
+			c, gotReq, gotBody, cleanup := kvClient(t, 2, nil)
+			defer cleanup()
+
+			err := c.deleteKVSecretVersion(c.kvEngine(""), "mysecret", 3, tt.destroy)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPath, gotReq.URL.Path)
+			require.Equal(t, map[string]interface{}{"versions": []interface{}{float64(3)}}, *gotBody)
+		})
+	}
+}
+
+func TestVaultClient_DeleteKVSecretVersion_KVv1(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 1, nil)
+	defer cleanup()
+
+	err := c.deleteKVSecretVersion(c.kvEngine(""), "mysecret", 0, false)
+	require.EqualError(t, err, "soft-delete is not supported by KV v1 engines, destroy must be set to delete the secret")
+
+	err = c.deleteKVSecretVersion(c.kvEngine(""), "mysecret", 0, true)
+	require.NoError(t, err)
+	require.Equal(t, http.MethodDelete, gotReq.Method)
+	require.Equal(t, "/v1/engine/mysecret", gotReq.URL.Path)
+}
+
+func TestVaultClient_ReadKVMetadata(t *testing.T) {
+	c, gotReq, _, cleanup := kvClient(t, 2, map[string]interface{}{
+		"current_version": 3,
+		"custom_metadata": map[string]interface{}{"address": "addr"},
+		"versions": map[string]interface{}{
+			"1": map[string]interface{}{"deletion_time": "", "destroyed": true},
+			"2": map[string]interface{}{"deletion_time": "2020-01-01T00:00:00.000000Z", "destroyed": false},
+			"3": map[string]interface{}{"deletion_time": "", "destroyed": false},
+		},
+	})
+	defer cleanup()
+
+	got, err := c.readKVMetadata(c.kvEngine(""), "mysecret")
+	require.NoError(t, err)
+	require.Equal(t, "/v1/engine/metadata/mysecret", gotReq.URL.Path)
+	require.Equal(t, kvMetadata{
+		currentVersion: 3,
+		customMetadata: map[string]string{"address": "addr"},
+		versions: map[int64]kvVersionMetadata{
+			1: {destroyed: true},
+			2: {deleted: true},
+			3: {},
+		},
+	}, got)
+}
+
+func TestVaultClient_ReadKVMetadata_KVv1(t *testing.T) {
+	c, _, _, cleanup := kvClient(t, 1, nil)
+	defer cleanup()
+
+	_, err := c.readKVMetadata(c.kvEngine(""), "mysecret")
+	require.EqualError(t, err, "metadata is not supported by KV v1 engines")
+}
+
+func TestIsDeleted(t *testing.T) {
+	require.False(t, isDeleted(""))
+	require.True(t, isDeleted("2020-01-01T00:00:00Z"))
+	require.False(t, isDeleted(time.Now().Add(time.Hour).Format(time.RFC3339Nano)))
+}
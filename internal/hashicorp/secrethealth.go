@@ -0,0 +1,244 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// SecretHealth is the state of the KV secret version an account's key is stored in
+type SecretHealth string
+
+const (
+	SecretHealthy   SecretHealth = "healthy"
+	SecretDeleted   SecretHealth = "deleted"   // the version has been soft-deleted and can be undeleted in Vault
+	SecretDestroyed SecretHealth = "destroyed" // the version's data has been permanently removed
+	SecretMissing   SecretHealth = "missing"   // the secret or version does not exist
+)
+
+// The errors returned (wrapped) by operations that read an account's key when its secret version is not healthy
+var (
+	ErrSecretMissing   = errors.New("account secret not found")
+	ErrSecretDeleted   = errors.New("account secret deleted")
+	ErrSecretDestroyed = errors.New("account secret destroyed")
+)
+
+// err returns an error wrapping the ErrSecret error for h, or nil if the secret is healthy.  version is the secret
+// version the account uses, or 0 if the secret is unversioned or has no versions.
+func (h SecretHealth) err(secretName string, version int64) error {
+	desc := fmt.Sprintf("secret %v", secretName)
+	if version > 0 {
+		desc = fmt.Sprintf("version %v of secret %v", version, secretName)
+	}
+	switch h {
+	case SecretMissing:
+		return fmt.Errorf("%w: %v does not exist", ErrSecretMissing, desc)
+	case SecretDeleted:
+		return fmt.Errorf("%w: %v has been deleted, undelete it in Vault to use the account", ErrSecretDeleted, desc)
+	case SecretDestroyed:
+		return fmt.Errorf("%w: %v has been destroyed", ErrSecretDestroyed, desc)
+	}
+	return nil
+}
+
+// isSecretHealthErr returns true if err is caused by an unhealthy secret version
+func isSecretHealthErr(err error) bool {
+	return errors.Is(err, ErrSecretMissing) || errors.Is(err, ErrSecretDeleted) || errors.Is(err, ErrSecretDestroyed)
+}
+
+// AccountHealth is the result of checking the secret of an account
+type AccountHealth struct {
+	Account account.Account
+	Health  SecretHealth // empty if the secret could not be checked
+	Version int64        // the secret version the account uses, resolved to the current version for accounts using the latest version
+	Err     error        // the reason the secret could not be checked
+}
+
+// kvSecretHealth returns the health of the given version of the secret, or of its current version if version is 0,
+// along with the version.  The health of KV v2 secrets is read from the secret's metadata.  KV v1 secrets are
+// unversioned and have no metadata, so they are either healthy or missing.  Reading a KV v1 secret would return its
+// data, i.e. the account's private key, so instead the secrets alongside it are listed to check that it exists.
+func (c *vaultClient) kvSecretHealth(engine kvEngine, secretName string, version int64) (SecretHealth, int64, error) {
+	if engine.version == kvVersion1 {
+		parent, name := path.Split(secretName)
+		resp, err := c.Logical().List(engine.dataPath(parent))
+		if err != nil {
+			return "", 0, err
+		}
+		if resp == nil || resp.Data == nil {
+			// there are no secrets under parent
+			return SecretMissing, 0, nil
+		}
+		keys, _ := resp.Data["keys"].([]interface{})
+		for _, k := range keys {
+			if k == name {
+				return SecretHealthy, 0, nil
+			}
+		}
+		return SecretMissing, 0, nil
+	}
+
+	md, err := c.readKVMetadata(engine, secretName)
+	if err == errKVSecretNotFound {
+		return SecretMissing, version, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	health, version := md.health(version)
+	return health, version, nil
+}
+
+// health returns the health of the given version of the secret, or of its current version if version is 0, along with
+// the version
+func (md kvMetadata) health(version int64) (SecretHealth, int64) {
+	if version == 0 {
+		version = md.currentVersion
+	}
+	v, ok := md.versions[version]
+	switch {
+	case version < 1 || !ok:
+		// versions older than the engine's max_versions are removed from the metadata
+		return SecretMissing, version
+	case v.destroyed:
+		return SecretDestroyed, version
+	case v.deleted:
+		return SecretDeleted, version
+	}
+	return SecretHealthy, version
+}
+
+// accountSecretHealth returns the health of the account's secret in the key store of the Vault connection the account
+// is stored in.  Transit accounts have no secret, so false is returned.
+func (c *vaultClient) accountSecretHealth(acctFile config.AccountFile) (SecretHealth, int64, bool, error) {
+	if acctFile.Contents.IsTransitAccount() {
+		return "", 0, false, nil
+	}
+	conn, err := c.connection(acctFile.Contents.Connection)
+	if err != nil {
+		return "", 0, true, err
+	}
+	if err := conn.authStatus.err(); err != nil {
+		return "", 0, true, err
+	}
+	conf := acctFile.Contents.VaultAccount
+	health, version, err := conn.keyStore(conf.KVEngineName).KeyHealth(conf.SecretName, conf.SecretVersion)
+	return health, version, true, err
+}
+
+// readKeyErr returns the error to report when the key of the account could not be read.  Vault does not distinguish
+// between a deleted, destroyed or missing version when reading a secret, so the secret's health is checked to report
+// the cause instead of Vault's error.
+func (c *vaultClient) readKeyErr(acctFile config.AccountFile, err error) error {
+	if isSecretHealthErr(err) {
+		return err
+	}
+	health, version, ok, healthErr := c.accountSecretHealth(acctFile)
+	if !ok {
+		return err
+	}
+	if healthErr != nil {
+		log.Printf("[DEBUG] unable to check health of secret %v: %v", acctFile.Contents.VaultAccount.SecretName, healthErr)
+		return err
+	}
+	if healthErr = health.err(acctFile.Contents.VaultAccount.SecretName, version); healthErr != nil {
+		return healthErr
+	}
+	return err
+}
+
+// secretHealthCache holds the results of the last check of the health of the accounts' secrets
+type secretHealthCache struct {
+	results map[string]AccountHealth // by hex address
+	mu      sync.RWMutex
+}
+
+// unhealthy returns the addresses and health of the accounts whose secrets were not healthy when last checked, in
+// address order
+func (h *secretHealthCache) unhealthy() []AccountHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var result []AccountHealth
+	for _, r := range h.results {
+		if r.Health != "" && r.Health != SecretHealthy {
+			result = append(result, r)
+		}
+	}
+	sortAccountHealth(result)
+	return result
+}
+
+func (h *secretHealthCache) set(results []AccountHealth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.results = make(map[string]AccountHealth, len(results))
+	for _, r := range results {
+		h.results[r.Account.Address.ToHexString()] = r
+	}
+}
+
+// checkAccountsHealth checks the health of the secrets of all accounts, records the results so that unhealthy accounts
+// are reported in the plugin's Status, and returns the results in address order
+func (c *vaultClient) checkAccountsHealth() []AccountHealth {
+	var results []AccountHealth
+	for u, acctFile := range c.accounts() {
+		health, version, ok, err := c.accountSecretHealth(acctFile)
+		if !ok {
+			continue
+		}
+		addr, addrErr := account.NewAddressFromHexString(acctFile.Contents.Address)
+		if addrErr != nil {
+			log.Printf("[WARN] unable to check health of secret for account %v: %v", acctFile.Contents.Address, addrErr)
+			continue
+		}
+		r := AccountHealth{
+			Account: account.Account{Address: addr, URL: u},
+			Health:  health,
+			Version: version,
+			Err:     err,
+		}
+		if err != nil {
+			log.Printf("[WARN] unable to check health of secret for account 0x%v: %v", acctFile.Contents.Address, err)
+		} else if health != SecretHealthy {
+			log.Printf("[WARN] account 0x%v: %v", acctFile.Contents.Address, health.err(acctFile.Contents.VaultAccount.SecretName, version))
+		}
+		results = append(results, r)
+	}
+	sortAccountHealth(results)
+
+	c.secretHealth.set(results)
+	return results
+}
+
+// healthCheckLoop checks the health of the accounts' secrets at startup and then every interval until the client is
+// closed
+func (c *vaultClient) healthCheckLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		results := c.checkAccountsHealth()
+		log.Printf("[DEBUG] checked health of %v account secrets", len(results))
+
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sortAccountHealth(results []AccountHealth) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Account.Address.ToHexString() < results[j].Account.Address.ToHexString()
+	})
+}
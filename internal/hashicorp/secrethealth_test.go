@@ -0,0 +1,194 @@
+package hashicorp
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultClient_KVSecretHealth(t *testing.T) {
+	metadata := map[string]interface{}{
+		"current_version": 4,
+		"versions": map[string]interface{}{
+			"2": map[string]interface{}{"deletion_time": "", "destroyed": true},
+			"3": map[string]interface{}{"deletion_time": "2020-01-01T00:00:00Z", "destroyed": false},
+			"4": map[string]interface{}{"deletion_time": "", "destroyed": false},
+		},
+	}
+
+	var tests = map[string]struct {
+		secretName  string
+		version     int64
+		want        SecretHealth
+		wantVersion int64
+	}{
+		"healthy":        {secretName: "mysecret", version: 4, want: SecretHealthy, wantVersion: 4},
+		"latest":         {secretName: "mysecret", version: 0, want: SecretHealthy, wantVersion: 4},
+		"deleted":        {secretName: "mysecret", version: 3, want: SecretDeleted, wantVersion: 3},
+		"destroyed":      {secretName: "mysecret", version: 2, want: SecretDestroyed, wantVersion: 2},
+		"version_pruned": {secretName: "mysecret", version: 1, want: SecretMissing, wantVersion: 1},
+		"version_future": {secretName: "mysecret", version: 5, want: SecretMissing, wantVersion: 5},
+		"secret_missing": {secretName: "other", version: 1, want: SecretMissing, wantVersion: 1},
+	}
+
+	c, _, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/engine/metadata/mysecret": metadata,
+	})
+	defer cleanup()
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotVersion, err := c.kvSecretHealth(c.kvEngine(""), tt.secretName, tt.version)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantVersion, gotVersion)
+		})
+	}
+}
+
+func TestVaultClient_KVSecretHealth_KVv1(t *testing.T) {
+	// only listing is handled so the test fails if the secret's data is read
+	c, _, cleanup := pathsClient(t, 1, map[string]map[string]interface{}{
+		"/v1/engine":       {"keys": []interface{}{"mysecret", "accts/"}},
+		"/v1/engine/accts": {"keys": []interface{}{"nested"}},
+	})
+	defer cleanup()
+
+	var tests = map[string]struct {
+		secretName string
+		want       SecretHealth
+	}{
+		"healthy":        {secretName: "mysecret", want: SecretHealthy},
+		"nested":         {secretName: "accts/nested", want: SecretHealthy},
+		"missing":        {secretName: "other", want: SecretMissing},
+		"missing_parent": {secretName: "other/mysecret", want: SecretMissing},
+		"directory_name": {secretName: "accts", want: SecretMissing},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, _, err := c.kvSecretHealth(c.kvEngine(""), tt.secretName, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecretHealth_Err(t *testing.T) {
+	require.NoError(t, SecretHealthy.err("mysecret", 2))
+
+	err := SecretMissing.err("mysecret", 2)
+	require.True(t, errors.Is(err, ErrSecretMissing))
+	require.EqualError(t, err, "account secret not found: version 2 of secret mysecret does not exist")
+
+	err = SecretDeleted.err("mysecret", 2)
+	require.True(t, errors.Is(err, ErrSecretDeleted))
+	require.EqualError(t, err, "account secret deleted: version 2 of secret mysecret has been deleted, undelete it in Vault to use the account")
+
+	err = SecretDestroyed.err("mysecret", 2)
+	require.True(t, errors.Is(err, ErrSecretDestroyed))
+	require.EqualError(t, err, "account secret destroyed: version 2 of secret mysecret has been destroyed")
+}
+
+func TestAccountManager_TimedUnlock_UnhealthySecret(t *testing.T) {
+	const acctAddr = "6038dc01869425004ca0b8370f6c81cf464213b3" // the address of layoutTestKey
+
+	// Vault responds to reads of deleted and destroyed versions with 404 Not Found, so the metadata is used to report
+	// the cause
+	c, _, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/engine/metadata/mysecret": {
+			"current_version": 2,
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"deletion_time": "", "destroyed": true},
+				"2": map[string]interface{}{"deletion_time": "2020-01-01T00:00:00Z", "destroyed": false},
+			},
+		},
+	})
+	defer cleanup()
+
+	var tests = map[string]struct {
+		secretVersion int64
+		wantErr       error
+	}{
+		"destroyed": {secretVersion: 1, wantErr: ErrSecretDestroyed},
+		"deleted":   {secretVersion: 2, wantErr: ErrSecretDeleted},
+		"missing":   {secretVersion: 3, wantErr: ErrSecretMissing},
+		"latest":    {secretVersion: 0, wantErr: ErrSecretDeleted},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			newAcct := config.NewAccount{SecretName: "mysecret"}
+			c.accts = accountsByURL{
+				&url.URL{Path: "acct"}: newAcct.AccountFile("", acctAddr, tt.secretVersion),
+			}
+			a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+
+			addr, err := account.NewAddressFromHexString(acctAddr)
+			require.NoError(t, err)
+
+			err = a.TimedUnlock(addr, 0)
+			require.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+			require.Empty(t, a.unlocked)
+		})
+	}
+}
+
+func TestAccountManager_CheckAccounts(t *testing.T) {
+	c, _, cleanup := pathsClient(t, 2, map[string]map[string]interface{}{
+		"/v1/engine/metadata/healthy": {
+			"current_version": 1,
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"deletion_time": "", "destroyed": false},
+			},
+		},
+		"/v1/engine/metadata/destroyed": {
+			"current_version": 1,
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"deletion_time": "", "destroyed": true},
+			},
+		},
+	})
+	defer cleanup()
+
+	var (
+		healthyAcct   = config.NewAccount{SecretName: "healthy"}
+		destroyedAcct = config.NewAccount{SecretName: "destroyed"}
+		missingAcct   = config.NewAccount{SecretName: "missing"}
+		transitAcct   = config.NewAccount{TransitKeyName: "key"}
+	)
+	c.accts = accountsByURL{
+		&url.URL{Path: "healthy"}:   healthyAcct.AccountFile("", "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", 0),
+		&url.URL{Path: "destroyed"}: destroyedAcct.AccountFile("", "6038dc01869425004ca0b8370f6c81cf464213b3", 1),
+		&url.URL{Path: "missing"}:   missingAcct.AccountFile("", "7e5f4552091a69125d5dfcb7b8c2659029395bdf", 1),
+		&url.URL{Path: "transit"}:   transitAcct.TransitAccountFile("", "dc99ddec13457de6c0f6bb8e6cf3955c86f55526", 1),
+	}
+	a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", status)
+
+	got := a.CheckAccounts()
+
+	// transit accounts are not checked
+	require.Len(t, got, 3)
+	require.Equal(t, "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5", got[0].Account.Address.ToHexString())
+	require.Equal(t, SecretHealthy, got[0].Health)
+	require.Equal(t, int64(1), got[0].Version)
+	require.Equal(t, "6038dc01869425004ca0b8370f6c81cf464213b3", got[1].Account.Address.ToHexString())
+	require.Equal(t, SecretDestroyed, got[1].Health)
+	require.Equal(t, "7e5f4552091a69125d5dfcb7b8c2659029395bdf", got[2].Account.Address.ToHexString())
+	require.Equal(t, SecretMissing, got[2].Health)
+	for _, r := range got {
+		require.NoError(t, r.Err)
+	}
+
+	status, err = a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), 2 account(s) with unhealthy secrets: [0x6038dc01869425004ca0b8370f6c81cf464213b3 (destroyed) 0x7e5f4552091a69125d5dfcb7b8c2659029395bdf (missing)]", status)
+}
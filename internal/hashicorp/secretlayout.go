@@ -0,0 +1,126 @@
+package hashicorp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+const defaultSecretLayoutKeyField = "privateKey"
+
+func secretLayoutKeyField(layout config.VaultClientSecretLayout) string {
+	if layout.KeyField == "" {
+		return defaultSecretLayoutKeyField
+	}
+	return layout.KeyField
+}
+
+// privateKeyFromSecret returns the hex private key of the account with address addrHex from the data of a secret
+// stored using layout.  Fields not used by the layout are ignored.
+func privateKeyFromSecret(layout config.VaultClientSecretLayout, data map[string]interface{}, addrHex string) (string, error) {
+	if !layout.IsNamedField() {
+		privKey, ok := data[addrHex]
+		if !ok {
+			return "", fmt.Errorf("response does not contain data for account address %v", addrHex)
+		}
+		s, ok := privKey.(string)
+		if !ok {
+			return "", fmt.Errorf("invalid data for account address %v", addrHex)
+		}
+		return s, nil
+	}
+
+	keyField := secretLayoutKeyField(layout)
+	privKey, ok := data[keyField].(string)
+	if !ok {
+		return "", fmt.Errorf("response does not contain %v field", keyField)
+	}
+	if layout.AddressField != "" {
+		addr, _ := data[layout.AddressField].(string)
+		if !sameAddress(addr, addrHex) {
+			return "", fmt.Errorf("%v field %v does not match account address %v", layout.AddressField, addr, addrHex)
+		}
+	}
+	return privKey, nil
+}
+
+// addressFromSecret returns the hex address of the account stored in the data of a secret using layout.  With the
+// addressKeyed layout the data must contain exactly one address field.  With the namedField layout the address is read
+// from the AddressField if configured, otherwise it is derived from the key.
+func addressFromSecret(layout config.VaultClientSecretLayout, data map[string]interface{}) (string, error) {
+	if !layout.IsNamedField() {
+		var addrs []string
+		for k := range data {
+			if addr, err := account.NewAddressFromHexString(k); err == nil && addr.ToHexString() == k {
+				addrs = append(addrs, k)
+			}
+		}
+		if len(addrs) != 1 {
+			return "", fmt.Errorf("secret must contain exactly one account address field, found %v", len(addrs))
+		}
+		return addrs[0], nil
+	}
+
+	if layout.AddressField != "" {
+		a, _ := data[layout.AddressField].(string)
+		addr, err := account.NewAddressFromHexString(a)
+		if err != nil {
+			return "", fmt.Errorf("invalid %v field: %v", layout.AddressField, err)
+		}
+		return addr.ToHexString(), nil
+	}
+
+	keyField := secretLayoutKeyField(layout)
+	privKey, ok := data[keyField].(string)
+	if !ok {
+		return "", fmt.Errorf("secret does not contain %v field", keyField)
+	}
+	key, err := account.NewKeyFromHexString(privKey)
+	if err != nil {
+		return "", err
+	}
+	defer zeroKey(key)
+
+	addr, err := account.PrivateKeyToAddress(key)
+	if err != nil {
+		return "", err
+	}
+	return addr.ToHexString(), nil
+}
+
+// secretData returns the data of a new secret storing the account's key using layout.  extraFields are added to the
+// data and must not use the same names as the fields used by the layout.
+func secretData(layout config.VaultClientSecretLayout, addrHex, keyHex string, extraFields map[string]string) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(extraFields)+2)
+	for k, v := range extraFields {
+		data[k] = v
+	}
+
+	set := func(field, value string) error {
+		if _, ok := data[field]; ok {
+			return fmt.Errorf("extra field %v is reserved by the secret layout", field)
+		}
+		data[field] = value
+		return nil
+	}
+
+	if !layout.IsNamedField() {
+		return data, set(addrHex, keyHex)
+	}
+	if err := set(secretLayoutKeyField(layout), keyHex); err != nil {
+		return nil, err
+	}
+	if layout.AddressField != "" {
+		if err := set(layout.AddressField, addrHex); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// sameAddress returns true if the hex addresses are equal, ignoring case and any 0x prefix
+func sameAddress(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+}
@@ -0,0 +1,110 @@
+package hashicorp
+
+import (
+	"testing"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	layoutTestAddr = "4d6d744b6da435b5bbdde2526dc20e9a41cb72e5"
+	layoutTestKey  = "1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b"
+)
+
+func TestPrivateKeyFromSecret(t *testing.T) {
+	namedField := config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout}
+	withAddress := config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout, KeyField: "key", AddressField: "address"}
+
+	var secrets = map[string]struct {
+		layout  config.VaultClientSecretLayout
+		data    map[string]interface{}
+		wantErr string
+	}{
+		"address_keyed":             {data: map[string]interface{}{layoutTestAddr: layoutTestKey}},
+		"address_keyed_extra":       {data: map[string]interface{}{layoutTestAddr: layoutTestKey, "createdBy": "node1"}},
+		"address_keyed_missing":     {data: map[string]interface{}{"other": layoutTestKey}, wantErr: "response does not contain data for account address " + layoutTestAddr},
+		"address_keyed_not_string":  {data: map[string]interface{}{layoutTestAddr: 1}, wantErr: "invalid data for account address " + layoutTestAddr},
+		"named_field":               {layout: namedField, data: map[string]interface{}{"privateKey": layoutTestKey, "createdBy": "node1"}},
+		"named_field_missing":       {layout: namedField, data: map[string]interface{}{layoutTestAddr: layoutTestKey}, wantErr: "response does not contain privateKey field"},
+		"named_field_address":       {layout: withAddress, data: map[string]interface{}{"key": layoutTestKey, "address": "0x" + layoutTestAddr}},
+		"named_field_wrong_address": {layout: withAddress, data: map[string]interface{}{"key": layoutTestKey, "address": "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526"}, wantErr: "address field 0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526 does not match account address " + layoutTestAddr},
+		"named_field_no_address":    {layout: withAddress, data: map[string]interface{}{"key": layoutTestKey}, wantErr: "address field  does not match account address " + layoutTestAddr},
+	}
+
+	for name, tt := range secrets {
+		t.Run(name, func(t *testing.T) {
+			got, err := privateKeyFromSecret(tt.layout, tt.data, layoutTestAddr)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, layoutTestKey, got)
+		})
+	}
+}
+
+func TestAddressFromSecret(t *testing.T) {
+	namedField := config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout}
+	withAddress := config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout, KeyField: "key", AddressField: "address"}
+
+	// the address derived from layoutTestKey
+	const derivedAddr = "6038dc01869425004ca0b8370f6c81cf464213b3"
+
+	var secrets = map[string]struct {
+		layout   config.VaultClientSecretLayout
+		data     map[string]interface{}
+		wantAddr string
+		wantErr  string
+	}{
+		"address_keyed":          {data: map[string]interface{}{layoutTestAddr: layoutTestKey, "createdBy": "node1"}},
+		"address_keyed_none":     {data: map[string]interface{}{"createdBy": "node1"}, wantErr: "secret must contain exactly one account address field, found 0"},
+		"address_keyed_multiple": {data: map[string]interface{}{layoutTestAddr: layoutTestKey, "dc99ddec13457de6c0f6bb8e6cf3955c86f55526": layoutTestKey}, wantErr: "secret must contain exactly one account address field, found 2"},
+		"named_field_derived":    {layout: namedField, data: map[string]interface{}{"privateKey": layoutTestKey}, wantAddr: derivedAddr},
+		"named_field_no_key":     {layout: namedField, data: map[string]interface{}{}, wantErr: "secret does not contain privateKey field"},
+		"named_field_address":    {layout: withAddress, data: map[string]interface{}{"key": layoutTestKey, "address": "0x" + layoutTestAddr}},
+		"named_field_no_address": {layout: withAddress, data: map[string]interface{}{"key": layoutTestKey}, wantErr: "invalid address field: account address must have length 20 bytes"},
+	}
+
+	for name, tt := range secrets {
+		t.Run(name, func(t *testing.T) {
+			got, err := addressFromSecret(tt.layout, tt.data)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantAddr == "" {
+				tt.wantAddr = layoutTestAddr
+			}
+			require.Equal(t, tt.wantAddr, got)
+		})
+	}
+}
+
+func TestSecretData(t *testing.T) {
+	extra := map[string]string{"createdBy": "node1"}
+
+	got, err := secretData(config.VaultClientSecretLayout{}, layoutTestAddr, layoutTestKey, extra)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{layoutTestAddr: layoutTestKey, "createdBy": "node1"}, got)
+
+	got, err = secretData(config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout}, layoutTestAddr, layoutTestKey, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"privateKey": layoutTestKey}, got)
+
+	got, err = secretData(config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout, KeyField: "key", AddressField: "address"}, layoutTestAddr, layoutTestKey, extra)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"key": layoutTestKey, "address": layoutTestAddr, "createdBy": "node1"}, got)
+}
+
+func TestSecretData_ExtraFieldReserved(t *testing.T) {
+	layout := config.VaultClientSecretLayout{Type: config.NamedFieldSecretLayout, AddressField: "address"}
+
+	_, err := secretData(layout, layoutTestAddr, layoutTestKey, map[string]string{"privateKey": "other"})
+	require.EqualError(t, err, "extra field privateKey is reserved by the secret layout")
+
+	_, err = secretData(layout, layoutTestAddr, layoutTestKey, map[string]string{"address": "other"})
+	require.EqualError(t, err, "extra field address is reserved by the secret layout")
+}
@@ -0,0 +1,52 @@
+package hashicorp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// tokenFile is a token sink file (e.g. written by Vault Agent) that is polled for changes so that the client always
+// uses the most recent token.
+type tokenFile struct {
+	path    string
+	token   string
+	modTime time.Time
+}
+
+func newTokenFile(path string) (*tokenFile, error) {
+	f := &tokenFile{path: path}
+	if _, err := f.read(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// read updates the cached token if the file has been modified, returning true if the token has changed
+func (f *tokenFile) read() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("unable to read token file: %v", err)
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return false, nil
+	}
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("unable to read token file: %v", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return false, fmt.Errorf("token file %v is empty", f.path)
+	}
+
+	f.modTime = info.ModTime()
+	if token == f.token {
+		return false, nil
+	}
+	f.token = token
+	return true, nil
+}
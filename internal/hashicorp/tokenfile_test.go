@@ -0,0 +1,71 @@
+package hashicorp
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// rewriteTokenFile writes token to the token file at path, making sure the modification time changes regardless of
+// filesystem timestamp resolution
+func rewriteTokenFile(t *testing.T, path, token string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(token), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func TestTokenFile_Read(t *testing.T) {
+	f, err := ioutil.TempFile("", "token-sink")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("token1\n"), 0600))
+
+	tf, err := newTokenFile(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, "token1", tf.token)
+
+	changed, err := tf.read()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	rewriteTokenFile(t, f.Name(), "token2")
+
+	changed, err = tf.read()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "token2", tf.token)
+}
+
+func TestTokenFile_Empty(t *testing.T) {
+	f, err := ioutil.TempFile("", "token-sink")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = newTokenFile(f.Name())
+	require.EqualError(t, err, "token file "+f.Name()+" is empty")
+}
+
+func TestTokenFile_NotFound(t *testing.T) {
+	_, err := newTokenFile("/does/not/exist")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to read token file")
+}
+
+func TestVaultClient_PollLoop_PollInterval(t *testing.T) {
+	c, _, cleanup := pathsClient(t, 2, nil)
+	defer cleanup()
+	c.authenticator = &countingAuthenticator{}
+	c.reauth = make(chan struct{}, 1)
+	defer close(c.stop)
+
+	// the default interval is a second so the token would not be reloaded in time
+	go c.pollLoop(config.VaultClientAuthentication{TokenFilePollInterval: 10 * time.Millisecond})
+
+	require.Eventually(t, func() bool {
+		return c.Token() == "new"
+	}, 500*time.Millisecond, 10*time.Millisecond)
+}
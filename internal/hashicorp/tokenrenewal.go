@@ -0,0 +1,123 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+)
+
+// tokenLifecycle is the lifecycle information of a directly provided token, as returned by a token lookup-self
+type tokenLifecycle struct {
+	ttl         time.Duration
+	creationTTL time.Duration
+	renewable   bool
+}
+
+func newTokenLifecycle(lookup *api.Secret) (tokenLifecycle, error) {
+	if lookup == nil || lookup.Data == nil {
+		return tokenLifecycle{}, errors.New("empty response from Vault")
+	}
+
+	var (
+		l   tokenLifecycle
+		err error
+	)
+	if l.ttl, err = lookup.TokenTTL(); err != nil {
+		return tokenLifecycle{}, fmt.Errorf("invalid token ttl returned from Vault: %v", err)
+	}
+	if l.renewable, err = lookup.TokenIsRenewable(); err != nil {
+		return tokenLifecycle{}, err
+	}
+	if v, ok := lookup.Data["creation_ttl"]; ok && v != nil {
+		if l.creationTTL, err = parseutil.ParseDurationSecond(v); err != nil {
+			return tokenLifecycle{}, fmt.Errorf("invalid token creation_ttl returned from Vault: %v", err)
+		}
+	}
+	return l, nil
+}
+
+// startTokenRenewal looks up the directly provided token and, if it is renewable, starts the background process for
+// renewing it.  If the token cannot be renewed and will expire, the client's authentication is marked as degraded.  The
+// token can still be used if it cannot be looked up (e.g. its policy does not allow lookup-self), so it is then not
+// renewed and a warning is logged.
+func (c *vaultClient) startTokenRenewal() error {
+	lookup, err := c.Auth().Token().LookupSelf()
+	if err != nil {
+		log.Printf("[WARN] unable to lookup Vault token, it will not be renewed: %v", err)
+		return nil
+	}
+	l, err := newTokenLifecycle(lookup)
+	if err != nil {
+		log.Printf("[WARN] unable to lookup Vault token, it will not be renewed: %v", err)
+		return nil
+	}
+
+	if l.ttl == 0 {
+		log.Print("[DEBUG] Vault token does not expire, renewal not required")
+		return nil
+	}
+	if !l.renewable {
+		c.authStatus.setDegraded(fmt.Sprintf("token is not renewable and will expire at %v", expiry(l.ttl)))
+		return nil
+	}
+
+	renewer, err := c.NewRenewer(&api.RenewerInput{
+		Secret: &api.Secret{
+			Auth: &api.SecretAuth{
+				ClientToken:   c.Token(),
+				Renewable:     true,
+				LeaseDuration: int(l.ttl.Seconds()),
+			},
+		},
+		Increment: int(l.creationTTL.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	go c.tokenRenewalLoop(renewer, l.creationTTL)
+	return nil
+}
+
+// tokenRenewalLoop renews the directly provided token until it can no longer be renewed.  Unlike the auth method
+// renewal in renewalLoop, the plugin has no credentials to get a new token with so once the token is approaching its
+// max TTL the authentication is marked as degraded.  The loop exits when the client is closed.
+func (c *vaultClient) tokenRenewalLoop(renewer *api.Renewer, increment time.Duration) {
+	go renewer.Renew()
+
+	for {
+		select {
+		case <-c.stop:
+			renewer.Stop()
+			return
+
+		case renewal := <-renewer.RenewCh():
+			if renewal.Secret == nil || renewal.Secret.Auth == nil {
+				continue
+			}
+			ttl := time.Duration(renewal.Secret.Auth.LeaseDuration) * time.Second
+			if increment != 0 && ttl < increment {
+				c.authStatus.setDegraded(fmt.Sprintf("token is approaching its max TTL and will expire at %v", expiry(ttl)))
+				continue
+			}
+			log.Print("[DEBUG] successfully renewed Vault token")
+
+		case err := <-renewer.DoneCh():
+			switch err {
+			case nil:
+				c.authStatus.setDegraded("token has reached its max TTL and can no longer be renewed, a new token must be provided")
+			default:
+				c.authStatus.setDegraded(fmt.Sprintf("renewal of token failed, err = %v", err))
+			}
+			return
+		}
+	}
+}
+
+func expiry(ttl time.Duration) string {
+	return time.Now().Add(ttl).UTC().Format(time.RFC3339)
+}
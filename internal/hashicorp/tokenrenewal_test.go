@@ -0,0 +1,81 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenLifecycle(t *testing.T) {
+	lookup := &api.Secret{
+		Data: map[string]interface{}{
+			"ttl":          json.Number("3600"),
+			"creation_ttl": json.Number("7200"),
+			"renewable":    true,
+		},
+	}
+
+	got, err := newTokenLifecycle(lookup)
+
+	require.NoError(t, err)
+	require.Equal(t, tokenLifecycle{ttl: time.Hour, creationTTL: 2 * time.Hour, renewable: true}, got)
+}
+
+func TestNewTokenLifecycle_NonExpiring(t *testing.T) {
+	lookup := &api.Secret{
+		Data: map[string]interface{}{
+			"ttl":          json.Number("0"),
+			"creation_ttl": json.Number("0"),
+			"renewable":    false,
+		},
+	}
+
+	got, err := newTokenLifecycle(lookup)
+
+	require.NoError(t, err)
+	require.Equal(t, tokenLifecycle{}, got)
+}
+
+func TestNewTokenLifecycle_EmptyResponse(t *testing.T) {
+	_, err := newTokenLifecycle(nil)
+	require.EqualError(t, err, "empty response from Vault")
+
+	_, err = newTokenLifecycle(&api.Secret{})
+	require.EqualError(t, err, "empty response from Vault")
+}
+
+func TestVaultClient_StartTokenRenewal_LookupFailed(t *testing.T) {
+	// the mock Vault responds to the token lookup with 404 Not Found
+	c, _, cleanup := pathsClient(t, 2, nil)
+	defer cleanup()
+
+	// the token can still be used so the plugin starts, but it is not renewed
+	require.NoError(t, c.startTokenRenewal())
+	require.Empty(t, c.authStatus.degradedReason())
+}
+
+func TestAccountManager_Status_AuthDegraded(t *testing.T) {
+	a := &accountManager{
+		client:   &vaultClient{},
+		unlocked: make(map[string]*lockableKey),
+	}
+
+	got, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", got)
+
+	a.client.authStatus.setDegraded("token has reached its max TTL")
+
+	got, err = a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s), Vault authentication degraded: token has reached its max TTL", got)
+
+	a.client.authStatus.setHealthy()
+
+	got, err = a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", got)
+}
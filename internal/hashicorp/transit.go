@@ -0,0 +1,241 @@
+package hashicorp
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
+)
+
+const defaultTransitKeyType = "ecdsa-p256k1"
+
+// transitSign signs toSign with the Transit key backing acct.  The private key never leaves Vault; the ASN.1 DER
+// signature returned by Vault is converted to the 65-byte [R || S || V] format expected by Quorum.
+func (c *vaultClient) transitSign(acct config.AccountFile, toSign []byte) ([]byte, error) {
+	conf := acct.Contents.TransitAccount
+
+	body := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(toSign),
+		"prehashed":            true,
+		"marshaling_algorithm": "asn1",
+	}
+	if conf.KeyVersion != 0 {
+		body["key_version"] = conf.KeyVersion
+	}
+
+	resp, err := c.Logical().Write(fmt.Sprintf("%v/sign/%v", c.transitEngineName, conf.KeyName), body)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("empty response from Vault")
+	}
+	vaultSig, ok := resp.Data["signature"].(string)
+	if !ok {
+		return nil, errors.New("no signature returned from Vault")
+	}
+
+	der, err := decodeTransitSignature(vaultSig)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := account.NewAddressFromHexString(acct.Contents.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRecoverableSignature(toSign, der, addr)
+}
+
+// createTransitKey creates a new Transit key with the provided name and returns the address derived from its public
+// key along with the key version.  An error is returned if a key with the same name already exists.  Vault does not
+// fail the creation of a key that already exists, so the check and creation are serialised per key name.  This only
+// protects against concurrent creations by this plugin: plugins on other nodes using the same Transit engine can still
+// create a key with the same name between the check and creation.
+func (c *vaultClient) createTransitKey(keyName string) (account.Address, int64, error) {
+	keyLocation := fmt.Sprintf("%v/keys/%v", c.transitEngineName, keyName)
+
+	lock := c.transitKeyLock(keyName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existing, err := c.Logical().Read(keyLocation)
+	if err != nil {
+		return account.Address{}, 0, err
+	}
+	if existing != nil {
+		return account.Address{}, 0, errors.New("transit key already exists")
+	}
+
+	if _, err := c.Logical().Write(keyLocation, map[string]interface{}{"type": c.transitKeyType}); err != nil {
+		return account.Address{}, 0, err
+	}
+
+	resp, err := c.Logical().Read(keyLocation)
+	if err != nil {
+		return account.Address{}, 0, err
+	}
+	if resp == nil {
+		return account.Address{}, 0, errors.New("empty response from Vault")
+	}
+
+	return getTransitPublicKeyAddress(resp.Data)
+}
+
+// transitKeyLock returns the lock serialising the creation of the named Transit key
+func (c *vaultClient) transitKeyLock(keyName string) *sync.Mutex {
+	c.transitKeyLocksMu.Lock()
+	defer c.transitKeyLocksMu.Unlock()
+
+	if c.transitKeyLocks == nil {
+		c.transitKeyLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := c.transitKeyLocks[keyName]
+	if !ok {
+		lock = new(sync.Mutex)
+		c.transitKeyLocks[keyName] = lock
+	}
+	return lock
+}
+
+// deleteTransitKey deletes the named Transit key.  Transit keys can only be deleted once deletion_allowed has been set
+// in the key's config.
+func (c *vaultClient) deleteTransitKey(keyName string) error {
+	keyLocation := fmt.Sprintf("%v/keys/%v", c.transitEngineName, keyName)
+
+	if _, err := c.Logical().Write(keyLocation+"/config", map[string]interface{}{"deletion_allowed": true}); err != nil {
+		return err
+	}
+	_, err := c.Logical().Delete(keyLocation)
+	return err
+}
+
+// getTransitPublicKeyAddress returns the address and version of the latest public key in the data returned from a
+// Transit read key request.
+func getTransitPublicKeyAddress(data map[string]interface{}) (account.Address, int64, error) {
+	latest, ok := data["latest_version"].(json.Number)
+	if !ok {
+		return account.Address{}, 0, errors.New("no version information returned from Vault")
+	}
+	keyVersion, err := latest.Int64()
+	if err != nil {
+		return account.Address{}, 0, fmt.Errorf("invalid version information returned from Vault, %v", err)
+	}
+
+	keys, ok := data["keys"].(map[string]interface{})
+	if !ok {
+		return account.Address{}, 0, errors.New("no key information returned from Vault")
+	}
+	key, ok := keys[strconv.FormatInt(keyVersion, 10)].(map[string]interface{})
+	if !ok {
+		return account.Address{}, 0, fmt.Errorf("no key information returned from Vault for version %v", keyVersion)
+	}
+	pubPEM, ok := key["public_key"].(string)
+	if !ok {
+		return account.Address{}, 0, errors.New("no public key returned from Vault")
+	}
+
+	pubBytes, err := decodeTransitPublicKey(pubPEM)
+	if err != nil {
+		return account.Address{}, 0, err
+	}
+	addr, err := account.PublicKeyBytesToAddress(pubBytes)
+	if err != nil {
+		return account.Address{}, 0, err
+	}
+	return addr, keyVersion, nil
+}
+
+// decodeTransitPublicKey parses the PEM-encoded PKIX public key returned by Vault.  The standard library x509 package
+// does not support the secp256k1 curve so the SubjectPublicKeyInfo structure is parsed directly.
+func decodeTransitPublicKey(pubPEM string) ([]byte, error) {
+	block, _ := pem.Decode([]byte(pubPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key returned from Vault: not PEM-encoded")
+	}
+
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(block.Bytes, &spki); err != nil {
+		return nil, fmt.Errorf("invalid public key returned from Vault: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("invalid public key returned from Vault: trailing data")
+	}
+
+	pubBytes := spki.PublicKey.RightAlign()
+	if len(pubBytes) == 33 {
+		x, y := secp256k1.DecompressPubkey(pubBytes)
+		if x == nil {
+			return nil, errors.New("invalid public key returned from Vault: not a secp256k1 key")
+		}
+		pubBytes = secp256k1.S256().Marshal(x, y)
+	}
+	return pubBytes, nil
+}
+
+// decodeTransitSignature extracts the DER-encoded signature from a Transit signature of the form vault:v<n>:<base64>
+func decodeTransitSignature(vaultSig string) ([]byte, error) {
+	parts := strings.Split(vaultSig, ":")
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, errors.New("invalid signature returned from Vault")
+	}
+	der, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature returned from Vault: %v", err)
+	}
+	return der, nil
+}
+
+// toRecoverableSignature converts a DER-encoded ECDSA signature to the 65-byte [R || S || V] format used by Ethereum.
+// S is normalised to the lower half of the curve order and the recovery ID V is determined by recovering the public key
+// and comparing it to the expected account address.
+func toRecoverableSignature(hash []byte, der []byte, addr account.Address) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("invalid signature returned from Vault: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("invalid signature returned from Vault: trailing data")
+	}
+
+	n := secp256k1.S256().Params().N
+	if sig.S.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		sig.S = new(big.Int).Sub(n, sig.S)
+	}
+
+	rByt, sByt := sig.R.Bytes(), sig.S.Bytes()
+	if len(rByt) > 32 || len(sByt) > 32 {
+		return nil, errors.New("invalid signature returned from Vault: R and S must be at most 32 bytes")
+	}
+
+	result := make([]byte, 65)
+	copy(result[32-len(rByt):32], rByt)
+	copy(result[64-len(sByt):64], sByt)
+
+	for v := byte(0); v < 2; v++ {
+		result[64] = v
+		pub, err := secp256k1.RecoverPubkey(hash, result)
+		if err != nil {
+			continue
+		}
+		recovered, err := account.PublicKeyBytesToAddress(pub)
+		if err == nil && recovered == addr {
+			return result, nil
+		}
+	}
+	return nil, errors.New("unable to determine signature recovery ID: signature was not created by the account's key")
+}
@@ -0,0 +1,319 @@
+package hashicorp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+func transitTestKey(t *testing.T) (*ecdsa.PrivateKey, account.Address) {
+	key, err := account.NewKeyFromHexString("1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b")
+	require.NoError(t, err)
+	addr, err := account.PrivateKeyToAddress(key)
+	require.NoError(t, err)
+	return key, addr
+}
+
+func TestDecodeTransitSignature(t *testing.T) {
+	want := []byte{1, 2, 3}
+
+	got, err := decodeTransitSignature("vault:v1:" + base64.StdEncoding.EncodeToString(want))
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestDecodeTransitSignature_Invalid(t *testing.T) {
+	_, err := decodeTransitSignature("notvault:v1:AQID")
+	require.EqualError(t, err, "invalid signature returned from Vault")
+
+	_, err = decodeTransitSignature("AQID")
+	require.EqualError(t, err, "invalid signature returned from Vault")
+}
+
+func TestDecodeTransitPublicKey(t *testing.T) {
+	key, _ := transitTestKey(t)
+	want := secp256k1.S256().Marshal(key.X, key.Y)
+
+	got, err := decodeTransitPublicKey(testutil.TransitPublicKeyPEM(t, key))
+
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestToRecoverableSignature(t *testing.T) {
+	key, addr := transitTestKey(t)
+	hash, _ := hex.DecodeString("9058f1483aa565541bdf632adbc8d88d58139e567906821a17442f5a06459c70")
+
+	// sign repeatedly so that both possible recovery IDs and high-S values are likely to be exercised
+	for i := 0; i < 20; i++ {
+		r, s, err := ecdsa.Sign(rand.Reader, key, hash)
+		require.NoError(t, err)
+		der, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+		require.NoError(t, err)
+
+		got, err := toRecoverableSignature(hash, der, addr)
+		require.NoError(t, err)
+		require.Len(t, got, 65)
+
+		pub, err := secp256k1.RecoverPubkey(hash, got)
+		require.NoError(t, err)
+		recovered, err := account.PublicKeyBytesToAddress(pub)
+		require.NoError(t, err)
+		require.Equal(t, addr, recovered)
+	}
+}
+
+func TestToRecoverableSignature_WrongAccount(t *testing.T) {
+	key, _ := transitTestKey(t)
+	hash, _ := hex.DecodeString("9058f1483aa565541bdf632adbc8d88d58139e567906821a17442f5a06459c70")
+	otherAddr, _ := account.NewAddressFromHexString("4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash)
+	require.NoError(t, err)
+	der, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+	require.NoError(t, err)
+
+	_, err = toRecoverableSignature(hash, der, otherAddr)
+	require.EqualError(t, err, "unable to determine signature recovery ID: signature was not created by the account's key")
+}
+
+// transitServer is a minimal in-memory Transit engine named transit with a single key version per key.  Keys created
+// by the plugin are given newKey.
+type transitServer struct {
+	t               *testing.T
+	mu              sync.Mutex
+	keys            map[string]*ecdsa.PrivateKey
+	newKey          *ecdsa.PrivateKey
+	deletionAllowed map[string]bool
+	deleted         []string
+	signed          []string
+}
+
+func (s *transitServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		keysPrefix = "/v1/transit/keys/"
+		signPrefix = "/v1/transit/sign/"
+		resp       map[string]interface{}
+	)
+	switch {
+	case strings.HasPrefix(r.URL.Path, keysPrefix) && strings.HasSuffix(r.URL.Path, "/config"):
+		s.deletionAllowed[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, keysPrefix), "/config")] = true
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case strings.HasPrefix(r.URL.Path, keysPrefix):
+		name := strings.TrimPrefix(r.URL.Path, keysPrefix)
+		key, ok := s.keys[name]
+		switch r.Method {
+		case http.MethodPut:
+			s.keys[name] = s.newKey
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodDelete:
+			if !s.deletionAllowed[name] {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			delete(s.keys, name)
+			s.deleted = append(s.deleted, name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp = map[string]interface{}{
+			"latest_version": 1,
+			"keys":           map[string]interface{}{"1": map[string]interface{}{"public_key": testutil.TransitPublicKeyPEM(s.t, key)}},
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, signPrefix):
+		name := strings.TrimPrefix(r.URL.Path, signPrefix)
+		key, ok := s.keys[name]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body struct{ Input string }
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		hash, err := base64.StdEncoding.DecodeString(body.Input)
+		require.NoError(s.t, err)
+
+		sigR, sigS, err := ecdsa.Sign(rand.Reader, key, hash)
+		require.NoError(s.t, err)
+		der, err := asn1.Marshal(struct{ R, S interface{} }{sigR, sigS})
+		require.NoError(s.t, err)
+
+		s.signed = append(s.signed, name)
+		resp = map[string]interface{}{"signature": "vault:v1:" + base64.StdEncoding.EncodeToString(der)}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	b, _ := json.Marshal(&api.Secret{Data: resp})
+	_, _ = w.Write(b)
+}
+
+// transitAccountManager returns an accountManager for a Transit engine whose keys are the given keys, and which gives
+// the transitTestKey to new keys.  New account files are written to a temporary directory.
+func transitAccountManager(t *testing.T, keys map[string]*ecdsa.PrivateKey) (*accountManager, *transitServer, func()) {
+	newKey, _ := transitTestKey(t)
+	transit := &transitServer{t: t, keys: keys, newKey: newKey, deletionAllowed: make(map[string]bool)}
+	c, closeVault := testVaultClient(t, transit)
+
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+
+	c.kvVersion = kvVersion2
+	c.transitEngineName = "transit"
+	c.transitKeyType = defaultTransitKeyType
+	c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir + "/"}}
+	a := &accountManager{client: c, unlocked: make(map[string]*lockableKey)}
+	return a, transit, func() {
+		closeVault()
+		os.RemoveAll(dir)
+	}
+}
+
+// requireSignedBy checks that sig is a valid signature of hash by the account
+func requireSignedBy(t *testing.T, addr account.Address, hash, sig []byte) {
+	require.Len(t, sig, 65)
+	pub, err := secp256k1.RecoverPubkey(hash, sig)
+	require.NoError(t, err)
+	recovered, err := account.PublicKeyBytesToAddress(pub)
+	require.NoError(t, err)
+	require.Equal(t, addr, recovered)
+}
+
+func TestAccountManager_Transit_NewAccount(t *testing.T) {
+	a, transit, cleanup := transitAccountManager(t, make(map[string]*ecdsa.PrivateKey))
+	defer cleanup()
+	_, wantAddr := transitTestKey(t)
+
+	acct, err := a.NewAccount(config.NewAccount{TransitKeyName: "mykey"})
+	require.NoError(t, err)
+	require.Equal(t, wantAddr, acct.Address)
+	require.Equal(t, a.client.Address()+"/v1/transit/keys/mykey?version=1", acct.URL.String())
+	require.True(t, a.Contains(wantAddr))
+	require.Contains(t, transit.keys, "mykey")
+
+	acctFile, err := a.client.getAccount(wantAddr)
+	require.NoError(t, err)
+	require.True(t, acctFile.Contents.IsTransitAccount())
+	require.Equal(t, "mykey", acctFile.Contents.TransitAccount.KeyName)
+	require.Equal(t, int64(1), acctFile.Contents.TransitAccount.KeyVersion)
+
+	// keys are never overwritten
+	_, err = a.NewAccount(config.NewAccount{TransitKeyName: "mykey"})
+	require.EqualError(t, err, "unable to create transit key in Vault: transit key already exists")
+}
+
+func TestVaultClient_CreateTransitKey_Concurrent(t *testing.T) {
+	a, _, cleanup := transitAccountManager(t, make(map[string]*ecdsa.PrivateKey))
+	defer cleanup()
+
+	var (
+		wg      sync.WaitGroup
+		created int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := a.client.createTransitKey("mykey"); err == nil {
+				atomic.AddInt32(&created, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), created)
+}
+
+func TestAccountManager_Transit_NewAccount_ExistingAccount(t *testing.T) {
+	a, transit, cleanup := transitAccountManager(t, make(map[string]*ecdsa.PrivateKey))
+	defer cleanup()
+	_, addr := transitTestKey(t)
+
+	_, err := a.NewAccount(config.NewAccount{TransitKeyName: "mykey"})
+	require.NoError(t, err)
+
+	// the new key has the address of the existing account so is deleted
+	_, err = a.NewAccount(config.NewAccount{TransitKeyName: "otherkey"})
+	require.EqualError(t, err, "account already exists")
+	require.Equal(t, []string{"otherkey"}, transit.deleted)
+	require.NotContains(t, transit.keys, "otherkey")
+
+	accts, err := a.Accounts()
+	require.NoError(t, err)
+	require.Len(t, accts, 1)
+	require.Equal(t, addr, accts[0].Address)
+}
+
+func TestAccountManager_Transit_TimedUnlockAndSign(t *testing.T) {
+	key, addr := transitTestKey(t)
+	a, transit, cleanup := transitAccountManager(t, map[string]*ecdsa.PrivateKey{"mykey": key})
+	defer cleanup()
+	newAcct := config.NewAccount{TransitKeyName: "mykey"}
+	a.client.accts[&url.URL{Path: "acct"}] = newAcct.TransitAccountFile("", addr.ToHexString(), 1)
+	hash, _ := hex.DecodeString("9058f1483aa565541bdf632adbc8d88d58139e567906821a17442f5a06459c70")
+
+	_, err := a.Sign(addr, hash)
+	require.EqualError(t, err, "account locked")
+	require.Empty(t, transit.signed)
+
+	require.NoError(t, a.TimedUnlock(addr, 0))
+	status, err := a.Status()
+	require.NoError(t, err)
+	require.Equal(t, "1 unlocked account(s): [0x"+addr.ToHexString()+"]", status)
+
+	// the key never leaves Vault
+	require.Nil(t, a.unlocked[addr.ToHexString()].key)
+
+	sig, err := a.Sign(addr, hash)
+	require.NoError(t, err)
+	requireSignedBy(t, addr, hash, sig)
+	require.Equal(t, []string{"mykey"}, transit.signed)
+
+	a.Lock(addr)
+	_, err = a.Sign(addr, hash)
+	require.EqualError(t, err, "account locked")
+}
+
+func TestAccountManager_Transit_UnlockAndSign(t *testing.T) {
+	key, addr := transitTestKey(t)
+	a, transit, cleanup := transitAccountManager(t, map[string]*ecdsa.PrivateKey{"mykey": key})
+	defer cleanup()
+	newAcct := config.NewAccount{TransitKeyName: "mykey"}
+	a.client.accts[&url.URL{Path: "acct"}] = newAcct.TransitAccountFile("", addr.ToHexString(), 1)
+	hash, _ := hex.DecodeString("9058f1483aa565541bdf632adbc8d88d58139e567906821a17442f5a06459c70")
+
+	sig, err := a.UnlockAndSign(addr, hash)
+	require.NoError(t, err)
+	requireSignedBy(t, addr, hash, sig)
+	require.Equal(t, []string{"mykey"}, transit.signed)
+
+	// the account is not left unlocked
+	require.Empty(t, a.unlocked)
+}
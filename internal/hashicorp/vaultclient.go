@@ -1,33 +1,124 @@
 package hashicorp
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/url"
-	"os"
-	"path/filepath"
-	"time"
+	"sort"
+	"sync"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 )
 
-const reauthRetryInterval = 5 * time.Second
-
 type vaultClient struct {
 	*api.Client
-	kvEngineName     string
-	accountDirectory *url.URL
-	accts            accountsByURL
+	name               string                  // the name of the client's Vault connection, empty for the default connection
+	connections        map[string]*vaultClient // the clients of the named Vault connections, only set on the default client
+	authClient         *api.Client             // used to login, has the auth method's namespace if different to the client's
+	namespace          string
+	kvEngineName       string
+	kvVersion          int
+	kvVersions         map[string]int // the detected versions of KV engines named by accounts, other than kvEngineName
+	kvVersionsMu       sync.Mutex
+	noCustomMetadata   map[string]bool // KV engines found not to support custom_metadata, i.e. on Vault < 1.9
+	noCustomMetadataMu sync.Mutex
+	secretLayout       config.VaultClientSecretLayout
+	fileKeyStore       *fileKeyStore // set if the insecure file key store is configured, in which case Vault is not used
+	transitEngineName  string
+	transitKeyType     string
+	transitKeyLocks    map[string]*sync.Mutex // serialise the creation of Transit keys with the same name
+	transitKeyLocksMu  sync.Mutex
+	accountDirectory   accountDirectory // only set on the default client
+	discovery          config.VaultClientDiscovery
+	secretHealth       secretHealthCache // the last results of checking the accounts' secrets, only set on the default client
+	accts              accountsByURL
+	acctsMu            sync.RWMutex // accts is updated in the background when discovered accounts are refreshed
+	authenticator      Authenticator
+	authStatus         authStatus
+	reauthBackoff      backoff
+	failover           *failoverTransport // nil if no failover addresses are configured
+	reauth             chan struct{}      // signalled to re-authenticate after failing over to another Vault address
+	stop               chan struct{}      // closed by close to stop the background renewal of the client's token
+	stopOnce           sync.Once
 }
 
-// newVaultClient creates an authenticated Vault client using the credentials provided as environment variables
-// (either logging in using the AppRole or using a provided token directly).  Providing tls will configure the client
-// to use TLS for Vault communications.  If the AppRole token is renewable the client will be started with a renewer.
+// newVaultClient creates a Vault client authenticated using the configured Authenticator, along with a client for each
+// of the configured Connections, checks the clients' tokens have the required capabilities and loads the accounts in
+// the account directory.  If Discovery is enabled the accounts
+// in the KV engine are also discovered, and refreshed in the background if a RefreshInterval is configured.  If a
+// HealthCheckInterval is configured the health of the accounts' secrets is checked in the background.
 func newVaultClient(conf config.VaultClient) (*vaultClient, error) {
+	client, err := newConnectionClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	client.connections = make(map[string]*vaultClient, len(conf.Connections))
+	for _, connConf := range conf.Connections {
+		conn, err := newConnectionClient(conf.ForConnection(connConf))
+		if err != nil {
+			client.close()
+			return nil, fmt.Errorf("error creating client for Vault connection %v: %v", connConf.Name, err)
+		}
+		conn.name = connConf.Name
+		client.connections[connConf.Name] = conn
+	}
+
+	client.accountDirectory = newAccountDirectory(client, conf.AccountDirectory)
+
+	// check the tokens' policies now rather than on the first account operation: on the configured engines before any
+	// accounts are loaded, and then on any other engines named by the loaded accounts
+	checked := make(map[checkedEngine]bool)
+	if err := client.checkEngineCapabilities(conf, checked); err != nil {
+		client.close()
+		return nil, err
+	}
+
+	result, err := client.loadAccounts()
+	if err != nil {
+		client.close()
+		return nil, fmt.Errorf("error loading account directory: %v", err)
+	}
+	client.accts = result
+
+	if err := client.checkEngineCapabilities(conf, checked); err != nil {
+		client.close()
+		return nil, err
+	}
+
+	if conf.Discovery.Enabled {
+		if err := client.refreshDiscoveredAccounts(); err != nil {
+			client.close()
+			return nil, fmt.Errorf("error discovering accounts in Vault: %v", err)
+		}
+		if conf.Discovery.RefreshInterval > 0 {
+			go client.discoveryLoop(conf.Discovery.RefreshInterval)
+		}
+	}
+
+	if conf.HealthCheckInterval > 0 {
+		go client.healthCheckLoop(conf.HealthCheckInterval)
+	}
+
+	return client, nil
+}
+
+// newConnectionClient creates a client for a single Vault connection, authenticated using the configured
+// Authenticator.  Providing tls will configure the client to use TLS for Vault communications.  The client's token is
+// kept valid according to the Authenticator's RenewalPolicy.
+func newConnectionClient(conf config.VaultClient) (*vaultClient, error) {
+	if conf.KeyStore.IsFile() {
+		return newFileKeyStoreClient(conf)
+	}
+
+	authenticator, err := newAuthenticator(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	clientConf := api.DefaultConfig()
 	clientConf.Address = conf.Vault.String()
 
@@ -38,28 +129,139 @@ func newVaultClient(conf config.VaultClient) (*vaultClient, error) {
 		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
 	}
 
+	var failover *failoverTransport
+	if len(conf.FailoverAddresses) > 0 {
+		failover = newFailoverTransport(clientConf.HttpClient.Transport, conf.Addresses())
+		clientConf.HttpClient.Transport = failover
+	}
+
 	c, err := api.NewClient(clientConf)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
 	}
+	if conf.Namespace != "" {
+		c.SetNamespace(conf.Namespace)
+	}
+
+	authClient, err := newAuthClient(c, conf)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
+	}
+
+	transitKeyType := conf.TransitKeyType
+	if transitKeyType == "" {
+		transitKeyType = defaultTransitKeyType
+	}
 
 	vaultClient := &vaultClient{
-		Client:           c,
-		kvEngineName:     conf.KVEngineName,
-		accountDirectory: conf.AccountDirectory,
+		Client:            c,
+		authClient:        authClient,
+		namespace:         conf.Namespace,
+		kvEngineName:      conf.KVEngineName,
+		secretLayout:      conf.SecretLayout,
+		transitEngineName: conf.TransitEngineName,
+		transitKeyType:    transitKeyType,
+		discovery:         conf.Discovery,
+		authenticator:     authenticator,
+		reauthBackoff:     newBackoff(conf.Authentication.Retry),
+		failover:          failover,
+		reauth:            make(chan struct{}, 1),
+		stop:              make(chan struct{}),
+	}
+	if failover != nil {
+		failover.onSwitch = vaultClient.switched
 	}
 
 	if err := vaultClient.authenticate(conf.Authentication); err != nil {
 		return nil, err
 	}
 
-	result, err := vaultClient.loadAccounts()
+	vaultClient.setKVVersion(conf.KVVersion)
+
+	return vaultClient, nil
+}
+
+// newFileKeyStoreClient creates a client that stores keys in the insecure file key store instead of Vault.  No requests
+// are made to Vault: the configured Vault address is only used in account URLs.
+func newFileKeyStoreClient(conf config.VaultClient) (*vaultClient, error) {
+	if !conf.InsecureDev {
+		return nil, errors.New(config.InvalidInsecureDev)
+	}
+
+	passphrase, err := conf.KeyStore.Passphrase.Resolve()
 	if err != nil {
-		return nil, fmt.Errorf("error loading account directory: %v", err)
+		return nil, fmt.Errorf("unable to resolve keyStore.passphrase from %v: %v", conf.KeyStore.Passphrase, err)
+	}
+	dir := conf.KeyStore.Directory.Host + conf.KeyStore.Directory.Path
+	ks, err := newFileKeyStore(dir, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file key store: %v", err)
 	}
-	vaultClient.accts = result
 
-	return vaultClient, nil
+	clientConf := api.DefaultConfig()
+	clientConf.Address = conf.Vault.String()
+	c, err := api.NewClient(clientConf)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Hashicorp Vault client: %v", err)
+	}
+
+	log.Printf("[WARN] using the insecure file key store at %v, keys are not stored in Vault: only use for development and test networks", dir)
+
+	return &vaultClient{
+		Client:       c,
+		namespace:    conf.Namespace,
+		kvEngineName: conf.KVEngineName,
+		kvVersion:    kvVersion2,
+		secretLayout: conf.SecretLayout,
+		fileKeyStore: ks,
+		reauth:       make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}, nil
+}
+
+// switched requests re-authentication after the client has failed over to another Vault address, so that a token
+// created by the plugin is replaced if it is not valid on the new node
+func (c *vaultClient) switched(_ *url.URL) {
+	select {
+	case c.reauth <- struct{}{}:
+	default:
+		// re-authentication has already been requested
+	}
+}
+
+// vaultAddress returns the Vault address the client is currently using
+func (c *vaultClient) vaultAddress() string {
+	if c.failover != nil {
+		return c.failover.address().String()
+	}
+	return c.Address()
+}
+
+// connection returns the client of the named Vault connection, or c if name is empty
+func (c *vaultClient) connection(name string) (*vaultClient, error) {
+	if name == "" {
+		return c, nil
+	}
+	conn, ok := c.connections[name]
+	if !ok {
+		return nil, fmt.Errorf("Vault connection %v is not configured", name)
+	}
+	return conn, nil
+}
+
+// clients returns c followed by the clients of the named Vault connections in name order
+func (c *vaultClient) clients() []*vaultClient {
+	names := make([]string, 0, len(c.connections))
+	for name := range c.connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clients := []*vaultClient{c}
+	for _, name := range names {
+		clients = append(clients, c.connections[name])
+	}
+	return clients
 }
 
 func convertTLSConfig(tls config.VaultClientTLS) *api.TLSConfig {
@@ -82,28 +284,54 @@ func convertTLSConfig(tls config.VaultClientTLS) *api.TLSConfig {
 	return tlsConfig
 }
 
+// newAuthClient returns the client to login with.  If the auth method is in a different namespace to the rest of the
+// plugin's requests then a copy of c using the auth namespace is returned, otherwise c is returned.
+func newAuthClient(c *api.Client, conf config.VaultClient) (*api.Client, error) {
+	authNamespace := conf.Authentication.Namespace
+	if authNamespace == "" || authNamespace == conf.Namespace {
+		return c, nil
+	}
+	authClient, err := c.Clone()
+	if err != nil {
+		return nil, err
+	}
+	authClient.SetHeaders(c.Headers())
+	authClient.SetNamespace(authNamespace)
+	return authClient, nil
+}
+
 func (c *vaultClient) authenticate(conf config.VaultClientAuthentication) error {
-	// authentication config has already been validated so only need to check if approle or token auth is being used
-	if conf.Token.IsSet() {
-		c.SetToken(conf.Token.Get())
+	switch c.authenticator.RenewalPolicy() {
+	case RenewOnly:
+		if _, err := c.login(conf); err != nil {
+			return err
+		}
+		if c.failover != nil {
+			go c.awaitFailover(conf)
+		}
+		return c.startTokenRenewal()
+	case Poll:
+		if _, err := c.login(conf); err != nil {
+			return err
+		}
+		go c.pollLoop(conf)
 		return nil
+	default:
+		return c.renewableAuthentication(conf)
 	}
-
-	return c.renewableApproleAuthentication(conf)
 }
 
-func (c *vaultClient) renewableApproleAuthentication(conf config.VaultClientAuthentication) error {
-	renewable, err := c.authenticateWithApprole(conf)
+func (c *vaultClient) renewableAuthentication(conf config.VaultClientAuthentication) error {
+	renewable, err := c.login(conf)
 	if err != nil {
 		return err
 	}
 	return renewable.startAuthenticationRenewal(c, conf)
 }
 
-func (c *vaultClient) authenticateWithApprole(conf config.VaultClientAuthentication) (*renewable, error) {
-	body := map[string]interface{}{"role_id": conf.RoleId.Get(), "secret_id": conf.SecretId.Get()}
-
-	resp, err := c.Logical().Write(fmt.Sprintf("auth/%s/login", conf.ApprolePath), body)
+// login authenticates using the configured Authenticator and updates the client to use the returned token
+func (c *vaultClient) login(conf config.VaultClientAuthentication) (*renewable, error) {
+	resp, err := c.authenticator.Login(c.authClient, conf)
 	if err != nil {
 		return nil, err
 	}
@@ -117,57 +345,114 @@ func (c *vaultClient) authenticateWithApprole(conf config.VaultClientAuthenticat
 	return &renewable{Secret: resp}, nil
 }
 
-func (c *vaultClient) loadAccounts() (map[*url.URL]config.AccountFile, error) {
-	result := make(map[*url.URL]config.AccountFile)
-
-	walkFn := filepath.WalkFunc(func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// close stops the background renewal of the client's token.  If the token was created by the plugin (i.e. the
+// Authenticator has the RenewAndReauthenticate RenewalPolicy) it is also revoked so that it does not outlive the
+// plugin.  Tokens provided to the plugin are not revoked as they are owned by the user or Vault Agent.  The clients of
+// any named Vault connections are also closed.
+func (c *vaultClient) close() {
+	for _, conn := range c.connections {
+		conn.close()
+	}
+	c.stopOnce.Do(func() {
+		if c.stop != nil {
+			close(c.stop)
+		}
+		if c.authenticator == nil || c.authenticator.RenewalPolicy() != RenewAndReauthenticate || c.Token() == "" {
+			return
 		}
-		if info.IsDir() {
-			// do nothing with directories
-			return nil
+		if err := c.Auth().Token().RevokeSelf(""); err != nil {
+			log.Printf("[WARN] unable to revoke Vault token: %v", err)
+			return
 		}
-		log.Printf("[DEBUG] Loading %v", path)
-		b, err := ioutil.ReadFile(path)
+		c.ClearToken()
+		log.Print("[INFO] revoked Vault token")
+	})
+}
 
-		conf := new(config.AccountFileJSON)
+func (c *vaultClient) loadAccounts() (map[*url.URL]config.AccountFile, error) {
+	files, err := c.accountDirectory.load()
+	if err != nil {
+		return nil, err
+	}
 
-		if err := json.Unmarshal(b, conf); err != nil {
-			return fmt.Errorf("unable to unmarshal contents of %v, err: %v", path, err)
+	result := make(map[*url.URL]config.AccountFile, len(files))
+	for _, file := range files {
+		conf := file.Contents
+
+		conn, err := c.connection(conf.Connection)
+		if err != nil {
+			return nil, fmt.Errorf("%v uses Vault connection %v which is not configured", file.Path, conf.Connection)
+		}
+		if conf.IsTransitAccount() && conn.transitEngineName == "" {
+			return nil, fmt.Errorf("%v is a transit account but transitEngineName is not configured", file.Path)
 		}
 
-		acctURL, err := conf.AccountURL(c.Address(), c.kvEngineName)
+		acctURL, err := conn.accountURL(&conf)
 		if err != nil {
-			return fmt.Errorf("unable to parse account URL for %v, err: %v", path, err)
+			return nil, fmt.Errorf("unable to parse account URL for %v, err: %v", file.Path, err)
 		}
 
-		result[acctURL] = config.AccountFile{Path: path, Contents: *conf}
-		return nil
-	})
+		result[acctURL] = file
+	}
+	return result, nil
+}
+
+// accountURL returns the URL of the account stored using c
+func (c *vaultClient) accountURL(conf *config.AccountFileJSON) (*url.URL, error) {
+	engineName := conf.KVEngine(c.kvEngineName)
+	return conf.AccountURL(c.Address(), c.namespace, engineName, c.keyStoreKVVersion(engineName), c.transitEngineName)
+}
+
+// addAccount adds the account to the internal list of accounts
+func (c *vaultClient) addAccount(acctURL *url.URL, acctFile config.AccountFile) {
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
 
-	root := c.accountDirectory.Host + "/" + c.accountDirectory.Path
+	c.accts[acctURL] = acctFile
+}
 
-	if _, err := os.Stat(root); os.IsNotExist(err) {
-		log.Printf("[DEBUG] Creating empty directory at %v", root)
-		if err := os.Mkdir(root, os.ModeDir+0755); err != nil {
-			return nil, err
+// removeAccount deletes the account's file and removes the account from the internal list of accounts.  Discovered
+// accounts have no file so are only removed from the list.
+func (c *vaultClient) removeAccount(acctFile config.AccountFile) error {
+	if !acctFile.Discovered {
+		if err := c.accountDirectory.remove(acctFile.Path); err != nil {
+			return err
 		}
-		return result, nil
 	}
 
-	log.Printf("[DEBUG] Loading accts from %v", root)
-	if err := filepath.Walk(root, walkFn); err != nil {
-		return nil, err
+	c.acctsMu.Lock()
+	defer c.acctsMu.Unlock()
+
+	for u, file := range c.accts {
+		if file.Discovered == acctFile.Discovered && file.Path == acctFile.Path && file.Contents.Address == acctFile.Contents.Address {
+			delete(c.accts, u)
+		}
 	}
+	return nil
+}
 
-	return result, nil
+// accounts returns a copy of the internal list of accounts
+func (c *vaultClient) accounts() accountsByURL {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
+
+	accts := make(accountsByURL, len(c.accts))
+	for u, file := range c.accts {
+		accts[u] = file
+	}
+	return accts
 }
 
 func (c *vaultClient) hasAccount(acctAddr account.Address) bool {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
+
 	return c.accts.HasAccountWithAddress(acctAddr)
 }
 
 func (c *vaultClient) getAccount(acctAddr account.Address) (config.AccountFile, error) {
+	c.acctsMu.RLock()
+	defer c.acctsMu.RUnlock()
+
 	return c.accts.GetAccountWithAddress(acctAddr)
 }
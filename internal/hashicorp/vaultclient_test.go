@@ -3,14 +3,39 @@ package hashicorp
 import (
 	"crypto/rand"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
 	"github.com/stretchr/testify/require"
 )
 
+// testVaultClient returns a client for a Vault server that uses handler, with the token authToken and the KV engine
+// engine.  The returned func closes the server.
+func testVaultClient(t *testing.T, handler http.Handler) (*vaultClient, func()) {
+	vault := httptest.NewServer(handler)
+
+	conf := api.DefaultConfig()
+	conf.Address = vault.URL
+	c, err := api.NewClient(conf)
+	require.NoError(t, err)
+	c.SetToken("authToken")
+
+	return &vaultClient{
+		Client:       c,
+		kvEngineName: "engine",
+		accts:        make(accountsByURL),
+		stop:         make(chan struct{}),
+	}, vault.Close
+}
+
 func TestVaultClient_LoadAccounts_AccountDirectoryCreatedIfDoesntExist(t *testing.T) {
 	buf := make([]byte, 16)
 	_, err := rand.Read(buf)
@@ -27,7 +52,7 @@ func TestVaultClient_LoadAccounts_AccountDirectoryCreatedIfDoesntExist(t *testin
 	require.True(t, os.IsNotExist(err))
 
 	c := vaultClient{
-		accountDirectory: acctDir,
+		accountDirectory: &fileAccountDirectory{root: acctDir},
 	}
 
 	result, err := c.loadAccounts()
@@ -38,6 +63,80 @@ func TestVaultClient_LoadAccounts_AccountDirectoryCreatedIfDoesntExist(t *testin
 	require.DirExists(t, acctDirPath)
 }
 
+func TestVaultClient_LoadAccounts_PerAccountKVEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "default"), []byte(`{"Address":"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5","VaultAccount":{"SecretName":"acct1","SecretVersion":1},"Version":1}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "team"), []byte(`{"Address":"dc99ddec13457de6c0f6bb8e6cf3955c86f55526","VaultAccount":{"SecretName":"acct2","SecretVersion":2,"KVEngineName":"team-engine"},"Version":1}`), 0600))
+
+	c, _, _, cleanup := kvClient(t, 2, map[string]interface{}{"options": map[string]interface{}{"version": "1"}})
+	defer cleanup()
+	c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}}
+
+	result, err := c.loadAccounts()
+	require.NoError(t, err)
+
+	var got []string
+	for u := range result {
+		got = append(got, u.Path+"?"+u.RawQuery)
+	}
+	require.ElementsMatch(t, []string{"/v1/engine/data/acct1?version=1", "/v1/team-engine/acct2?"}, got)
+}
+
+func TestVaultClient_LoadAccounts_UnknownConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "acct")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"Address":"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5","Connection":"dr","VaultAccount":{"SecretName":"acct1","SecretVersion":1},"Version":1}`), 0600))
+
+	c := vaultClient{
+		accountDirectory: &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}},
+	}
+
+	_, err = c.loadAccounts()
+	require.EqualError(t, err, fmt.Sprintf("%v uses Vault connection dr which is not configured", path))
+}
+
+func TestVaultClient_LoadAccounts_NamedConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "default"), []byte(`{"Address":"4d6d744b6da435b5bbdde2526dc20e9a41cb72e5","VaultAccount":{"SecretName":"acct1","SecretVersion":1},"Version":1}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dr"), []byte(`{"Address":"dc99ddec13457de6c0f6bb8e6cf3955c86f55526","Connection":"dr","VaultAccount":{"SecretName":"acct2","SecretVersion":2},"Version":1}`), 0600))
+
+	c, _, _, cleanup := kvClient(t, 2, nil)
+	defer cleanup()
+	dr, _, _, drCleanup := kvClient(t, 1, nil)
+	defer drCleanup()
+	dr.name = "dr"
+	dr.kvEngineName = "dr-engine"
+
+	c.accountDirectory = &fileAccountDirectory{root: &url.URL{Scheme: "file", Path: dir}}
+	c.connections = map[string]*vaultClient{"dr": dr}
+
+	result, err := c.loadAccounts()
+	require.NoError(t, err)
+
+	var got []string
+	for u := range result {
+		got = append(got, u.String())
+	}
+	require.ElementsMatch(t, []string{
+		fmt.Sprintf("%v/v1/engine/data/acct1?version=1", c.Address()),
+		fmt.Sprintf("%v/v1/dr-engine/acct2", dr.Address()),
+	}, got)
+
+	gotConn, err := c.connection("dr")
+	require.NoError(t, err)
+	require.Equal(t, dr, gotConn)
+	require.Equal(t, []*vaultClient{c, dr}, c.clients())
+}
+
 func TestConvertTLSConfig(t *testing.T) {
 	caCert, _ := url.Parse("file:///leading/slash/ca.cert")
 	clientCert, _ := url.Parse("file://path/to/client.cert")
@@ -71,3 +170,78 @@ func TestConvertTLSConfigNoUrls(t *testing.T) {
 	require.Equal(t, "", got.ClientCert)
 	require.Equal(t, "", got.ClientKey)
 }
+
+func TestNewAuthClient(t *testing.T) {
+	c, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	c.SetNamespace("ns1/network1")
+
+	var namespaces = map[string]struct {
+		namespace, authNamespace string
+		wantSameClient           bool
+		wantNamespace            string
+	}{
+		"no_auth_namespace":   {namespace: "ns1/network1", wantSameClient: true, wantNamespace: "ns1/network1"},
+		"same_auth_namespace": {namespace: "ns1/network1", authNamespace: "ns1/network1", wantSameClient: true, wantNamespace: "ns1/network1"},
+		"auth_namespace":      {namespace: "ns1/network1", authNamespace: "ns1", wantNamespace: "ns1"},
+	}
+
+	for name, tt := range namespaces {
+		t.Run(name, func(t *testing.T) {
+			conf := config.VaultClient{
+				Namespace:      tt.namespace,
+				Authentication: config.VaultClientAuthentication{Namespace: tt.authNamespace},
+			}
+
+			got, err := newAuthClient(c, conf)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSameClient, got == c)
+			require.Equal(t, tt.wantNamespace, got.Headers().Get(consts.NamespaceHeaderName))
+
+			// the client's own namespace is unchanged
+			require.Equal(t, "ns1/network1", c.Headers().Get(consts.NamespaceHeaderName))
+		})
+	}
+}
+
+func revokeSelfClient(t *testing.T, authenticator Authenticator) (*vaultClient, *int, func()) {
+	var revoked int
+	c, cleanup := testVaultClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/revoke-self" || r.Header.Get(consts.AuthHeaderName) != "authToken" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		revoked++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	c.authenticator = authenticator
+	return c, &revoked, cleanup
+}
+
+func TestVaultClient_Close_RevokesPluginCreatedToken(t *testing.T) {
+	c, revoked, cleanup := revokeSelfClient(t, new(approleAuthenticator))
+	defer cleanup()
+
+	c.close()
+	c.close()
+
+	require.Equal(t, 1, *revoked)
+	require.Empty(t, c.Token())
+	select {
+	case <-c.stop:
+	default:
+		t.Fatal("stop channel not closed")
+	}
+}
+
+func TestVaultClient_Close_DoesNotRevokeProvidedToken(t *testing.T) {
+	for _, a := range []Authenticator{new(tokenAuthenticator), new(tokenFileAuthenticator)} {
+		c, revoked, cleanup := revokeSelfClient(t, a)
+
+		c.close()
+
+		require.Equal(t, 0, *revoked)
+		require.Equal(t, "authToken", c.Token())
+		cleanup()
+	}
+}
@@ -0,0 +1,137 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+)
+
+// resolveSecret returns the credential identified by the SecretSource s, named name in errors.  A vault-wrapped://
+// source is unwrapped using client, and the credential is taken from the unwrapped token if dataKey is empty (e.g. for
+// a wrapped token create request) or from the dataKey field of the unwrapped data otherwise.
+func resolveSecret(client *api.Client, name string, s config.SecretSource, dataKey string) (string, error) {
+	if !s.IsVaultWrapped() {
+		v, err := s.Resolve()
+		if err != nil {
+			return "", fmt.Errorf("unable to resolve %v from %v: %v", name, s, err)
+		}
+		return v, nil
+	}
+
+	src, err := s.WrappingToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v from %v: %v", name, s, err)
+	}
+	wrappingToken, err := src.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v from %v: %v", name, s, err)
+	}
+	resp, err := unwrap(client, name, wrappingToken, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if dataKey == "" {
+		v, err := resp.TokenID()
+		if err != nil || v == "" {
+			return "", fmt.Errorf("no token returned from Vault when unwrapping %v", name)
+		}
+		return v, nil
+	}
+	v, ok := resp.Data[dataKey].(string)
+	if !ok || v == "" {
+		return "", fmt.Errorf("no %v returned from Vault when unwrapping %v", dataKey, name)
+	}
+	return v, nil
+}
+
+// unwrapSecretId reads the response-wrapping token configured by a vault-wrapped:// conf.SecretId and unwraps it to
+// retrieve the AppRole secret ID.  Before unwrapping, the token is checked to have
+// been created by the configured AppRole.  A token that has already been unwrapped is rejected as this may indicate it
+// has been intercepted.
+func (a *approleAuthenticator) unwrapSecretId(client *api.Client, conf config.VaultClientAuthentication) (string, error) {
+	const name = "secretId"
+	src, err := conf.SecretId.WrappingToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v: %v", name, err)
+	}
+
+	wrappingToken, err := src.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v from %v: %v", name, src, err)
+	}
+	if wrappingToken == "" {
+		return "", fmt.Errorf("%v is empty", name)
+	}
+	if wrappingToken == a.usedWrappingToken {
+		return "", fmt.Errorf("%v has already been used, a new response-wrapping token must be provided", name)
+	}
+
+	resp, err := unwrap(client, name, wrappingToken, func(lookupData map[string]interface{}) error {
+		return checkWrappingCreationPath(lookupData, conf.ApprolePath, name)
+	})
+	if err != nil {
+		// the token is only consumed by a successful unwrap so can be retried
+		return "", err
+	}
+	a.usedWrappingToken = wrappingToken
+
+	secretId, ok := resp.Data["secret_id"].(string)
+	if !ok || secretId == "" {
+		return "", fmt.Errorf("no secret_id returned from Vault when unwrapping %v", name)
+	}
+	return secretId, nil
+}
+
+// unwrap unwraps wrappingToken, named name in errors.  The token is looked up first so that a token that has already
+// been unwrapped is reported as possible tampering, and check (if provided) can verify the lookup data before the
+// token is used.
+func unwrap(client *api.Client, name, wrappingToken string, check func(lookupData map[string]interface{}) error) (*api.Secret, error) {
+	// use a separate client authenticated with the wrapping token so that the client's own (possibly expired) token is
+	// not sent with the unwrap requests.  Headers are not cloned so are copied to keep the client's namespace, as
+	// wrapping tokens can only be unwrapped in the namespace they were created in.
+	wrappingClient, err := client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	wrappingClient.SetHeaders(client.Headers())
+	wrappingClient.SetToken(wrappingToken)
+
+	lookup, err := wrappingClient.Logical().Write("sys/wrapping/lookup", map[string]interface{}{"token": wrappingToken})
+	if err != nil {
+		return nil, fmt.Errorf("%v is invalid or has already been unwrapped, this may indicate tampering: %v", name, err)
+	}
+	if lookup == nil {
+		return nil, errors.New("empty response from Vault")
+	}
+	if check != nil {
+		if err := check(lookup.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := wrappingClient.Logical().Unwrap(wrappingToken)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap %v: %v", name, err)
+	}
+	if resp == nil {
+		return nil, errors.New("empty response from Vault")
+	}
+	return resp, nil
+}
+
+// checkWrappingCreationPath checks that the lookup data of the wrapping token named name shows the token was created by
+// a secret ID request for the AppRole mounted at approlePath, i.e. auth/<approlePath>/role/<role>/secret-id
+func checkWrappingCreationPath(lookupData map[string]interface{}, approlePath, name string) error {
+	creationPath, _ := lookupData["creation_path"].(string)
+
+	prefix := fmt.Sprintf("auth/%v/role/", approlePath)
+	suffix := "/secret-id"
+	if !strings.HasPrefix(creationPath, prefix) || !strings.HasSuffix(creationPath, suffix) || len(creationPath) <= len(prefix)+len(suffix) {
+		return fmt.Errorf("%v was not created by a secret-id request for approle %v (creation path = %v), this may indicate tampering", name, approlePath, creationPath)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const wrappingToken = "s.wrapping"
+
+// wrappingHandler is a Vault server whose response-wrapping token wrappingToken wraps a secret-id and role-id, created
+// at creationPath.  Requests with any other token are forbidden.
+func wrappingHandler(creationPath string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/wrapping/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(consts.AuthHeaderName) != wrappingToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"creation_path": creationPath}})
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("/v1/sys/wrapping/unwrap", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(consts.AuthHeaderName) != wrappingToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		b, _ := json.Marshal(&api.Secret{Data: map[string]interface{}{"secret_id": "secretidval", "role_id": "roleidval"}})
+		_, _ = w.Write(b)
+	})
+	return mux
+}
+
+func wrappingClient(t *testing.T, handler http.Handler) (*api.Client, func()) {
+	c, cleanup := testVaultClient(t, handler)
+	return c.Client, cleanup
+}
+
+// wrappingTokenFile writes the wrapping token to a temp file and returns its path
+func wrappingTokenFile(t *testing.T) string {
+	f, err := ioutil.TempFile("", "wrapped-secret-id")
+	require.NoError(t, err)
+	_, err = f.WriteString(wrappingToken + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func wrappedSecretIdConf(wrappingTokenPath string) config.VaultClientAuthentication {
+	return config.VaultClientAuthentication{
+		SecretId:    config.SecretSource("vault-wrapped://file://" + wrappingTokenPath),
+		ApprolePath: "myapprole",
+	}
+}
+
+func TestUnwrapSecretId(t *testing.T) {
+	vault := wrappingHandler("auth/myapprole/role/myrole/secret-id")
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+	conf := wrappedSecretIdConf(path)
+	require.True(t, conf.IsWrappedSecretIdSet())
+
+	c, cleanup := wrappingClient(t, vault)
+	defer cleanup()
+	a := new(approleAuthenticator)
+
+	got, err := a.unwrapSecretId(c, conf)
+	require.NoError(t, err)
+	require.Equal(t, "secretidval", got)
+
+	// the client's own token is unchanged
+	require.Equal(t, "authToken", c.Token())
+}
+
+func TestUnwrapSecretId_KeepsNamespace(t *testing.T) {
+	vault := wrappingHandler("auth/myapprole/role/myrole/secret-id")
+
+	var namespaces []string
+	ns := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get(consts.NamespaceHeaderName))
+		vault.ServeHTTP(w, r)
+	})
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+	conf := wrappedSecretIdConf(path)
+
+	c, cleanup := wrappingClient(t, ns)
+	defer cleanup()
+	c.SetNamespace("ns1")
+	a := new(approleAuthenticator)
+
+	_, err := a.unwrapSecretId(c, conf)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ns1", "ns1"}, namespaces)
+}
+
+func TestUnwrapSecretId_RejectsReusedToken(t *testing.T) {
+	vault := wrappingHandler("auth/myapprole/role/myrole/secret-id")
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+	conf := wrappedSecretIdConf(path)
+
+	c, cleanup := wrappingClient(t, vault)
+	defer cleanup()
+	a := new(approleAuthenticator)
+
+	_, err := a.unwrapSecretId(c, conf)
+	require.NoError(t, err)
+
+	_, err = a.unwrapSecretId(c, conf)
+	require.EqualError(t, err, "secretId has already been used, a new response-wrapping token must be provided")
+}
+
+func TestUnwrapSecretId_RetriesAfterFailedUnwrap(t *testing.T) {
+	vault := wrappingHandler("auth/myapprole/role/myrole/secret-id")
+
+	// the first unwrap fails without consuming the token
+	var unwraps int
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/wrapping/unwrap" {
+			unwraps++
+			if unwraps == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["temporary failure"]}`))
+				return
+			}
+		}
+		vault.ServeHTTP(w, r)
+	})
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+	conf := wrappedSecretIdConf(path)
+
+	c, cleanup := wrappingClient(t, flaky)
+	defer cleanup()
+	a := new(approleAuthenticator)
+
+	_, err := a.unwrapSecretId(c, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to unwrap secretId")
+
+	got, err := a.unwrapSecretId(c, conf)
+	require.NoError(t, err)
+	require.Equal(t, "secretidval", got)
+
+	// once unwrapped the token cannot be used again
+	_, err = a.unwrapSecretId(c, conf)
+	require.EqualError(t, err, "secretId has already been used, a new response-wrapping token must be provided")
+}
+
+func TestUnwrapSecretId_RejectsUnexpectedCreationPath(t *testing.T) {
+	vault := wrappingHandler("secret/data/something")
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+	conf := wrappedSecretIdConf(path)
+
+	c, cleanup := wrappingClient(t, vault)
+	defer cleanup()
+	a := new(approleAuthenticator)
+
+	_, err := a.unwrapSecretId(c, conf)
+	require.EqualError(t, err, "secretId was not created by a secret-id request for approle myapprole (creation path = secret/data/something), this may indicate tampering")
+}
+
+func TestResolveSecret(t *testing.T) {
+	vault := wrappingHandler("auth/myapprole/role/myrole/role-id")
+
+	path := wrappingTokenFile(t)
+	defer os.Remove(path)
+
+	c, cleanup := wrappingClient(t, vault)
+	defer cleanup()
+
+	got, err := resolveSecret(c, "roleId", config.SecretSource("vault-wrapped://file://"+path), "role_id")
+	require.NoError(t, err)
+	require.Equal(t, "roleidval", got)
+
+	got, err = resolveSecret(c, "roleId", config.SecretSource("file://"+path), "role_id")
+	require.NoError(t, err)
+	require.Equal(t, wrappingToken, got)
+
+	_, err = resolveSecret(c, "roleId", config.SecretSource("env://NOT_SET"), "role_id")
+	require.EqualError(t, err, "unable to resolve roleId from env://NOT_SET: environment variable NOT_SET not set")
+
+	_, err = resolveSecret(c, "roleId", config.SecretSource("vault-wrapped://roleidval"), "role_id")
+	require.EqualError(t, err, "unable to resolve roleId from vault-wrapped://roleidval: vault-wrapped:// must be followed by an env:// or file:// source, e.g. vault-wrapped://file:///path/to/token")
+}
+
+func TestCheckWrappingCreationPath(t *testing.T) {
+	var paths = map[string]struct {
+		creationPath string
+		wantErr      bool
+	}{
+		"valid":           {creationPath: "auth/myapprole/role/myrole/secret-id"},
+		"other_approle":   {creationPath: "auth/otherapprole/role/myrole/secret-id", wantErr: true},
+		"no_role":         {creationPath: "auth/myapprole/role/secret-id", wantErr: true},
+		"other_operation": {creationPath: "auth/myapprole/role/myrole/role-id", wantErr: true},
+		"empty":           {wantErr: true},
+	}
+
+	for name, tt := range paths {
+		t.Run(name, func(t *testing.T) {
+			err := checkWrappingCreationPath(map[string]interface{}{"creation_path": tt.creationPath}, "myapprole", "secretId")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The AccountAdminService is registered alongside the Quorum account plugin services so that tooling can manage the
+// plugin's accounts.  Its types are generated from accountadmin.proto into accountadmin.pb.go.
+
+// DeleteAccount deletes the account's secret version from Vault, soft-deleting it unless destroy is set, and removes
+// the account file
+func (p *HashicorpPlugin) DeleteAccount(_ context.Context, req *DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.isInitialized() {
+		return nil, status.Error(codes.Unavailable, "not configured")
+	}
+	addr, err := account.NewAddress(req.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := p.acctManager.DeleteAccount(addr, req.Destroy); err != nil {
+		if errors.Is(err, hashicorp.ErrUnknownAccount) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, acctManagerError(err)
+	}
+	return &DeleteAccountResponse{}, nil
+}
+
+// CheckAccounts checks the health of the secrets of the accounts now, rather than waiting for the next periodic check
+func (p *HashicorpPlugin) CheckAccounts(_ context.Context, _ *CheckAccountsRequest) (*CheckAccountsResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.isInitialized() {
+		return nil, status.Error(codes.Unavailable, "not configured")
+	}
+	results := p.acctManager.CheckAccounts()
+	accts := make([]*AccountHealth, 0, len(results))
+	for _, r := range results {
+		acct := &AccountHealth{
+			Address: r.Account.Address.ToBytes(),
+			Url:     r.Account.URL.String(),
+			Health:  string(r.Health),
+			Version: r.Version,
+		}
+		if r.Err != nil {
+			acct.Error = r.Err.Error()
+		}
+		accts = append(accts, acct)
+	}
+	return &CheckAccountsResponse{Accounts: accts}, nil
+}
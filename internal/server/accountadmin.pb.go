@@ -0,0 +1,405 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: accountadmin.proto
+
+package server
+
+import (
+	context "context"
+	fmt "fmt"
+	math "math"
+
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type DeleteAccountRequest struct {
+	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// permanently destroy the secret version instead of soft-deleting it
+	Destroy              bool     `protobuf:"varint,2,opt,name=destroy,proto3" json:"destroy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteAccountRequest) Reset()         { *m = DeleteAccountRequest{} }
+func (m *DeleteAccountRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteAccountRequest) ProtoMessage()    {}
+func (*DeleteAccountRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_14c23cc9eaab764c, []int{0}
+}
+
+func (m *DeleteAccountRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteAccountRequest.Unmarshal(m, b)
+}
+func (m *DeleteAccountRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteAccountRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteAccountRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteAccountRequest.Merge(m, src)
+}
+func (m *DeleteAccountRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteAccountRequest.Size(m)
+}
+func (m *DeleteAccountRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteAccountRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteAccountRequest proto.InternalMessageInfo
+
+func (m *DeleteAccountRequest) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *DeleteAccountRequest) GetDestroy() bool {
+	if m != nil {
+		return m.Destroy
+	}
+	return false
+}
+
+type DeleteAccountResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteAccountResponse) Reset()         { *m = DeleteAccountResponse{} }
+func (m *DeleteAccountResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteAccountResponse) ProtoMessage()    {}
+func (*DeleteAccountResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_14c23cc9eaab764c, []int{1}
+}
+
+func (m *DeleteAccountResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteAccountResponse.Unmarshal(m, b)
+}
+func (m *DeleteAccountResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteAccountResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteAccountResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteAccountResponse.Merge(m, src)
+}
+func (m *DeleteAccountResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteAccountResponse.Size(m)
+}
+func (m *DeleteAccountResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteAccountResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteAccountResponse proto.InternalMessageInfo
+
+type CheckAccountsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckAccountsRequest) Reset()         { *m = CheckAccountsRequest{} }
+func (m *CheckAccountsRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckAccountsRequest) ProtoMessage()    {}
+func (*CheckAccountsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_14c23cc9eaab764c, []int{2}
+}
+
+func (m *CheckAccountsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckAccountsRequest.Unmarshal(m, b)
+}
+func (m *CheckAccountsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckAccountsRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckAccountsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckAccountsRequest.Merge(m, src)
+}
+func (m *CheckAccountsRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckAccountsRequest.Size(m)
+}
+func (m *CheckAccountsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckAccountsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckAccountsRequest proto.InternalMessageInfo
+
+type CheckAccountsResponse struct {
+	Accounts             []*AccountHealth `protobuf:"bytes,1,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *CheckAccountsResponse) Reset()         { *m = CheckAccountsResponse{} }
+func (m *CheckAccountsResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckAccountsResponse) ProtoMessage()    {}
+func (*CheckAccountsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_14c23cc9eaab764c, []int{3}
+}
+
+func (m *CheckAccountsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckAccountsResponse.Unmarshal(m, b)
+}
+func (m *CheckAccountsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckAccountsResponse.Marshal(b, m, deterministic)
+}
+func (m *CheckAccountsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckAccountsResponse.Merge(m, src)
+}
+func (m *CheckAccountsResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckAccountsResponse.Size(m)
+}
+func (m *CheckAccountsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckAccountsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckAccountsResponse proto.InternalMessageInfo
+
+func (m *CheckAccountsResponse) GetAccounts() []*AccountHealth {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+type AccountHealth struct {
+	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Url     string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// healthy, deleted, destroyed or missing, empty if the secret could not be checked
+	Health string `protobuf:"bytes,3,opt,name=health,proto3" json:"health,omitempty"`
+	// the secret version the account uses, resolved to the current version for accounts using the latest version
+	Version int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	// the reason the secret could not be checked
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccountHealth) Reset()         { *m = AccountHealth{} }
+func (m *AccountHealth) String() string { return proto.CompactTextString(m) }
+func (*AccountHealth) ProtoMessage()    {}
+func (*AccountHealth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_14c23cc9eaab764c, []int{4}
+}
+
+func (m *AccountHealth) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountHealth.Unmarshal(m, b)
+}
+func (m *AccountHealth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountHealth.Marshal(b, m, deterministic)
+}
+func (m *AccountHealth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountHealth.Merge(m, src)
+}
+func (m *AccountHealth) XXX_Size() int {
+	return xxx_messageInfo_AccountHealth.Size(m)
+}
+func (m *AccountHealth) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountHealth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccountHealth proto.InternalMessageInfo
+
+func (m *AccountHealth) GetAddress() []byte {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *AccountHealth) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *AccountHealth) GetHealth() string {
+	if m != nil {
+		return m.Health
+	}
+	return ""
+}
+
+func (m *AccountHealth) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *AccountHealth) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*DeleteAccountRequest)(nil), "hashicorp.DeleteAccountRequest")
+	proto.RegisterType((*DeleteAccountResponse)(nil), "hashicorp.DeleteAccountResponse")
+	proto.RegisterType((*CheckAccountsRequest)(nil), "hashicorp.CheckAccountsRequest")
+	proto.RegisterType((*CheckAccountsResponse)(nil), "hashicorp.CheckAccountsResponse")
+	proto.RegisterType((*AccountHealth)(nil), "hashicorp.AccountHealth")
+}
+
+func init() { proto.RegisterFile("accountadmin.proto", fileDescriptor_14c23cc9eaab764c) }
+
+var fileDescriptor_14c23cc9eaab764c = []byte{
+	// 345 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0xcd, 0x4e, 0xb3, 0x40,
+	0x14, 0x0d, 0x1f, 0x5f, 0x6b, 0x3b, 0xda, 0xc4, 0x8c, 0x6d, 0x25, 0xdd, 0x48, 0x58, 0xb1, 0x01,
+	0x92, 0xea, 0x0b, 0x54, 0x5d, 0x18, 0x13, 0x8d, 0x19, 0x77, 0xee, 0xa6, 0x70, 0xd3, 0x41, 0x61,
+	0x86, 0xce, 0x0f, 0x89, 0x6b, 0x9f, 0xcb, 0x77, 0x33, 0xfc, 0x94, 0x48, 0xd3, 0xba, 0xe3, 0xdc,
+	0x7b, 0xee, 0xb9, 0xf7, 0x1c, 0x06, 0x61, 0x1a, 0xc7, 0xc2, 0x70, 0x4d, 0x93, 0x3c, 0xe5, 0x61,
+	0x21, 0x85, 0x16, 0x78, 0xcc, 0xa8, 0x62, 0x69, 0x2c, 0x64, 0xe1, 0x3d, 0xa2, 0xe9, 0x3d, 0x64,
+	0xa0, 0x61, 0xd5, 0xd0, 0x08, 0x6c, 0x0d, 0x28, 0x8d, 0x1d, 0x74, 0x42, 0x93, 0x44, 0x82, 0x52,
+	0x8e, 0xe5, 0x5a, 0xfe, 0x19, 0xd9, 0xc1, 0xaa, 0x93, 0x80, 0xd2, 0x52, 0x7c, 0x3a, 0xff, 0x5c,
+	0xcb, 0x1f, 0x91, 0x1d, 0xf4, 0x2e, 0xd1, 0x6c, 0x4f, 0x4b, 0x15, 0x82, 0x2b, 0xf0, 0xe6, 0x68,
+	0x7a, 0xc7, 0x20, 0xfe, 0x68, 0xeb, 0xaa, 0x5d, 0xe2, 0x3d, 0xa1, 0xd9, 0x5e, 0xbd, 0x19, 0xc0,
+	0x37, 0x68, 0xd4, 0x9e, 0x5d, 0xad, 0xb7, 0xfd, 0xd3, 0xa5, 0x13, 0x76, 0x37, 0x87, 0x2d, 0xfd,
+	0x01, 0x68, 0xa6, 0x19, 0xe9, 0x98, 0xde, 0x97, 0x85, 0x26, 0xbd, 0xde, 0x1f, 0x2e, 0xce, 0x91,
+	0x6d, 0x64, 0x56, 0x3b, 0x18, 0x93, 0xea, 0x13, 0xcf, 0xd1, 0x90, 0xd5, 0x53, 0x8e, 0x5d, 0x17,
+	0x87, 0xac, 0xd3, 0x28, 0x41, 0xaa, 0x54, 0x70, 0xe7, 0xbf, 0x6b, 0xf9, 0x36, 0xd9, 0x41, 0x3c,
+	0x45, 0x03, 0x90, 0x52, 0x48, 0x67, 0x50, 0x0f, 0x34, 0x60, 0xf9, 0x6d, 0xa1, 0x8b, 0xf6, 0x8a,
+	0x55, 0x95, 0xf9, 0x2b, 0xc8, 0x32, 0x8d, 0x01, 0x13, 0x34, 0xe9, 0xa5, 0x83, 0xaf, 0x7e, 0x59,
+	0x3a, 0xf4, 0x0f, 0x16, 0xee, 0x71, 0x42, 0x9b, 0x13, 0x41, 0x93, 0x5e, 0x80, 0x3d, 0xcd, 0x43,
+	0x91, 0x2f, 0xdc, 0xe3, 0x84, 0x46, 0xf3, 0xf6, 0xe5, 0xed, 0x79, 0x93, 0x6a, 0x66, 0xd6, 0x61,
+	0x2c, 0xf2, 0xe8, 0xbd, 0xc8, 0x85, 0xdc, 0x50, 0x1e, 0x33, 0xaa, 0x20, 0xda, 0x1a, 0x21, 0x4d,
+	0x1e, 0xb4, 0x89, 0x07, 0x45, 0x66, 0x36, 0x29, 0x0f, 0x3a, 0xc5, 0xa0, 0xa4, 0x26, 0xd3, 0x51,
+	0xca, 0x35, 0x48, 0x4e, 0xb3, 0x48, 0x81, 0x2c, 0x41, 0xae, 0x87, 0xf5, 0xab, 0xbb, 0xfe, 0x19,
+	0x00, 0xd1, 0xd4, 0xf5, 0xf7, 0x8b, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// AccountAdminServiceClient is the client API for AccountAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AccountAdminServiceClient interface {
+	// DeleteAccount locks the account, deletes its secret version from Vault and removes its account file
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResponse, error)
+	// CheckAccounts checks the health of the KV secret versions of the accounts
+	CheckAccounts(ctx context.Context, in *CheckAccountsRequest, opts ...grpc.CallOption) (*CheckAccountsResponse, error)
+}
+
+type accountAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAccountAdminServiceClient(cc grpc.ClientConnInterface) AccountAdminServiceClient {
+	return &accountAdminServiceClient{cc}
+}
+
+func (c *accountAdminServiceClient) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResponse, error) {
+	out := new(DeleteAccountResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.AccountAdminService/DeleteAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountAdminServiceClient) CheckAccounts(ctx context.Context, in *CheckAccountsRequest, opts ...grpc.CallOption) (*CheckAccountsResponse, error) {
+	out := new(CheckAccountsResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.AccountAdminService/CheckAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AccountAdminServiceServer is the server API for AccountAdminService service.
+type AccountAdminServiceServer interface {
+	// DeleteAccount locks the account, deletes its secret version from Vault and removes its account file
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error)
+	// CheckAccounts checks the health of the KV secret versions of the accounts
+	CheckAccounts(context.Context, *CheckAccountsRequest) (*CheckAccountsResponse, error)
+}
+
+// UnimplementedAccountAdminServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedAccountAdminServiceServer struct {
+}
+
+func (*UnimplementedAccountAdminServiceServer) DeleteAccount(ctx context.Context, req *DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
+func (*UnimplementedAccountAdminServiceServer) CheckAccounts(ctx context.Context, req *CheckAccountsRequest) (*CheckAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckAccounts not implemented")
+}
+
+func RegisterAccountAdminServiceServer(s *grpc.Server, srv AccountAdminServiceServer) {
+	s.RegisterService(&_AccountAdminService_serviceDesc, srv)
+}
+
+func _AccountAdminService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountAdminServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.AccountAdminService/DeleteAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountAdminServiceServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountAdminService_CheckAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountAdminServiceServer).CheckAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.AccountAdminService/CheckAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountAdminServiceServer).CheckAccounts(ctx, req.(*CheckAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AccountAdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hashicorp.AccountAdminService",
+	HandlerType: (*AccountAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _AccountAdminService_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "CheckAccounts",
+			Handler:    _AccountAdminService_CheckAccounts_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "accountadmin.proto",
+}
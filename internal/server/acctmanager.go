@@ -3,20 +3,25 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
 	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// isInitialized must be called with p.mu held
 func (p *HashicorpPlugin) isInitialized() bool {
 	return p.acctManager != nil
 }
 
 func (p *HashicorpPlugin) Status(_ context.Context, _ *proto.StatusRequest) (*proto.StatusResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -38,6 +43,8 @@ func (p *HashicorpPlugin) Close(_ context.Context, _ *proto.CloseRequest) (*prot
 }
 
 func (p *HashicorpPlugin) Accounts(_ context.Context, _ *proto.AccountsRequest) (*proto.AccountsResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -54,6 +61,8 @@ func (p *HashicorpPlugin) Accounts(_ context.Context, _ *proto.AccountsRequest)
 }
 
 func (p *HashicorpPlugin) Contains(_ context.Context, req *proto.ContainsRequest) (*proto.ContainsResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -67,6 +76,8 @@ func (p *HashicorpPlugin) Contains(_ context.Context, req *proto.ContainsRequest
 }
 
 func (p *HashicorpPlugin) Sign(_ context.Context, req *proto.SignRequest) (*proto.SignResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -76,12 +87,14 @@ func (p *HashicorpPlugin) Sign(_ context.Context, req *proto.SignRequest) (*prot
 	}
 	result, err := p.acctManager.Sign(addr, req.ToSign)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, acctManagerError(err)
 	}
 	return &proto.SignResponse{Sig: result}, nil
 }
 
 func (p *HashicorpPlugin) UnlockAndSign(_ context.Context, req *proto.UnlockAndSignRequest) (*proto.SignResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -91,12 +104,14 @@ func (p *HashicorpPlugin) UnlockAndSign(_ context.Context, req *proto.UnlockAndS
 	}
 	result, err := p.acctManager.UnlockAndSign(addr, req.ToSign)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, acctManagerError(err)
 	}
 	return &proto.SignResponse{Sig: result}, nil
 }
 
 func (p *HashicorpPlugin) TimedUnlock(_ context.Context, req *proto.TimedUnlockRequest) (*proto.TimedUnlockResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -105,12 +120,14 @@ func (p *HashicorpPlugin) TimedUnlock(_ context.Context, req *proto.TimedUnlockR
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 	if err := p.acctManager.TimedUnlock(addr, time.Duration(req.Duration)); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, acctManagerError(err)
 	}
 	return &proto.TimedUnlockResponse{}, nil
 }
 
 func (p *HashicorpPlugin) Lock(_ context.Context, req *proto.LockRequest) (*proto.LockResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -123,6 +140,8 @@ func (p *HashicorpPlugin) Lock(_ context.Context, req *proto.LockRequest) (*prot
 }
 
 func (p *HashicorpPlugin) NewAccount(_ context.Context, req *proto.NewAccountRequest) (*proto.NewAccountResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -135,7 +154,7 @@ func (p *HashicorpPlugin) NewAccount(_ context.Context, req *proto.NewAccountReq
 	}
 	acct, err := p.acctManager.NewAccount(*conf)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, acctManagerError(err)
 	}
 	return &proto.NewAccountResponse{
 		Account: acct.ToProtoAccount(),
@@ -143,6 +162,8 @@ func (p *HashicorpPlugin) NewAccount(_ context.Context, req *proto.NewAccountReq
 }
 
 func (p *HashicorpPlugin) ImportRawKey(_ context.Context, req *proto.ImportRawKeyRequest) (*proto.ImportRawKeyResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if !p.isInitialized() {
 		return nil, status.Error(codes.Unavailable, "not configured")
 	}
@@ -159,9 +180,25 @@ func (p *HashicorpPlugin) ImportRawKey(_ context.Context, req *proto.ImportRawKe
 	}
 	acct, err := p.acctManager.ImportPrivateKey(privateKey, *conf)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, acctManagerError(err)
 	}
 	return &proto.ImportRawKeyResponse{
 		Account: acct.ToProtoAccount(),
 	}, nil
 }
+
+// acctManagerError converts an error returned by the account manager to a gRPC status error.  Errors caused by the
+// plugin being unable to authenticate with Vault are Unavailable so that callers can retry later.  Errors caused by an
+// account's secret version not existing are NotFound, and by it being deleted or destroyed are FailedPrecondition as
+// the account cannot be used until the secret is fixed in Vault.
+func acctManagerError(err error) error {
+	switch {
+	case errors.Is(err, hashicorp.ErrAuthUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, hashicorp.ErrSecretMissing):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, hashicorp.ErrSecretDeleted), errors.Is(err, hashicorp.ErrSecretDestroyed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -16,6 +16,8 @@ func (p *HashicorpPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error
 	proto_common.RegisterPluginInitializerServer(s, p)
 	log.Println("[INFO] Register Hashicorp Vault AccountManager")
 	proto.RegisterAccountServiceServer(s, p)
+	log.Println("[INFO] Register Hashicorp Vault AccountAdminService")
+	RegisterAccountAdminServiceServer(s, p)
 	return nil
 }
 
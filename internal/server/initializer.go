@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -30,11 +31,16 @@ func (p *HashicorpPlugin) Init(_ context.Context, req *proto_common.PluginInitia
 	}
 
 	am, err := hashicorp.NewAccountManager(*conf)
+	if errors.Is(err, hashicorp.ErrMissingCapabilities) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
 
-	p.acctManager = am
+	// any previous initialization is only replaced once the new AccountManager is ready so that a failed
+	// re-initialization leaves the plugin usable.  It is then closed so its token and unlocked keys do not outlive it.
+	p.setAccountManager(am)
 
 	return &proto_common.PluginInitialization_Response{}, nil
 }
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"sync"
+
 	"github.com/hashicorp/go-plugin"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/hashicorp"
 )
@@ -8,4 +10,24 @@ import (
 type HashicorpPlugin struct {
 	plugin.Plugin
 	acctManager hashicorp.AccountManager
+	mu          sync.RWMutex // guards acctManager, held for reading by requests for their duration so that it is not closed while in use
+}
+
+// setAccountManager replaces the plugin's AccountManager with am, closing the previous AccountManager, if there is one,
+// once no requests are using it
+func (p *HashicorpPlugin) setAccountManager(am hashicorp.AccountManager) {
+	p.mu.Lock()
+	prev := p.acctManager
+	p.acctManager = am
+	p.mu.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// Shutdown closes the plugin's AccountManager, if it has been initialized, locking all accounts and revoking the Vault
+// token if it was created by the plugin
+func (p *HashicorpPlugin) Shutdown() {
+	p.setAccountManager(nil)
 }
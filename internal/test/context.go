@@ -17,6 +17,7 @@ type ITContext struct {
 	Vault                  *httptest.Server
 	AccountConfigDirectory string
 	AccountManager         *hashicorpPluginGRPCClient
+	DeleteRequests         chan string // the delete and destroy requests received by the mock Vault server
 }
 
 // starts a plugin server and client, returning the client
@@ -11,14 +11,47 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/config"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/server"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
 	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto"
 	"github.com/jpmorganchase/quorum-account-plugin-sdk-go/proto_common"
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 	"github.com/stretchr/testify/require"
 )
 
-func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, args ...map[string]string) {
+// setupOptions configures the mock Vault, account directory and plugin config created by setupPluginAndVaultAndFiles
+// and startPluginAndVaultAndFiles.  The zero value sets up a KV v2 account with approle authentication.
+type setupOptions struct {
+	transit                   bool     // add a Transit account and serve its Transit key
+	capabilities              []string // if set, the token's capabilities returned by sys/capabilities-self
+	kvVersion1                bool     // serve the KV engine as KV v1
+	certAuth                  bool     // use cert authentication instead of approle
+	warnOnMissingCapabilities bool
+	unlock                    []string
+}
+
+func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, opts ...setupOptions) {
+	conf := startPluginAndVaultAndFiles(t, ctx, opts...)
+
+	rawConf, err := json.Marshal(&conf)
+	require.NoError(t, err)
+
+	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
+		RawConfiguration: rawConf,
+	})
+	require.NoError(t, err)
+}
+
+// startPluginAndVaultAndFiles starts the plugin and the mock Vault, creates the account directory and returns the
+// plugin config to initialize the plugin with
+func startPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, opts ...setupOptions) config.VaultClient {
+	var o setupOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
 	err := ctx.StartPlugin(t)
 	require.NoError(t, err)
 
@@ -34,10 +67,26 @@ func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, args ...map[strin
 	ctx.CreateAccountConfigDirectory(t)
 	err = ctx.WriteToAccountConfigDirectory(t, []byte(acctConf))
 	require.NoError(t, err)
+	ctx.DeleteRequests = make(chan string, 1)
+
+	if o.transit {
+		transitAcctConf := `{
+	"address": "6038dc01869425004ca0b8370f6c81cf464213b3",
+	"TransitAccount": {
+		"KeyName": "myTransitKey",
+		"KeyVersion": 1
+	},
+	"id": "5a5a6e8e-8e4b-4a8a-9fd0-4a3c5d2f9d1b",
+	"version": 1
+}`
+		err = ctx.WriteToAccountConfigDirectory(t, []byte(transitAcctConf))
+		require.NoError(t, err)
+	}
 
 	var vaultBuilder VaultBuilder
 	vaultBuilder.
 		WithLoginHandler("myapprole").
+		WithLoginHandler("cert").
 		WithHandler(t, HandlerData{
 			SecretEnginePath: "engine",
 			SecretPath:       "myAcct",
@@ -49,9 +98,40 @@ func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, args ...map[strin
 			SecretEnginePath: "engine",
 			SecretPath:       "newAcct",
 		}).
+		WithDeleteHandler(t, HandlerData{
+			SecretEnginePath: "engine",
+			SecretPath:       "myAcct",
+		}, ctx.DeleteRequests).
+		WithMetadataHandler(t, HandlerData{
+			SecretEnginePath: "engine",
+			SecretPath:       "myAcct",
+			SecretVersion:    2,
+		}).
 		WithCaCert(CA_CERT).
 		WithServerCert(SERVER_CERT).
 		WithServerKey(SERVER_KEY)
+	if o.transit {
+		vaultBuilder.WithTransitHandler(t, "transit", map[string]string{
+			"myTransitKey": "1fe8f1ad4053326db20529257ac9401f2e6c769ef1d736b8c2f5aba5f787c72b",
+		})
+	}
+	if o.capabilities != nil {
+		vaultBuilder.WithCapabilitiesHandler(t, o.capabilities...)
+	}
+	if o.kvVersion1 {
+		vaultBuilder.
+			WithMountsHandler(1).
+			WithKVv1Handler(t, HandlerData{
+				SecretEnginePath: "engine",
+				SecretPath:       "myAcct",
+				AcctAddrResponse: "dc99ddec13457de6c0f6bb8e6cf3955c86f55526",
+				PrivKeyResponse:  "7af58d8bd863ce3fce9508a57dff50a2655663a1411b6634cea6246398380b28",
+			}).
+			WithKVv1AccountCreationHandler(t, HandlerData{
+				SecretEnginePath: "engine",
+				SecretPath:       "newAcct",
+			})
+	}
 	ctx.StartTLSVaultServer(t, vaultBuilder)
 
 	wd, err := os.Getwd()
@@ -61,27 +141,25 @@ func setupPluginAndVaultAndFiles(t *testing.T, ctx *ITContext, args ...map[strin
 		WithVaultUrl(ctx.Vault.URL).
 		WithKVEngineName("engine").
 		WithAccountDirectory(fmt.Sprintf("file://%v/%v", wd, ctx.AccountConfigDirectory)).
-		WithRoleIdUrl("env://" + testutil.MY_ROLE_ID).
-		WithSecretIdUrl("env://" + testutil.MY_SECRET_ID).
-		WithApprolePath("myapprole").
 		WithCaCertUrl(fmt.Sprintf("file://%v/%v", wd, CA_CERT)).
 		WithClientCertUrl(fmt.Sprintf("file://%v/%v", wd, CLIENT_CERT)).
 		WithClientKeyUrl(fmt.Sprintf("file://%v/%v", wd, CLIENT_KEY))
-
-	if args != nil {
-		if unlock, ok := args[0]["unlock"]; ok {
-			vaultClientBuilder.WithUnlock(strings.Split(unlock, ","))
-		}
+	if o.transit {
+		vaultClientBuilder.WithTransitEngineName("transit")
 	}
-	conf := vaultClientBuilder.Build(t)
-
-	rawConf, err := json.Marshal(&conf)
-	require.NoError(t, err)
-
-	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
-		RawConfiguration: rawConf,
-	})
-	require.NoError(t, err)
+	if o.unlock != nil {
+		vaultClientBuilder.WithUnlock(o.unlock)
+	}
+	vaultClientBuilder.WithWarnOnMissingCapabilities(o.warnOnMissingCapabilities)
+	if o.certAuth {
+		vaultClientBuilder.WithCertAuthPath("cert")
+	} else {
+		vaultClientBuilder.
+			WithRoleIdUrl("env://" + testutil.MY_ROLE_ID).
+			WithSecretIdUrl("env://" + testutil.MY_SECRET_ID).
+			WithApprolePath("myapprole")
+	}
+	return vaultClientBuilder.Build(t)
 }
 
 func TestPlugin_Init_InvalidPluginConfig(t *testing.T) {
@@ -105,6 +183,58 @@ func TestPlugin_Init_InvalidPluginConfig(t *testing.T) {
 	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = vault must be a valid HTTP/HTTPS url")
 }
 
+func TestPlugin_Init_CertAuthentication(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{certAuth: true})
+
+	// the mock Vault checks the token obtained from the cert login is used
+	addr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address:  addr,
+		Duration: 0,
+	})
+	require.NoError(t, err)
+}
+
+func TestPlugin_Init_MissingCapabilities(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	conf := startPluginAndVaultAndFiles(t, ctx, setupOptions{capabilities: []string{"read"}})
+	rawConf, err := json.Marshal(&conf)
+	require.NoError(t, err)
+
+	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
+		RawConfiguration: rawConf,
+	})
+	require.EqualError(t, err, "rpc error: code = PermissionDenied desc = Vault token is missing required capabilities: engine/data/*: [create update], engine/metadata/*: [create update]")
+}
+
+func TestPlugin_Init_WarnOnMissingCapabilities(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{capabilities: []string{"read"}, warnOnMissingCapabilities: true})
+
+	// the plugin starts and accounts can still be read
+	addr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address:  addr,
+		Duration: 0,
+	})
+	require.NoError(t, err)
+}
+
 func TestPlugin_Status_AccountLockedByDefault(t *testing.T) {
 	ctx := new(ITContext)
 	defer ctx.Cleanup()
@@ -543,7 +673,7 @@ func TestPlugin_UnlockAtStartup(t *testing.T) {
 	testutil.SetSecretID()
 	defer testutil.UnsetAll()
 
-	setupPluginAndVaultAndFiles(t, ctx, map[string]string{"unlock": "0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526,UnknownAcctShouldNotCauseError"})
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{unlock: []string{"0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526", "UnknownAcctShouldNotCauseError"}})
 
 	resp, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
 	require.NoError(t, err)
@@ -932,3 +1062,407 @@ func TestPlugin_ImportRawKey_ErrorIfAccountExists(t *testing.T) {
 	files, _ = ioutil.ReadDir(ctx.AccountConfigDirectory)
 	require.Len(t, files, 1)
 }
+
+func TestPlugin_DeleteAccount(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address: acctAddr,
+	})
+	require.NoError(t, err)
+
+	_, err = ctx.AccountManager.DeleteAccount(context.Background(), &server.DeleteAccountRequest{
+		Address: acctAddr,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "delete [2]", <-ctx.DeleteRequests)
+
+	status, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", status.Status)
+
+	contains, err := ctx.AccountManager.Contains(context.Background(), &proto.ContainsRequest{Address: acctAddr})
+	require.NoError(t, err)
+	require.False(t, contains.IsContained)
+
+	files, err := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestPlugin_DeleteAccount_UnknownAccount(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	acctAddr, _ := hex.DecodeString("4d6d744b6da435b5bbdde2526dc20e9a41cb72e5")
+	_, err := ctx.AccountManager.DeleteAccount(context.Background(), &server.DeleteAccountRequest{
+		Address: acctAddr,
+		Destroy: true,
+	})
+	require.EqualError(t, err, "rpc error: code = NotFound desc = unknown account")
+}
+
+func TestPlugin_DeleteAccount_InvalidAddress(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	for name, addr := range map[string][]byte{"empty": nil, "short": {0xdc, 0x99}} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ctx.AccountManager.DeleteAccount(context.Background(), &server.DeleteAccountRequest{
+				Address: addr,
+			})
+			require.EqualError(t, err, "rpc error: code = InvalidArgument desc = account address must have length 20 bytes")
+		})
+	}
+
+	// the account is not affected
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	contains, err := ctx.AccountManager.Contains(context.Background(), &proto.ContainsRequest{Address: acctAddr})
+	require.NoError(t, err)
+	require.True(t, contains.IsContained)
+	require.Empty(t, ctx.DeleteRequests)
+}
+
+func TestPlugin_CheckAccounts(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	resp, err := ctx.AccountManager.CheckAccounts(context.Background(), &server.CheckAccountsRequest{})
+	require.NoError(t, err)
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	want := &server.AccountHealth{
+		Address: acctAddr,
+		Url:     fmt.Sprintf("%v/v1/engine/data/myAcct?version=2", ctx.Vault.URL),
+		Health:  "healthy",
+		Version: 2,
+	}
+	require.Len(t, resp.Accounts, 1)
+	require.Equal(t, want.String(), resp.Accounts[0].String())
+
+	status, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", status.Status)
+}
+
+func TestPlugin_Init_FailedReinitializationKeepsAccountManager(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx)
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address: acctAddr,
+	})
+	require.NoError(t, err)
+
+	// the config is valid but the account manager cannot be created as the account directory does not exist
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	vaultClientBuilder := &VaultClientBuilder{}
+	conf := vaultClientBuilder.
+		WithVaultUrl(ctx.Vault.URL).
+		WithKVEngineName("engine").
+		WithAccountDirectory("file:///does/not/exist").
+		WithCaCertUrl(fmt.Sprintf("file://%v/%v", wd, CA_CERT)).
+		WithClientCertUrl(fmt.Sprintf("file://%v/%v", wd, CLIENT_CERT)).
+		WithClientKeyUrl(fmt.Sprintf("file://%v/%v", wd, CLIENT_KEY)).
+		WithRoleIdUrl("env://" + testutil.MY_ROLE_ID).
+		WithSecretIdUrl("env://" + testutil.MY_SECRET_ID).
+		WithApprolePath("myapprole").
+		Build(t)
+	rawConf, err := json.Marshal(&conf)
+	require.NoError(t, err)
+
+	_, err = ctx.AccountManager.Init(context.Background(), &proto_common.PluginInitialization_Request{
+		RawConfiguration: rawConf,
+	})
+	require.EqualError(t, err, "rpc error: code = InvalidArgument desc = error loading account directory: mkdir //does/not/exist/: no such file or directory")
+
+	// the previous initialization is still in use
+	status, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "1 unlocked account(s): [0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526]", status.Status)
+
+	_, err = ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{
+		Address: acctAddr,
+		ToSign:  make([]byte, 32),
+	})
+	require.NoError(t, err)
+}
+
+func TestPlugin_KVv1_Accounts(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{kvVersion1: true})
+
+	resp, err := ctx.AccountManager.Accounts(context.Background(), &proto.AccountsRequest{})
+	require.NoError(t, err)
+
+	// KV v1 secrets are unversioned so the account URL has no data path or version
+	addr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	want := proto.Account{
+		Address: addr,
+		Url:     fmt.Sprintf("%v/v1/%v/%v", ctx.Vault.URL, "engine", "myAcct"),
+	}
+
+	require.Len(t, resp.Accounts, 1)
+	require.Equal(t, want, *resp.Accounts[0])
+}
+
+func TestPlugin_KVv1_UnlockAndSign(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{kvVersion1: true})
+
+	acctAddr, _ := hex.DecodeString("dc99ddec13457de6c0f6bb8e6cf3955c86f55526")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address:  acctAddr,
+		Duration: 0,
+	})
+	require.NoError(t, err)
+
+	status, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "1 unlocked account(s): [0xdc99ddec13457de6c0f6bb8e6cf3955c86f55526]", status.Status)
+
+	toSign := make([]byte, 32)
+	resp, err := ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{
+		Address: acctAddr,
+		ToSign:  toSign,
+	})
+	require.NoError(t, err)
+	requireSignedBy(t, acctAddr, toSign, resp.Sig)
+}
+
+func TestPlugin_KVv1_NewAccount(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{kvVersion1: true})
+
+	// KV v1 does not support check-and-set so overwrite protection is ignored
+	newAcctConf := `{
+	"secretName": "newAcct",
+	"overwriteProtection": {
+		"currentVersion": 0
+	}
+}`
+
+	resp, err := ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(newAcctConf)})
+	require.NoError(t, err)
+	require.Equal(t, ctx.Vault.URL+"/v1/engine/newAcct", resp.Account.Url)
+	require.Len(t, resp.Account.Address, 20)
+
+	files, _ := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		if !strings.Contains(f.Name(), "UTC") {
+			continue
+		}
+		raw, err := ioutil.ReadFile(ctx.AccountConfigDirectory + "/" + f.Name())
+		require.NoError(t, err)
+		gotContents := new(config.AccountFileJSON)
+		require.NoError(t, json.Unmarshal(raw, gotContents))
+
+		require.Equal(t, hex.EncodeToString(resp.Account.Address), gotContents.Address)
+		require.Equal(t, "newAcct", gotContents.VaultAccount.SecretName)
+		require.Equal(t, int64(0), gotContents.VaultAccount.SecretVersion)
+	}
+
+	contains, err := ctx.AccountManager.Contains(context.Background(), &proto.ContainsRequest{Address: resp.Account.Address})
+	require.NoError(t, err)
+	require.True(t, contains.IsContained)
+}
+
+// requireSignedBy checks that sig is a valid 65-byte signature of toSign by the account with address acctAddr
+func requireSignedBy(t *testing.T, acctAddr, toSign, sig []byte) {
+	require.Len(t, sig, 65)
+	pub, err := secp256k1.RecoverPubkey(toSign, sig)
+	require.NoError(t, err)
+	recovered, err := account.PublicKeyBytesToAddress(pub)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(acctAddr), recovered.ToHexString())
+}
+
+func TestPlugin_Transit_Sign(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{transit: true})
+
+	acctAddr, _ := hex.DecodeString("6038dc01869425004ca0b8370f6c81cf464213b3")
+	toSign := []byte{188, 76, 145, 93, 105, 137, 107, 25, 143, 2, 146, 167, 35, 115, 162, 189, 205, 13, 82, 188, 203, 252, 236, 17, 217, 200, 76, 15, 255, 113, 176, 188}
+
+	_, err := ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{
+		Address: acctAddr,
+		ToSign:  toSign,
+	})
+	require.EqualError(t, err, "rpc error: code = Internal desc = account locked")
+
+	_, err = ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address:  acctAddr,
+		Duration: 0,
+	})
+	require.NoError(t, err)
+
+	resp, err := ctx.AccountManager.Sign(context.Background(), &proto.SignRequest{
+		Address: acctAddr,
+		ToSign:  toSign,
+	})
+	require.NoError(t, err)
+	requireSignedBy(t, acctAddr, toSign, resp.Sig)
+}
+
+func TestPlugin_Transit_UnlockAndSign(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{transit: true})
+
+	acctAddr, _ := hex.DecodeString("6038dc01869425004ca0b8370f6c81cf464213b3")
+	toSign := []byte{188, 76, 145, 93, 105, 137, 107, 25, 143, 2, 146, 167, 35, 115, 162, 189, 205, 13, 82, 188, 203, 252, 236, 17, 217, 200, 76, 15, 255, 113, 176, 188}
+
+	resp, err := ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{
+		Address: acctAddr,
+		ToSign:  toSign,
+	})
+	require.NoError(t, err)
+	requireSignedBy(t, acctAddr, toSign, resp.Sig)
+
+	statusResp, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", statusResp.Status)
+}
+
+func TestPlugin_Transit_TimedUnlock(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{transit: true})
+
+	addr, _ := hex.DecodeString("6038dc01869425004ca0b8370f6c81cf464213b3")
+	_, err := ctx.AccountManager.TimedUnlock(context.Background(), &proto.TimedUnlockRequest{
+		Address:  addr,
+		Duration: (1 * time.Second).Nanoseconds(),
+	})
+	require.NoError(t, err)
+
+	resp, err := ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "1 unlocked account(s): [0x6038dc01869425004ca0b8370f6c81cf464213b3]", resp.Status)
+
+	time.Sleep(1 * time.Second)
+
+	resp, err = ctx.AccountManager.Status(context.Background(), &proto.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "0 unlocked account(s)", resp.Status)
+}
+
+func TestPlugin_Transit_NewAccount(t *testing.T) {
+	ctx := new(ITContext)
+	defer ctx.Cleanup()
+
+	testutil.SetRoleID()
+	testutil.SetSecretID()
+	defer testutil.UnsetAll()
+
+	setupPluginAndVaultAndFiles(t, ctx, setupOptions{transit: true})
+
+	newAcctConf := `{
+	"transitKeyName": "newTransitKey"
+}`
+
+	resp, err := ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(newAcctConf)})
+	require.NoError(t, err)
+	require.Equal(t, ctx.Vault.URL+"/v1/transit/keys/newTransitKey?version=1", resp.Account.Url)
+	require.Len(t, resp.Account.Address, 20)
+
+	files, _ := ioutil.ReadDir(ctx.AccountConfigDirectory)
+	require.Len(t, files, 3)
+
+	var newFile os.FileInfo
+	for _, f := range files {
+		if strings.Contains(f.Name(), "UTC") { // this is the new account
+			newFile = f
+		}
+	}
+	raw, err := ioutil.ReadFile(ctx.AccountConfigDirectory + "/" + newFile.Name())
+	require.NoError(t, err)
+	gotContents := new(config.AccountFileJSON)
+	require.NoError(t, json.Unmarshal(raw, gotContents))
+	require.Equal(t, hex.EncodeToString(resp.Account.Address), gotContents.Address)
+	require.True(t, gotContents.IsTransitAccount())
+	require.Equal(t, "newTransitKey", gotContents.TransitAccount.KeyName)
+	require.Equal(t, int64(1), gotContents.TransitAccount.KeyVersion)
+
+	// the new account can be used to sign
+	toSign := make([]byte, 32)
+	signResp, err := ctx.AccountManager.UnlockAndSign(context.Background(), &proto.UnlockAndSignRequest{
+		Address: resp.Account.Address,
+		ToSign:  toSign,
+	})
+	require.NoError(t, err)
+	requireSignedBy(t, resp.Account.Address, toSign, signResp.Sig)
+
+	// keys are never overwritten
+	_, err = ctx.AccountManager.NewAccount(context.Background(), &proto.NewAccountRequest{NewAccountConfig: []byte(newAcctConf)})
+	require.EqualError(t, err, "rpc error: code = Internal desc = unable to create transit key in Vault: transit key already exists")
+}
@@ -19,11 +19,13 @@ type testableHashicorpPlugin struct {
 type hashicorpPluginGRPCClient struct {
 	proto_common.PluginInitializerClient
 	proto.AccountServiceClient
+	server.AccountAdminServiceClient
 }
 
-func (testableHashicorpPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, cc *grpc.ClientConn) (interface{}, error) {
+func (*testableHashicorpPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, cc *grpc.ClientConn) (interface{}, error) {
 	return hashicorpPluginGRPCClient{
-		PluginInitializerClient: proto_common.NewPluginInitializerClient(cc),
-		AccountServiceClient:    proto.NewAccountServiceClient(cc),
+		PluginInitializerClient:   proto_common.NewPluginInitializerClient(cc),
+		AccountServiceClient:      proto.NewAccountServiceClient(cc),
+		AccountAdminServiceClient: server.NewAccountAdminServiceClient(cc),
 	}, nil
 }
@@ -1,17 +1,28 @@
 package test
 
 import (
+	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/account"
+	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/testutil"
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
 	"github.com/stretchr/testify/require"
 )
 
@@ -136,6 +147,259 @@ func (b *VaultBuilder) WithAccountCreationHandler(t *testing.T, d HandlerData) *
 	return b
 }
 
+// WithKVv1Handler handles reading the secret from a KV v1 engine.  KV v1 secrets are unversioned and the response
+// contains the secret's data directly.  Use with WithMountsHandler(1).
+func (b *VaultBuilder) WithKVv1Handler(t *testing.T, d HandlerData) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	path := fmt.Sprintf("/v1/%v/%v", d.SecretEnginePath, d.SecretPath)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// check plugin has correctly authenticated the request
+		header := map[string][]string(r.Header)
+		requestTokens := header[consts.AuthHeaderName]
+		require.Equal(t, AUTH_TOKEN, requestTokens[0])
+
+		require.Empty(t, r.URL.Query().Get("version"))
+
+		vaultResponse := &api.Secret{
+			Data: map[string]interface{}{
+				d.AcctAddrResponse: d.PrivKeyResponse,
+			},
+		}
+		b, _ := json.Marshal(vaultResponse)
+		_, _ = w.Write(b)
+	}
+
+	b.handlers[path] = handler
+	return b
+}
+
+// WithKVv1AccountCreationHandler handles account creation in a KV v1 engine.  KV v1 does not support check-and-set so
+// the request body is the secret's data.  Use with WithMountsHandler(1).
+func (b *VaultBuilder) WithKVv1AccountCreationHandler(t *testing.T, d HandlerData) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	path := fmt.Sprintf("/v1/%v/%v", d.SecretEnginePath, d.SecretPath)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// check plugin has correctly authenticated the request
+		header := map[string][]string(r.Header)
+		requestTokens := header[consts.AuthHeaderName]
+		require.Equal(t, AUTH_TOKEN, requestTokens[0])
+
+		if r.Method != http.MethodPut {
+			http.Error(w, "retrieval of created account not implemented by mock server", http.StatusNotImplemented)
+			return
+		}
+
+		data := make(map[string]interface{})
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		require.Len(t, data, 1)
+		for k, v := range data {
+			require.Len(t, k, 2*20) // 20-byte address equates to 40 hexadecimal digits
+			require.NotEmpty(t, v)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	b.handlers[path] = handler
+	return b
+}
+
+// WithDeleteHandler handles soft-deleting and destroying versions of the secret.  Each request is sent to
+// gotRequests as the operation and requested versions, e.g. "delete [2]".
+func (b *VaultBuilder) WithDeleteHandler(t *testing.T, d HandlerData, gotRequests chan<- string) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+
+	for _, operation := range []string{"delete", "destroy"} {
+		operation := operation
+		path := fmt.Sprintf("/v1/%v/%v/%v", d.SecretEnginePath, operation, d.SecretPath)
+
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			// check plugin has correctly authenticated the request
+			header := map[string][]string(r.Header)
+			requestTokens := header[consts.AuthHeaderName]
+			require.Equal(t, AUTH_TOKEN, requestTokens[0])
+
+			body := make(map[string]interface{})
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			gotRequests <- fmt.Sprintf("%v %v", operation, body["versions"])
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+		b.handlers[path] = handler
+	}
+	return b
+}
+
+// WithMetadataHandler handles reading the KV v2 metadata of the secret.  The secret's versions are all healthy, with
+// SecretVersion as the current version.
+func (b *VaultBuilder) WithMetadataHandler(t *testing.T, d HandlerData) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	path := fmt.Sprintf("/v1/%v/metadata/%v", d.SecretEnginePath, d.SecretPath)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// check plugin has correctly authenticated the request
+		header := map[string][]string(r.Header)
+		requestTokens := header[consts.AuthHeaderName]
+		require.Equal(t, AUTH_TOKEN, requestTokens[0])
+
+		versions := make(map[string]interface{})
+		for v := 1; v <= d.SecretVersion; v++ {
+			versions[strconv.Itoa(v)] = map[string]interface{}{"deletion_time": "", "destroyed": false}
+		}
+		vaultResponse := &api.Secret{
+			Data: map[string]interface{}{
+				"current_version": d.SecretVersion,
+				"versions":        versions,
+			},
+		}
+		b, _ := json.Marshal(vaultResponse)
+		_, _ = w.Write(b)
+	}
+
+	b.handlers[path] = handler
+	return b
+}
+
+// WithCapabilitiesHandler configures the capabilities the plugin's token is reported to have for any path.  If not
+// used, the token has the capabilities required by the plugin.
+func (b *VaultBuilder) WithCapabilitiesHandler(t *testing.T, capabilities ...string) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		path, _ := body["path"].(string)
+
+		vaultResponse := &api.Secret{
+			Data: map[string]interface{}{
+				path:           capabilities,
+				"capabilities": capabilities,
+			},
+		}
+		b, _ := json.Marshal(vaultResponse)
+		_, _ = w.Write(b)
+	}
+	b.handlers["/v1/sys/capabilities-self"] = handler
+	return b
+}
+
+// WithMountsHandler configures the version of the KV engine reported by sys/internal/ui/mounts.  If not used, the KV
+// engine is reported as KV v2.
+func (b *VaultBuilder) WithMountsHandler(kvVersion int) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		vaultResponse := &api.Secret{
+			Data: map[string]interface{}{
+				"type": "kv",
+				"options": map[string]interface{}{
+					"version": strconv.Itoa(kvVersion),
+				},
+			},
+		}
+		b, _ := json.Marshal(vaultResponse)
+		_, _ = w.Write(b)
+	}
+	b.handlers["/v1/sys/internal/ui/mounts/"] = handler
+	return b
+}
+
+// WithTransitHandler handles a Transit engine containing keys, given as hex-encoded secp256k1 private keys by key name.
+// Keys created by the plugin are randomly generated.  Each key only has version 1.
+func (b *VaultBuilder) WithTransitHandler(t *testing.T, transitEnginePath string, keys map[string]string) *VaultBuilder {
+	if b.handlers == nil {
+		b.handlers = make(map[string]http.HandlerFunc)
+	}
+	var (
+		keysPrefix  = fmt.Sprintf("/v1/%v/keys/", transitEnginePath)
+		signPrefix  = fmt.Sprintf("/v1/%v/sign/", transitEnginePath)
+		transitKeys = make(map[string]*ecdsa.PrivateKey)
+		mu          sync.Mutex
+	)
+	for name, keyHex := range keys {
+		key, err := account.NewKeyFromHexString(keyHex)
+		require.NoError(t, err)
+		transitKeys[name] = key
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// check plugin has correctly authenticated the request
+		header := map[string][]string(r.Header)
+		requestTokens := header[consts.AuthHeaderName]
+		require.Equal(t, AUTH_TOKEN, requestTokens[0])
+
+		vaultResponse := new(api.Secret)
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, keysPrefix) && r.Method == http.MethodPut: // key creation
+			key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+			require.NoError(t, err)
+			transitKeys[strings.TrimPrefix(r.URL.Path, keysPrefix)] = key
+			w.WriteHeader(http.StatusNoContent)
+			return
+
+		case strings.HasPrefix(r.URL.Path, keysPrefix): // key retrieval
+			key, ok := transitKeys[strings.TrimPrefix(r.URL.Path, keysPrefix)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			vaultResponse.Data = map[string]interface{}{
+				"latest_version": 1,
+				"keys": map[string]interface{}{
+					"1": map[string]interface{}{"public_key": testutil.TransitPublicKeyPEM(t, key)},
+				},
+			}
+
+		case strings.HasPrefix(r.URL.Path, signPrefix): // signing
+			key, ok := transitKeys[strings.TrimPrefix(r.URL.Path, signPrefix)]
+			if !ok {
+				http.Error(w, "signing key not found", http.StatusBadRequest)
+				return
+			}
+			body := make(map[string]interface{})
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, true, body["prehashed"])
+			hash, err := base64.StdEncoding.DecodeString(body["input"].(string))
+			require.NoError(t, err)
+
+			sigR, sigS, err := ecdsa.Sign(rand.Reader, key, hash)
+			require.NoError(t, err)
+			der, err := asn1.Marshal(struct{ R, S *big.Int }{sigR, sigS})
+			require.NoError(t, err)
+			vaultResponse.Data = map[string]interface{}{
+				"signature": "vault:v1:" + base64.StdEncoding.EncodeToString(der),
+			}
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		b, _ := json.Marshal(vaultResponse)
+		_, _ = w.Write(b)
+	}
+
+	b.handlers[fmt.Sprintf("/v1/%v/", transitEnginePath)] = handler
+	return b
+}
+
 func (b *VaultBuilder) WithCaCert(s string) *VaultBuilder {
 	b.caCert = s
 	return b
@@ -153,6 +417,12 @@ func (b *VaultBuilder) WithServerKey(s string) *VaultBuilder {
 
 func (b *VaultBuilder) Build(t *testing.T) *httptest.Server {
 	require.True(t, len(b.handlers) > 0)
+	if _, ok := b.handlers["/v1/sys/capabilities-self"]; !ok {
+		b.WithCapabilitiesHandler(t, "create", "read", "update")
+	}
+	if _, ok := b.handlers["/v1/sys/internal/ui/mounts/"]; !ok {
+		b.WithMountsHandler(2)
+	}
 
 	mux := http.NewServeMux()
 	for path, handler := range b.handlers {
@@ -9,17 +9,20 @@ import (
 )
 
 type VaultClientBuilder struct {
-	vaultUrl      string
-	kvEngineName  string
-	acctDir       string
-	unlock        []string
-	tokenUrl      string
-	roleIdUrl     string
-	secretIdUrl   string
-	approlePath   string
-	caCertUrl     string
-	clientCertUrl string
-	clientKeyUrl  string
+	vaultUrl          string
+	kvEngineName      string
+	transitEngineName string
+	acctDir           string
+	unlock            []string
+	tokenUrl          string
+	roleIdUrl         string
+	secretIdUrl       string
+	approlePath       string
+	certAuthPath      string
+	caCertUrl         string
+	clientCertUrl     string
+	clientKeyUrl      string
+	warnOnMissingCaps bool
 }
 
 func (b *VaultClientBuilder) WithVaultUrl(s string) *VaultClientBuilder {
@@ -32,6 +35,11 @@ func (b *VaultClientBuilder) WithKVEngineName(s string) *VaultClientBuilder {
 	return b
 }
 
+func (b *VaultClientBuilder) WithTransitEngineName(s string) *VaultClientBuilder {
+	b.transitEngineName = s
+	return b
+}
+
 func (b *VaultClientBuilder) WithAccountDirectory(s string) *VaultClientBuilder {
 	b.acctDir = s
 	return b
@@ -62,6 +70,11 @@ func (b *VaultClientBuilder) WithApprolePath(s string) *VaultClientBuilder {
 	return b
 }
 
+func (b *VaultClientBuilder) WithCertAuthPath(s string) *VaultClientBuilder {
+	b.certAuthPath = s
+	return b
+}
+
 func (b *VaultClientBuilder) WithCaCertUrl(s string) *VaultClientBuilder {
 	b.caCertUrl = s
 	return b
@@ -77,6 +90,11 @@ func (b *VaultClientBuilder) WithClientKeyUrl(s string) *VaultClientBuilder {
 	return b
 }
 
+func (b *VaultClientBuilder) WithWarnOnMissingCapabilities(warn bool) *VaultClientBuilder {
+	b.warnOnMissingCaps = warn
+	return b
+}
+
 func (b *VaultClientBuilder) Build(t *testing.T) config.VaultClient {
 	var err error
 
@@ -92,27 +110,6 @@ func (b *VaultClientBuilder) Build(t *testing.T) config.VaultClient {
 		assert.NoError(t, err)
 	}
 
-	var tokenEnv config.EnvironmentVariable
-	if b.tokenUrl != "" {
-		token, err := url.Parse(b.tokenUrl)
-		assert.NoError(t, err)
-		tokenEnv = config.EnvironmentVariable(*token)
-	}
-
-	var roleIdEnv config.EnvironmentVariable
-	if b.roleIdUrl != "" {
-		roleId, err := url.Parse(b.roleIdUrl)
-		assert.NoError(t, err)
-		roleIdEnv = config.EnvironmentVariable(*roleId)
-	}
-
-	var secretIdEnv config.EnvironmentVariable
-	if b.secretIdUrl != "" {
-		secretId, err := url.Parse(b.secretIdUrl)
-		assert.NoError(t, err)
-		secretIdEnv = config.EnvironmentVariable(*secretId)
-	}
-
 	var caCert *url.URL
 	if b.caCertUrl != "" {
 		caCert, err = url.Parse(b.caCertUrl)
@@ -132,15 +129,20 @@ func (b *VaultClientBuilder) Build(t *testing.T) config.VaultClient {
 	}
 
 	return config.VaultClient{
-		Vault:            vault,
-		KVEngineName:     b.kvEngineName,
-		AccountDirectory: acctDir,
-		Unlock:           b.unlock,
+		Vault:                     vault,
+		KVEngineName:              b.kvEngineName,
+		TransitEngineName:         b.transitEngineName,
+		AccountDirectory:          acctDir,
+		Unlock:                    b.unlock,
+		WarnOnMissingCapabilities: b.warnOnMissingCaps,
 		Authentication: config.VaultClientAuthentication{
-			Token:       &tokenEnv,
-			RoleId:      &roleIdEnv,
-			SecretId:    &secretIdEnv,
+			Token:       config.SecretSource(b.tokenUrl),
+			RoleId:      config.SecretSource(b.roleIdUrl),
+			SecretId:    config.SecretSource(b.secretIdUrl),
 			ApprolePath: b.approlePath,
+			Cert: config.VaultClientCertAuthentication{
+				Path: b.certAuthPath,
+			},
 		},
 		TLS: config.VaultClientTLS{
 			CaCert:     caCert,
@@ -0,0 +1,33 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/jpmorganchase/quorum/crypto/secp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+// TransitPublicKeyPEM returns the PEM-encoded PKIX public key of key, as returned by Vault for secp256k1 Transit keys
+func TransitPublicKeyPEM(t *testing.T, key *ecdsa.PrivateKey) string {
+	type algorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.ObjectIdentifier
+	}
+	pub := secp256k1.S256().Marshal(key.X, key.Y)
+
+	spki, err := asn1.Marshal(struct {
+		Algorithm algorithm
+		PublicKey asn1.BitString
+	}{
+		Algorithm: algorithm{
+			Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}, // id-ecPublicKey
+			Parameters: asn1.ObjectIdentifier{1, 3, 132, 0, 10},       // secp256k1
+		},
+		PublicKey: asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}))
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/jpmorganchase/quorum-account-plugin-hashicorp-vault/internal/server"
@@ -21,11 +23,28 @@ var (
 func main() {
 	log.SetFlags(0)          // remove timestamp when logging to host process
 	log.SetOutput(os.Stderr) // host process listens to stderr to log
+
+	impl := &server.HashicorpPlugin{}
+
+	// interrupts are ignored by plugin.Serve as the host process is responsible for stopping the plugin, so only
+	// handle termination
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Println("[INFO] received termination signal, shutting down")
+		impl.Shutdown()
+		os.Exit(0)
+	}()
+
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: defaultHandshakeConfig,
 		Plugins: map[string]plugin.Plugin{
-			"impl": &server.HashicorpPlugin{},
+			"impl": impl,
 		},
 		GRPCServer: plugin.DefaultGRPCServer,
 	})
+
+	// the host process has stopped the plugin
+	impl.Shutdown()
 }